@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/object"
 )
 
 type StitchType uint
@@ -40,13 +41,27 @@ var typeStrings = map[StitchType]string{
 type Symbol struct {
 	Name       *ast.Identifier
 	Type       StitchType
-	ParamTypes []StitchType // For Functions
-	ReturnType StitchType   // For Functions.
+	ParamTypes []StitchType  // For Functions
+	ReturnType StitchType    // For Functions.
+	Const      bool          // Declared with const.
+	Value      object.Object // For constants, when it's known before evaluation.
 	// TODO: Add origin.. File/etc Line & Column
 }
 
+// The analysis types of objects, for the types methods declare.
+var objectTypes = map[object.ObjectType]StitchType{
+	object.IntegerObjectType:  TypeInteger,
+	object.StringObjectType:   TypeString,
+	object.BoolObjectType:     TypeBoolean,
+	object.ListObjectType:     TypeList,
+	object.MapObjectType:      TypeMap,
+	object.NodeObjectType:     TypeNode,
+	object.FunctionObjectType: TypeFunction,
+}
+
 var ErrSymbolExists = errors.New("symbol already exists")
 var ErrTypeMismatch = errors.New("type mismatch")
+var ErrConstant = object.ErrConstant
 
 type SymbolTable struct {
 	symbols map[string]*Symbol
@@ -76,11 +91,14 @@ func AnalyzeWithSymbols(tree *ast.ASTree, table *SymbolTable) (*SymbolTable, err
 	if table == nil {
 		table = NewSymbolTable()
 	}
-	for _, stmt := range tree.Statements {
-		if tpe, err := analyzeStatement(stmt, table); err != nil {
+	for i, stmt := range tree.Statements {
+		if _, err := analyzeStatement(stmt, table); err != nil {
+			return nil, err
+		}
+		if folded, err := foldStatement(stmt, table); err != nil {
 			return nil, err
 		} else {
-			fmt.Printf("ANALYSIS: %s => %s\n", stmt, typeStrings[tpe])
+			tree.Statements[i] = folded
 		}
 	}
 	return table, nil
@@ -92,7 +110,7 @@ func analyzeStatement(stmt ast.Statement, symTable *SymbolTable) (StitchType, er
 		if tpe, err := analyzeExpression(t.Value, symTable); err != nil {
 			return TypeUnknown, err
 		} else {
-			err := symTable.Add(t.Name.String(), &Symbol{Name: t.Name, Type: tpe})
+			err := symTable.Add(t.Name.String(), &Symbol{Name: t.Name, Type: tpe, Const: t.Const})
 			return tpe, err
 		}
 	case *ast.FunctionLiteral:
@@ -101,8 +119,6 @@ func analyzeStatement(stmt ast.Statement, symTable *SymbolTable) (StitchType, er
 		return analyzeExpression(t, symTable)
 	case *ast.CommentStatement:
 		return TypeUnknown, nil
-	default:
-		fmt.Printf("ANALYSIS: Unknown statement type\n")
 	}
 	return TypeUnknown, nil
 }
@@ -117,10 +133,44 @@ func analyzeExpression(exp ast.Expression, symTable *SymbolTable) (StitchType, e
 		return TypeInteger, nil
 	case *ast.StringLiteral:
 		return TypeString, nil
+	case *ast.InterpolatedString:
+		for _, part := range t.Parts {
+			if _, err := analyzeExpression(part, symTable); err != nil {
+				return TypeUnknown, err
+			}
+		}
+		return TypeString, nil
 	case *ast.BoolLiteral:
 		return TypeBoolean, nil
+	case *ast.ListLiteral:
+		return TypeList, nil
+	case *ast.MapLiteral, *ast.HashLiteral:
+		return TypeMap, nil
 	case *ast.InfixExpression:
 		return analyzeInfixExpression(t, symTable)
+	case *ast.ForeachStatement:
+		return TypeList, nil
+	case *ast.MatchExpression:
+		return analyzeMatchExpression(t, symTable)
+	case *ast.CallExpression:
+		return analyzeCallExpression(t, symTable)
+	case *ast.IndexExpression:
+		return analyzeIndexExpression(t, symTable)
+	case *ast.SliceExpression:
+		return analyzeSliceExpression(t, symTable)
+	case *ast.IndexAssignment:
+		if _, err := analyzeIndexExpression(t.Target, symTable); err != nil {
+			return TypeUnknown, err
+		}
+		return analyzeExpression(t.Value, symTable)
+	case *ast.PrefixExpression:
+		if tpe, err := analyzeExpression(t.Right, symTable); err != nil {
+			return TypeUnknown, err
+		} else if tpe != TypeUnknown && tpe != TypeInteger && (tpe != TypeFloat || t.Operator == "~") {
+			return TypeUnknown, fmt.Errorf("%w: operator '%s' not defined for %s", ErrTypeMismatch, t.Operator, typeStrings[tpe])
+		} else {
+			return tpe, nil
+		}
 	case *ast.Identifier:
 		if sym, ok := symTable.symbols[t.Identifier]; ok {
 			return sym.Type, nil
@@ -131,6 +181,139 @@ func analyzeExpression(exp ast.Expression, symTable *SymbolTable) (StitchType, e
 	}
 }
 
+// The arms of a match must all have the same type, which is the type of the
+// match. Arms of unknown type are assumed to agree.
+func analyzeMatchExpression(m *ast.MatchExpression, symTable *SymbolTable) (StitchType, error) {
+	if _, err := analyzeExpression(m.Subject, symTable); err != nil {
+		return TypeUnknown, err
+	}
+	matchType := TypeUnknown
+	for _, arm := range m.Arms {
+		armType, err := analyzeExpression(arm.Body, symTable)
+		if err != nil {
+			return TypeUnknown, err
+		} else if armType == TypeUnknown {
+			continue
+		} else if matchType == TypeUnknown {
+			matchType = armType
+		} else if armType != matchType {
+			return TypeUnknown, fmt.Errorf("%w: match arms are %s and %s", ErrTypeMismatch, typeStrings[matchType], typeStrings[armType])
+		}
+	}
+	return matchType, nil
+}
+
+// Calls of the builtin methods of lists, maps and strings are checked against
+// the methods' signatures. Maps' fields hide their methods, so unknown names on
+// maps are left alone.
+func analyzeCallExpression(call *ast.CallExpression, symTable *SymbolTable) (StitchType, error) {
+	argTypes := make([]StitchType, 0, len(call.Arguments))
+	for _, arg := range call.Arguments {
+		if tpe, err := analyzeExpression(arg, symTable); err != nil {
+			return TypeUnknown, err
+		} else {
+			argTypes = append(argTypes, tpe)
+		}
+	}
+
+	dot, ok := call.Function.(*ast.InfixExpression)
+	if !ok || dot.Operator != "." {
+		return TypeUnknown, nil
+	}
+	name, ok := dot.Right.(*ast.Identifier)
+	if !ok {
+		return TypeUnknown, nil
+	}
+	lType, err := analyzeExpression(dot.Left, symTable)
+	if err != nil {
+		return TypeUnknown, err
+	}
+
+	var methods map[string]*object.Method
+	switch lType {
+	case TypeList:
+		methods = object.MethodsOf(object.ListObjectType)
+	case TypeMap:
+		methods = object.MethodsOf(object.MapObjectType)
+	case TypeString:
+		methods = object.MethodsOf(object.StringObjectType)
+	default:
+		return TypeUnknown, nil
+	}
+
+	method, ok := methods[name.Identifier]
+	if !ok {
+		if lType == TypeMap {
+			return TypeUnknown, nil
+		}
+		return TypeUnknown, fmt.Errorf("%w: %s has no method '%s'", ErrTypeMismatch, typeStrings[lType], name.Identifier)
+	}
+	if len(argTypes) != len(method.Params) {
+		return TypeUnknown, fmt.Errorf("%s expects %d arguments but found %d", method.Name, len(method.Params), len(argTypes))
+	}
+	for i, p := range method.Params {
+		if want, ok := objectTypes[p.Type]; ok && argTypes[i] != TypeUnknown && argTypes[i] != want {
+			return TypeUnknown, fmt.Errorf("%w: %s expects %s for %s, found %s", ErrTypeMismatch, method.Name, typeStrings[want], p.Name, typeStrings[argTypes[i]])
+		}
+	}
+	return objectTypes[method.Returns], nil
+}
+
+// Elements of lists and maps can be of any type, but a string's are strings.
+func analyzeIndexExpression(in *ast.IndexExpression, symTable *SymbolTable) (StitchType, error) {
+	lType, err := analyzeExpression(in.Left, symTable)
+	if err != nil {
+		return TypeUnknown, err
+	}
+	iType, err := analyzeExpression(in.Index, symTable)
+	if err != nil {
+		return TypeUnknown, err
+	}
+
+	switch lType {
+	case TypeList, TypeString:
+		if iType != TypeUnknown && iType != TypeInteger {
+			return TypeUnknown, fmt.Errorf("%w: %s index must be INTEGER, found %s", ErrTypeMismatch, typeStrings[lType], typeStrings[iType])
+		}
+		if lType == TypeString {
+			return TypeString, nil
+		}
+	case TypeMap:
+		if iType != TypeUnknown && iType != TypeString && iType != TypeInteger {
+			return TypeUnknown, fmt.Errorf("%w: map key must be STRING or INTEGER, found %s", ErrTypeMismatch, typeStrings[iType])
+		}
+	case TypeUnknown:
+	default:
+		return TypeUnknown, fmt.Errorf("%w: cannot index %s", ErrTypeMismatch, typeStrings[lType])
+	}
+	return TypeUnknown, nil
+}
+
+// A slice has the type of what it slices.
+func analyzeSliceExpression(s *ast.SliceExpression, symTable *SymbolTable) (StitchType, error) {
+	lType, err := analyzeExpression(s.Left, symTable)
+	if err != nil {
+		return TypeUnknown, err
+	}
+	for _, bound := range []ast.Expression{s.Low, s.High} {
+		if bound == nil {
+			continue
+		}
+		if bType, err := analyzeExpression(bound, symTable); err != nil {
+			return TypeUnknown, err
+		} else if bType != TypeUnknown && bType != TypeInteger {
+			return TypeUnknown, fmt.Errorf("%w: slice bounds must be INTEGER, found %s", ErrTypeMismatch, typeStrings[bType])
+		}
+	}
+
+	switch lType {
+	case TypeList, TypeString, TypeUnknown:
+		return lType, nil
+	default:
+		return TypeUnknown, fmt.Errorf("%w: cannot slice %s", ErrTypeMismatch, typeStrings[lType])
+	}
+}
+
 func analyzeInfixExpression(infix *ast.InfixExpression, symTable *SymbolTable) (StitchType, error) {
 	lType, err := analyzeExpression(infix.Left, symTable)
 	if err != nil {
@@ -143,10 +326,46 @@ func analyzeInfixExpression(infix *ast.InfixExpression, symTable *SymbolTable) (
 
 	switch infix.Operator {
 	case "+", "-", "/", "*":
+		// Strings add integers by their text.
+		if lType == TypeString && rType == TypeInteger && infix.Operator == "+" {
+			return TypeString, nil
+		}
+		// Elements, fields and results of hosted functions aren't typed, so
+		// they're left for evaluation to check.
+		if lType == TypeUnknown || rType == TypeUnknown {
+			return TypeUnknown, nil
+		}
 		if lType != rType {
 			return TypeUnknown, fmt.Errorf("%w: operator '%s' not defined for %s and %s", ErrTypeMismatch, infix.Operator, typeStrings[lType], typeStrings[rType])
 		}
+		// Lists, and the ranges standing in for them, only concatenate.
+		if lType == TypeList && infix.Operator != "+" {
+			return TypeUnknown, fmt.Errorf("%w: operator '%s' not defined for LIST", ErrTypeMismatch, infix.Operator)
+		}
 		return lType, nil
+	case "&", "|", "^", "<<", ">>", "**":
+		for _, tpe := range []StitchType{lType, rType} {
+			if tpe != TypeUnknown && tpe != TypeInteger {
+				return TypeUnknown, fmt.Errorf("%w: operator '%s' not defined for %s", ErrTypeMismatch, infix.Operator, typeStrings[tpe])
+			}
+		}
+		return TypeInteger, nil
+	case "??":
+		// The default is used when the left side is none.
+		if lType == rType {
+			return lType, nil
+		}
+		return TypeUnknown, nil
+	case "is":
+		return TypeBoolean, nil
+	case "..", "..<":
+		// Ranges stand in for lists of their integers.
+		for _, tpe := range []StitchType{lType, rType} {
+			if tpe != TypeUnknown && tpe != TypeInteger {
+				return TypeUnknown, fmt.Errorf("%w: range bounds must be INTEGER, found %s", ErrTypeMismatch, typeStrings[tpe])
+			}
+		}
+		return TypeList, nil
 	}
 	return TypeUnknown, nil
 }
@@ -0,0 +1,38 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nirosys/stitch/parsing"
+)
+
+func Test_AnalyzeInfix(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		// List and map elements aren't typed, so they're checked when evaluated.
+		{"let l = [1, 2]\nlet x = l[0]\nx + 1", ""},
+		{"let l = [1, 2]\nl[0] + 1", ""},
+		{"let m = #{\"a\": 1}\nm[\"a\"] + 1", ""},
+		{"[1, 2].map(fn(x): x)[0] + 1", ""},
+		{"let l = [\"a\"]\n1 - l[0]", ""},
+		{"\"a\" + 1", ""},
+		{"1 + \"a\"", "type mismatch: operator '+' not defined for INTEGER and STRING"},
+		{"[1] + 1", "type mismatch: operator '+' not defined for LIST and INTEGER"},
+	}
+	for i, test := range tests {
+		parser := parsing.NewParser(strings.NewReader(test.src))
+		tree := parser.Parse()
+		if tree == nil {
+			t.Fatalf("[%d] unable to parse %q: %v", i, test.src, parser.Errors())
+		}
+		_, err := Analyze(tree)
+		if test.err == "" && err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+		} else if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("[%d] expected error %q, found %v", i, test.err, err)
+		}
+	}
+}
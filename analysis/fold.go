@@ -0,0 +1,247 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/eval"
+	"github.com/nirosys/stitch/lexing"
+	"github.com/nirosys/stitch/object"
+)
+
+// Constant Folding ///////////////////////////////////////////////////////////
+
+// A folder rewrites the expressions of a statement that only involve literals
+// and constants into the literals they evaluate to, so they aren't worked out
+// again each time the statement is evaluated. Along the way it finds any
+// assignment to a constant, however deeply it's nested.
+type folder struct {
+	symbols  *SymbolTable
+	shadowed map[string]int // Names bound by the enclosing functions and loops.
+	err      error
+}
+
+func foldStatement(stmt ast.Statement, symbols *SymbolTable) (ast.Statement, error) {
+	f := &folder{symbols: symbols, shadowed: map[string]int{}}
+	if let, ok := stmt.(*ast.LetStatement); ok {
+		// The declaration itself is the one binding a constant can have.
+		let.Value = f.expression(let.Value)
+		if sym, have := symbols.symbols[let.Name.Identifier]; have && let.Const {
+			sym.Value = valueOf(let.Value)
+		}
+		return let, f.err
+	}
+	stmt = f.statement(stmt)
+	return stmt, f.err
+}
+
+func (f *folder) statement(stmt ast.Statement) ast.Statement {
+	switch t := stmt.(type) {
+	case *ast.LetStatement:
+		f.assign(t.Name.Identifier)
+		t.Value = f.expression(t.Value)
+	case *ast.NodeStatement:
+		f.assign(t.Identifier.Identifier)
+	case *ast.ExpressionStatement:
+		t.Expression = f.expression(t.Expression)
+	case ast.Expression:
+		return f.expression(t)
+	}
+	return stmt
+}
+
+func (f *folder) expression(exp ast.Expression) ast.Expression {
+	switch t := exp.(type) {
+	case *ast.Identifier:
+		if sym, ok := f.symbols.symbols[t.Identifier]; ok && sym.Const && sym.Value != nil && f.shadowed[t.Identifier] == 0 {
+			return literalOf(sym.Value, t.Token.Position)
+		}
+	case *ast.InfixExpression:
+		t.Left = f.expression(t.Left)
+		if t.Operator == "." {
+			return t // The right side names a field, it isn't a value.
+		}
+		t.Right = f.expression(t.Right)
+		return f.reduce(t, t.Left, t.Right)
+	case *ast.PrefixExpression:
+		t.Right = f.expression(t.Right)
+		return f.reduce(t, t.Right)
+	case *ast.NotExpression:
+		t.Expression = f.expression(t.Expression)
+		return f.reduce(t, t.Expression)
+	case *ast.InterpolatedString:
+		for i := range t.Parts {
+			t.Parts[i] = f.expression(t.Parts[i])
+		}
+		return f.reduce(t, t.Parts...)
+	case *ast.ConditionalExpression:
+		t.Condition = f.expression(t.Condition)
+		t.Block = f.block(t.Block)
+		if t.Else != nil {
+			t.Else = f.expression(t.Else)
+		}
+		// Only the branch a constant condition takes is kept.
+		if cond, ok := t.Condition.(*ast.BoolLiteral); !ok {
+			return t
+		} else if cond.Value {
+			return t.Block
+		} else if t.Else != nil {
+			return t.Else
+		}
+		return &ast.NoneLiteral{Token: lexing.Token{Text: "none", Position: t.Token.Position, Type: lexing.K_NONE}}
+	case *ast.MatchExpression:
+		t.Subject = f.expression(t.Subject)
+		for _, arm := range t.Arms {
+			for i := range arm.Patterns {
+				arm.Patterns[i] = f.expression(arm.Patterns[i])
+			}
+			arm.Body = f.expression(arm.Body)
+		}
+	case *ast.TryExpression:
+		t.Block = f.block(t.Block)
+		f.scope(func() { t.Catch = f.block(t.Catch) }, t.ErrVar)
+	case *ast.BlockExpression:
+		return f.block(t)
+	case *ast.FunctionLiteral:
+		if t.Identifier != nil {
+			f.assign(t.Identifier.Identifier)
+		}
+		params := make([]*ast.Identifier, 0, len(t.Parameters))
+		for _, p := range t.Parameters {
+			params = append(params, p.Identifier)
+		}
+		f.scope(func() { t.Body = f.block(t.Body) }, params...)
+	case *ast.ForeachStatement:
+		t.List = f.expression(t.List)
+		f.scope(func() { t.Block = f.block(t.Block) }, t.KeyVar, t.LoopVar)
+	case *ast.CallExpression:
+		t.Function = f.expression(t.Function)
+		for i := range t.Arguments {
+			t.Arguments[i] = f.expression(t.Arguments[i])
+		}
+	case *ast.NamedNodeExpression:
+		t.Expression = f.expression(t.Expression)
+	case *ast.ArrowExpression:
+		t.Left = f.expression(t.Left)
+		t.Right = f.expression(t.Right)
+	case *ast.AssignmentExpression:
+		f.assign(t.Identifier.Identifier)
+		t.Value = f.expression(t.Value)
+	case *ast.IndexExpression:
+		t.Left = f.expression(t.Left)
+		t.Index = f.expression(t.Index)
+		return f.reduce(t, t.Left, t.Index)
+	case *ast.SliceExpression:
+		t.Left = f.expression(t.Left)
+		if t.Low != nil {
+			t.Low = f.expression(t.Low)
+		}
+		if t.High != nil {
+			t.High = f.expression(t.High)
+		}
+	case *ast.IndexAssignment:
+		t.Target.Index = f.expression(t.Target.Index)
+		t.Value = f.expression(t.Value)
+	case *ast.ListLiteral:
+		for i := range t.Contents {
+			t.Contents[i] = f.expression(t.Contents[i])
+		}
+	case *ast.MapLiteral:
+		for _, a := range t.Assignments {
+			a.Value = f.expression(a.Value) // Keys are field names, not variables.
+		}
+	case *ast.HashLiteral:
+		for i := range t.Keys {
+			t.Keys[i] = f.expression(t.Keys[i])
+			t.Values[i] = f.expression(t.Values[i])
+		}
+	}
+	return exp
+}
+
+func (f *folder) block(b *ast.BlockExpression) *ast.BlockExpression {
+	if b == nil {
+		return nil
+	}
+	for i := range b.Statements {
+		b.Statements[i] = f.statement(b.Statements[i])
+	}
+	return b
+}
+
+// Runs fold with the names bound to something other than the constants they
+// might share a name with.
+func (f *folder) scope(fold func(), names ...*ast.Identifier) {
+	for _, n := range names {
+		if n != nil {
+			f.shadowed[n.Identifier]++
+		}
+	}
+	fold()
+	for _, n := range names {
+		if n != nil {
+			f.shadowed[n.Identifier]--
+		}
+	}
+}
+
+func (f *folder) assign(name string) {
+	if sym, ok := f.symbols.symbols[name]; ok && sym.Const && f.shadowed[name] == 0 && f.err == nil {
+		f.err = fmt.Errorf("%w: %s", ErrConstant, name)
+	}
+}
+
+// Evaluates an expression whose operands are all literals, giving the literal
+// it evaluates to. Expressions that fail are left for evaluation to report,
+// and those giving anything but an integer, string, bool or none are left
+// as they are.
+func (f *folder) reduce(exp ast.Expression, operands ...ast.Expression) ast.Expression {
+	for _, o := range operands {
+		if valueOf(o) == nil {
+			return exp
+		}
+	}
+	obj, err := eval.NewEvaluator().EvalStatement(context.Background(), exp, object.NewEnvironment())
+	if err != nil {
+		return exp
+	}
+	if lit := literalOf(obj, ast.PositionOf(exp)); lit != nil {
+		return lit
+	}
+	return exp
+}
+
+// Returns the value of a literal, or nil for anything else.
+func valueOf(exp ast.Expression) object.Object {
+	switch t := exp.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: t.Value}
+	case *ast.StringLiteral:
+		return &object.String{Value: t.Value}
+	case *ast.BoolLiteral:
+		return object.NewBoolObject(t.Value)
+	case *ast.NoneLiteral:
+		return object.None
+	}
+	return nil
+}
+
+func literalOf(obj object.Object, pos lexing.Position) ast.Expression {
+	switch t := obj.(type) {
+	case *object.Integer:
+		text := strconv.FormatInt(t.Value, 10)
+		return &ast.IntegerLiteral{Token: lexing.Token{Text: text, Position: pos, Type: lexing.L_INTEGER}, Value: t.Value}
+	case *object.String:
+		return &ast.StringLiteral{Token: lexing.Token{Text: t.Value, Position: pos, Type: lexing.L_STRING}, Value: t.Value}
+	case *object.BoolObject:
+		if bool(*t) {
+			return &ast.BoolLiteral{Token: lexing.Token{Text: "true", Position: pos, Type: lexing.K_TRUE}, Value: true}
+		}
+		return &ast.BoolLiteral{Token: lexing.Token{Text: "false", Position: pos, Type: lexing.K_FALSE}, Value: false}
+	case *object.NoneObject:
+		return &ast.NoneLiteral{Token: lexing.Token{Text: "none", Position: pos, Type: lexing.K_NONE}}
+	}
+	return nil
+}
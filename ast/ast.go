@@ -13,6 +13,8 @@ type ASTree struct {
 
 type Node interface {
 	TokenLiteral() string
+	// Pos is the position of the token the node was parsed from.
+	Pos() lexing.Position
 	String() string
 }
 
@@ -29,6 +31,7 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Text }
+func (es *ExpressionStatement) Pos() lexing.Position { return es.Token.Position }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -45,6 +48,7 @@ type CallExpression struct {
 func (c *CallExpression) statementNode()       {}
 func (c *CallExpression) expressionNode()      {}
 func (c *CallExpression) TokenLiteral() string { return c.Token.Text }
+func (c *CallExpression) Pos() lexing.Position { return c.Token.Position }
 func (c *CallExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(c.Function.String())
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"strings"
 
 	"github.com/nirosys/stitch/lexing"
 )
@@ -16,6 +17,7 @@ type ArrowExpression struct {
 
 func (a *ArrowExpression) statementNode()       {}
 func (a *ArrowExpression) TokenLiteral() string { return a.Token.Text }
+func (a *ArrowExpression) Pos() lexing.Position { return a.Token.Position }
 func (a *ArrowExpression) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteString(a.Left.String())
@@ -33,6 +35,7 @@ type BlockExpression struct {
 
 func (b *BlockExpression) statementNode()       {}
 func (b *BlockExpression) TokenLiteral() string { return b.Token.Text }
+func (b *BlockExpression) Pos() lexing.Position { return b.Token.Position }
 func (b *BlockExpression) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteString("{\n")
@@ -56,6 +59,7 @@ type AssignmentExpression struct {
 func (a *AssignmentExpression) statementNode()       {}
 func (a *AssignmentExpression) expressionNode()      {}
 func (a *AssignmentExpression) TokenLiteral() string { return a.Token.Text }
+func (a *AssignmentExpression) Pos() lexing.Position { return a.Token.Position }
 func (a *AssignmentExpression) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteString(a.Identifier.String())
@@ -75,6 +79,7 @@ type InfixExpression struct {
 func (i *InfixExpression) statementNode()       {}
 func (i *InfixExpression) expressionNode()      {}
 func (i *InfixExpression) TokenLiteral() string { return i.Token.Text }
+func (i *InfixExpression) Pos() lexing.Position { return i.Token.Position }
 func (i *InfixExpression) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteString(i.Left.String())
@@ -92,6 +97,7 @@ type Tag struct {
 func (t *Tag) statementNode()       {}
 func (t *Tag) expressionNode()      {}
 func (t *Tag) TokenLiteral() string { return t.Token.Text }
+func (t *Tag) Pos() lexing.Position { return t.Token.Position }
 func (t *Tag) String() string       { return t.Expression.String() }
 
 // InternalExpression /////////////////////////////////////////////////////////
@@ -105,6 +111,7 @@ type InternalExpression struct {
 func (i *InternalExpression) statementNode()       {}
 func (i *InternalExpression) expressionNode()      {}
 func (i *InternalExpression) TokenLiteral() string { return i.Token.Text }
+func (i *InternalExpression) Pos() lexing.Position { return i.Token.Position }
 func (i *InternalExpression) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteString("internal \"")
@@ -125,6 +132,7 @@ type ConditionalExpression struct {
 func (i *ConditionalExpression) statementNode()       {}
 func (i *ConditionalExpression) expressionNode()      {}
 func (i *ConditionalExpression) TokenLiteral() string { return i.Token.Text }
+func (i *ConditionalExpression) Pos() lexing.Position { return i.Token.Position }
 func (i *ConditionalExpression) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteString("if ")
@@ -137,6 +145,99 @@ func (i *ConditionalExpression) String() string {
 	return buffer.String()
 }
 
+// MatchExpression ////////////////////////////////////////////////////////////
+
+// MatchExpression evaluates to the body of the first arm with a pattern equal
+// to its subject.
+type MatchExpression struct {
+	Token lexing.Token
+
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (m *MatchExpression) statementNode()       {}
+func (m *MatchExpression) expressionNode()      {}
+func (m *MatchExpression) TokenLiteral() string { return m.Token.Text }
+func (m *MatchExpression) Pos() lexing.Position { return m.Token.Position }
+func (m *MatchExpression) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("match ")
+	buffer.WriteString(m.Subject.String())
+	buffer.WriteString(" { ")
+	for i, arm := range m.Arms {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(arm.String())
+	}
+	buffer.WriteString(" }")
+	return buffer.String()
+}
+
+// HasDefault reports whether one of the arms matches anything.
+func (m *MatchExpression) HasDefault() bool {
+	for _, arm := range m.Arms {
+		if arm.IsDefault() {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchArm is `pattern | pattern => body`. The pattern `_` matches anything.
+type MatchArm struct {
+	Token lexing.Token
+
+	Patterns []Expression
+	Body     Expression
+}
+
+func (a *MatchArm) IsDefault() bool {
+	if len(a.Patterns) != 1 {
+		return false
+	}
+	ident, ok := a.Patterns[0].(*Identifier)
+	return ok && ident.Identifier == "_"
+}
+
+func (a *MatchArm) String() string {
+	patterns := make([]string, 0, len(a.Patterns))
+	for _, p := range a.Patterns {
+		patterns = append(patterns, p.String())
+	}
+	return strings.Join(patterns, " | ") + " => " + a.Body.String()
+}
+
+// TryExpression //////////////////////////////////////////////////////////////
+
+// TryExpression evaluates to its block, or, when the block fails, to its catch
+// block with the error bound to ErrVar.
+type TryExpression struct {
+	Token lexing.Token
+
+	Block  *BlockExpression
+	ErrVar *Identifier // Optional
+	Catch  *BlockExpression
+}
+
+func (t *TryExpression) statementNode()       {}
+func (t *TryExpression) expressionNode()      {}
+func (t *TryExpression) TokenLiteral() string { return t.Token.Text }
+func (t *TryExpression) Pos() lexing.Position { return t.Token.Position }
+func (t *TryExpression) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("try ")
+	buffer.WriteString(t.Block.String())
+	buffer.WriteString(" catch ")
+	if t.ErrVar != nil {
+		buffer.WriteString(t.ErrVar.String())
+		buffer.WriteByte(' ')
+	}
+	buffer.WriteString(t.Catch.String())
+	return buffer.String()
+}
+
 // NamedNodeExpression ////////////////////////////////////////////////////////
 type NamedNodeExpression struct {
 	Token lexing.Token
@@ -149,6 +250,7 @@ type NamedNodeExpression struct {
 func (n *NamedNodeExpression) statementNode()       {}
 func (n *NamedNodeExpression) expressionNode()      {}
 func (n *NamedNodeExpression) TokenLiteral() string { return n.Token.Text }
+func (n *NamedNodeExpression) Pos() lexing.Position { return n.Token.Position }
 func (n *NamedNodeExpression) String() string {
 	return "<not implemented>"
 }
@@ -163,6 +265,85 @@ type NotExpression struct {
 func (n *NotExpression) statementNode()       {}
 func (n *NotExpression) expressionNode()      {}
 func (n *NotExpression) TokenLiteral() string { return n.Token.Text }
+func (n *NotExpression) Pos() lexing.Position { return n.Token.Position }
 func (n *NotExpression) String() string {
 	return "!" + n.Expression.String()
 }
+
+// PrefixExpression ///////////////////////////////////////////////////////////
+type PrefixExpression struct {
+	Token lexing.Token
+
+	Operator string
+	Right    Expression
+}
+
+func (p *PrefixExpression) statementNode()       {}
+func (p *PrefixExpression) expressionNode()      {}
+func (p *PrefixExpression) TokenLiteral() string { return p.Token.Text }
+func (p *PrefixExpression) Pos() lexing.Position { return p.Token.Position }
+func (p *PrefixExpression) String() string {
+	return p.Operator + p.Right.String()
+}
+
+// IndexExpression ////////////////////////////////////////////////////////////
+
+// IndexExpression is `left[index]`, for lists, maps and strings.
+type IndexExpression struct {
+	Token lexing.Token
+
+	Left  Expression
+	Index Expression
+}
+
+func (i *IndexExpression) statementNode()       {}
+func (i *IndexExpression) expressionNode()      {}
+func (i *IndexExpression) TokenLiteral() string { return i.Token.Text }
+func (i *IndexExpression) Pos() lexing.Position { return i.Token.Position }
+func (i *IndexExpression) String() string {
+	return i.Left.String() + "[" + i.Index.String() + "]"
+}
+
+// SliceExpression is `left[low:high]`, where either bound can be left off.
+type SliceExpression struct {
+	Token lexing.Token
+
+	Left Expression
+	Low  Expression // Optional
+	High Expression // Optional
+}
+
+func (s *SliceExpression) statementNode()       {}
+func (s *SliceExpression) expressionNode()      {}
+func (s *SliceExpression) TokenLiteral() string { return s.Token.Text }
+func (s *SliceExpression) Pos() lexing.Position { return s.Token.Position }
+func (s *SliceExpression) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(s.Left.String())
+	buffer.WriteByte('[')
+	if s.Low != nil {
+		buffer.WriteString(s.Low.String())
+	}
+	buffer.WriteByte(':')
+	if s.High != nil {
+		buffer.WriteString(s.High.String())
+	}
+	buffer.WriteByte(']')
+	return buffer.String()
+}
+
+// IndexAssignment is `left[index] = value`.
+type IndexAssignment struct {
+	Token lexing.Token
+
+	Target *IndexExpression
+	Value  Expression
+}
+
+func (a *IndexAssignment) statementNode()       {}
+func (a *IndexAssignment) expressionNode()      {}
+func (a *IndexAssignment) TokenLiteral() string { return a.Token.Text }
+func (a *IndexAssignment) Pos() lexing.Position { return a.Token.Position }
+func (a *IndexAssignment) String() string {
+	return a.Target.String() + " = " + a.Value.String()
+}
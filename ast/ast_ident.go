@@ -15,6 +15,7 @@ type Identifier struct {
 }
 
 func (i *Identifier) TokenLiteral() string { return i.Token.Text }
+func (i *Identifier) Pos() lexing.Position { return i.Token.Position }
 func (i *Identifier) String() string       { return i.Identifier }
 
 // FunctionParameter //////////////////////////////////////////////////////////
@@ -26,6 +27,7 @@ type FunctionParameter struct {
 
 func (p *FunctionParameter) statementNode()       {}
 func (p *FunctionParameter) TokenLiteral() string { return p.Token.Text }
+func (p *FunctionParameter) Pos() lexing.Position { return p.Token.Position }
 func (p *FunctionParameter) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteString(p.Identifier.String())
@@ -45,6 +47,7 @@ type TagName struct {
 func (t *TagName) statementNode()       {}
 func (t *TagName) expressionNode()      {}
 func (t *TagName) TokenLiteral() string { return t.Token.Text }
+func (t *TagName) Pos() lexing.Position { return t.Token.Position }
 func (t *TagName) String() string {
 	return "@" + t.Identifier.String()
 }
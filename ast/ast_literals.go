@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"strconv"
 	"strings"
 
 	"github.com/nirosys/stitch/lexing"
@@ -24,10 +25,40 @@ type StringLiteral struct {
 func (s *StringLiteral) statementNode()       {}
 func (s *StringLiteral) expressionNode()      {}
 func (s *StringLiteral) TokenLiteral() string { return s.Token.Text }
+func (s *StringLiteral) Pos() lexing.Position { return s.Token.Position }
 func (s *StringLiteral) String() string {
+	return `"` + quoteText(s.Value) + `"`
+}
+
+// The text of a string, escaped for double quotes.
+func quoteText(s string) string {
+	q := strconv.Quote(s)
+	return strings.ReplaceAll(q[1:len(q)-1], "${", `\${`)
+}
+
+// InterpolatedString is a string with `${expression}`s in it. Its parts are
+// StringLiterals for the text in between, and the expressions.
+type InterpolatedString struct {
+	Token lexing.Token
+	Parts []Expression
+}
+
+func (s *InterpolatedString) statementNode()       {}
+func (s *InterpolatedString) expressionNode()      {}
+func (s *InterpolatedString) TokenLiteral() string { return s.Token.Text }
+func (s *InterpolatedString) Pos() lexing.Position { return s.Token.Position }
+func (s *InterpolatedString) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteByte('"')
-	buffer.WriteString(s.Token.Text)
+	for _, part := range s.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			buffer.WriteString(quoteText(text.Value))
+		} else {
+			buffer.WriteString("${")
+			buffer.WriteString(part.String())
+			buffer.WriteByte('}')
+		}
+	}
 	buffer.WriteByte('"')
 	return buffer.String()
 }
@@ -40,6 +71,7 @@ type IntegerLiteral struct {
 func (i *IntegerLiteral) statementNode()       {}
 func (i *IntegerLiteral) expressionNode()      {}
 func (i *IntegerLiteral) TokenLiteral() string { return i.Token.Text }
+func (i *IntegerLiteral) Pos() lexing.Position { return i.Token.Position }
 func (i *IntegerLiteral) String() string       { return i.Token.Text }
 
 /// Node Literal //////////////////////////////////////////////////////////////
@@ -57,6 +89,7 @@ type NodeLiteral struct {
 func (n *NodeLiteral) statementNode()       {}
 func (n *NodeLiteral) expressionNode()      {}
 func (n *NodeLiteral) TokenLiteral() string { return n.Token.Text }
+func (n *NodeLiteral) Pos() lexing.Position { return n.Token.Position }
 func (n *NodeLiteral) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteString("node ")
@@ -83,6 +116,7 @@ type FunctionLiteral struct {
 func (f *FunctionLiteral) statementNode()       {}
 func (f *FunctionLiteral) expressionNode()      {}
 func (f *FunctionLiteral) TokenLiteral() string { return f.Token.Text }
+func (f *FunctionLiteral) Pos() lexing.Position { return f.Token.Position }
 func (f *FunctionLiteral) String() string {
 	return "<not implemented>"
 }
@@ -98,6 +132,7 @@ type ListLiteral struct {
 func (l *ListLiteral) statementNode()       {}
 func (l *ListLiteral) expressionNode()      {}
 func (l *ListLiteral) TokenLiteral() string { return l.Token.Text }
+func (l *ListLiteral) Pos() lexing.Position { return l.Token.Position }
 func (l *ListLiteral) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteByte('[')
@@ -121,6 +156,7 @@ type BoolLiteral struct {
 func (b *BoolLiteral) statementNode()       {}
 func (b *BoolLiteral) expressionNode()      {}
 func (b *BoolLiteral) TokenLiteral() string { return b.Token.Text }
+func (b *BoolLiteral) Pos() lexing.Position { return b.Token.Position }
 func (b *BoolLiteral) String() string {
 	if b.Value {
 		return "true"
@@ -129,6 +165,18 @@ func (b *BoolLiteral) String() string {
 	}
 }
 
+/// None Literal //////////////////////////////////////////////////////////////
+
+type NoneLiteral struct {
+	Token lexing.Token
+}
+
+func (n *NoneLiteral) statementNode()       {}
+func (n *NoneLiteral) expressionNode()      {}
+func (n *NoneLiteral) TokenLiteral() string { return n.Token.Text }
+func (n *NoneLiteral) Pos() lexing.Position { return n.Token.Position }
+func (n *NoneLiteral) String() string       { return "none" }
+
 /// Map Literal ///////////////////////////////////////////////////////////////
 type MapLiteral struct {
 	Token       lexing.Token
@@ -138,6 +186,33 @@ type MapLiteral struct {
 func (m *MapLiteral) statementNode()       {}
 func (m *MapLiteral) expressionNode()      {}
 func (m *MapLiteral) TokenLiteral() string { return m.Token.Text }
+func (m *MapLiteral) Pos() lexing.Position { return m.Token.Position }
 func (m *MapLiteral) String() string {
 	return "<not implemented"
 }
+
+// HashLiteral is `#{ key: value, ... }`, a map whose keys are expressions.
+type HashLiteral struct {
+	Token  lexing.Token
+	Keys   []Expression
+	Values []Expression
+}
+
+func (h *HashLiteral) statementNode()       {}
+func (h *HashLiteral) expressionNode()      {}
+func (h *HashLiteral) TokenLiteral() string { return h.Token.Text }
+func (h *HashLiteral) Pos() lexing.Position { return h.Token.Position }
+func (h *HashLiteral) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("#{")
+	for i := range h.Keys {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(h.Keys[i].String())
+		buffer.WriteString(": ")
+		buffer.WriteString(h.Values[i].String())
+	}
+	buffer.WriteByte('}')
+	return buffer.String()
+}
@@ -16,10 +16,12 @@ type LetStatement struct {
 	Token lexing.Token
 	Name  *Identifier
 	Value Expression
+	Const bool // Declared with const, so the name can't be assigned again.
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Text }
+func (ls *LetStatement) Pos() lexing.Position { return ls.Token.Position }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
@@ -40,6 +42,7 @@ type CommentStatement struct {
 
 func (c *CommentStatement) statementNode()       {}
 func (c *CommentStatement) TokenLiteral() string { return c.Token.Text }
+func (c *CommentStatement) Pos() lexing.Position { return c.Token.Position }
 func (c *CommentStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString("# ")
@@ -55,6 +58,7 @@ type ImportStatement struct {
 
 func (i *ImportStatement) statementNode()       {}
 func (i *ImportStatement) TokenLiteral() string { return i.Token.Text }
+func (i *ImportStatement) Pos() lexing.Position { return i.Token.Position }
 func (i *ImportStatement) String() string {
 	var buffer bytes.Buffer
 	buffer.WriteString("import \"")
@@ -72,6 +76,7 @@ type NodeStatement struct {
 
 func (n *NodeStatement) statementNode()       {}
 func (n *NodeStatement) TokenLiteral() string { return n.Token.Text }
+func (n *NodeStatement) Pos() lexing.Position { return n.Token.Position }
 func (n *NodeStatement) String() string {
 	return "<not implemented>"
 }
@@ -87,21 +92,60 @@ type ModifierStatement struct {
 
 func (m *ModifierStatement) statementNode()       {}
 func (m *ModifierStatement) TokenLiteral() string { return m.Token.Text }
+func (m *ModifierStatement) Pos() lexing.Position { return m.Token.Position }
 func (m *ModifierStatement) String() string {
 	return "<not implemented>"
 }
 
 // ForeachStatement ///////////////////////////////////////////////////////////
+
+// ForeachStatement loops over a list, a map or an integer. It is also an
+// expression, producing the list of its block's results.
 type ForeachStatement struct {
 	Token lexing.Token
 
+	KeyVar  *Identifier // Only for `foreach k, v in m`.
 	LoopVar *Identifier
 	List    Expression
 	Block   *BlockExpression
 }
 
 func (f *ForeachStatement) statementNode()       {}
+func (f *ForeachStatement) expressionNode()      {}
 func (f *ForeachStatement) TokenLiteral() string { return f.Token.Text }
+func (f *ForeachStatement) Pos() lexing.Position { return f.Token.Position }
 func (f *ForeachStatement) String() string {
-	return "<not implemented>"
+	var out bytes.Buffer
+	out.WriteString("foreach ")
+	if f.KeyVar != nil {
+		out.WriteString(f.KeyVar.String())
+		out.WriteString(", ")
+	}
+	out.WriteString(f.LoopVar.String())
+	out.WriteString(" in ")
+	out.WriteString(f.List.String())
+	out.WriteByte(' ')
+	out.WriteString(f.Block.String())
+	return out.String()
 }
+
+// BreakStatement leaves the innermost foreach loop.
+type BreakStatement struct {
+	Token lexing.Token
+}
+
+func (b *BreakStatement) statementNode()       {}
+func (b *BreakStatement) TokenLiteral() string { return b.Token.Text }
+func (b *BreakStatement) Pos() lexing.Position { return b.Token.Position }
+func (b *BreakStatement) String() string       { return "break" }
+
+// ContinueStatement moves on to the next iteration of the innermost foreach
+// loop.
+type ContinueStatement struct {
+	Token lexing.Token
+}
+
+func (c *ContinueStatement) statementNode()       {}
+func (c *ContinueStatement) TokenLiteral() string { return c.Token.Text }
+func (c *ContinueStatement) Pos() lexing.Position { return c.Token.Position }
+func (c *ContinueStatement) String() string       { return "continue" }
@@ -0,0 +1,14 @@
+package ast
+
+import (
+	"github.com/nirosys/stitch/lexing"
+)
+
+// PositionOf returns the position of the token a node was parsed from, or the
+// zero position when there's no node.
+func PositionOf(n Node) lexing.Position {
+	if n == nil {
+		return lexing.Position{}
+	}
+	return n.Pos()
+}
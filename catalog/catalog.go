@@ -0,0 +1,258 @@
+// Package catalog describes the node types a Gaufre runtime provides, and
+// validates graphs against them before they're handed to the runtime.
+package catalog
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/eval"
+	"github.com/nirosys/stitch/object"
+)
+
+// Kind is the kind of value a node argument accepts.
+type Kind string
+
+const (
+	KindAny    Kind = "any"
+	KindString Kind = "string"
+	KindInt    Kind = "int"
+	KindBool   Kind = "bool"
+	KindOID    Kind = "oid"
+)
+
+// Accepts reports whether an argument value is of the kind. OIDs, IPs and
+// CIDRs are passed to the runtime as strings, so string and any arguments
+// take them too, and oid arguments take strings in the form of an OID.
+func (k Kind) Accepts(obj object.Object) bool {
+	switch obj.Type() {
+	case object.OIDObjectType:
+		return k == KindOID || k == KindString || k == KindAny
+	case object.IPObjectType, object.CIDRObjectType:
+		return k == KindString || k == KindAny
+	}
+	switch k {
+	case KindString:
+		return obj.Type() == object.StringObjectType
+	case KindInt:
+		return obj.Type() == object.IntegerObjectType
+	case KindBool:
+		return obj.Type() == object.BoolObjectType
+	case KindOID:
+		s, ok := obj.(*object.String)
+		return ok && isOIDText(s.Value)
+	case KindAny:
+		return KindString.Accepts(obj) || KindInt.Accepts(obj) || KindBool.Accepts(obj)
+	}
+	return false
+}
+
+var templates = regexp.MustCompile(`\{\{.*?\}\}`)
+
+// OIDs given as text are checked as far as they can be: each arc must be a
+// number or a template, though the first can be a MIB name, as in
+// "ifInOctets.{{ .Input.Key }}".
+func isOIDText(s string) bool {
+	arcs := strings.Split(strings.TrimPrefix(templates.ReplaceAllString(s, "0"), "."), ".")
+	for i, arc := range arcs {
+		if arc == "" {
+			return false
+		}
+		for j, r := range arc {
+			digit := r >= '0' && r <= '9'
+			name := i == 0 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || (j > 0 && (digit || r == '-')))
+			if !digit && !name {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NodeTypeDef ////////////////////////////////////////////////////////////////
+
+type NodeTypeDef struct {
+	Name    string
+	Doc     string
+	Pure    bool // The only effect of its nodes is the data they send downstream.
+	Args    []ArgDef
+	Inputs  []SlotDef
+	Outputs []SlotDef
+
+	nodeType *object.NodeType
+}
+
+type ArgDef struct {
+	Name     string
+	Doc      string
+	Kind     Kind
+	Required bool
+	Default  object.Object // Used when the argument is left off, nil if Required.
+}
+
+type SlotDef struct {
+	Name     string
+	Doc      string
+	Required bool       // Only meaningful for inputs.
+	Schema   []FieldDef // Fields of the data sent from the slot.
+}
+
+// FieldDef describes one field of the data a node produces. Types are the
+// runtime's, and aren't checked by stitch.
+type FieldDef struct {
+	Name string `yaml:"name" json:"name"`
+	Type string `yaml:"type" json:"type"`
+	Doc  string `yaml:"doc" json:"doc"`
+}
+
+// NodeType returns the node type stitch programs construct nodes from. The
+// same node type is returned every time.
+func (d *NodeTypeDef) NodeType() *object.NodeType {
+	if d.nodeType == nil {
+		d.nodeType = &object.NodeType{
+			Name:        d.Name,
+			NodeArgs:    []*ast.FunctionParameter{},
+			InputSlots:  slotNames(d.Inputs),
+			OutputSlots: slotNames(d.Outputs),
+		}
+		for _, arg := range d.Args {
+			d.nodeType.NodeArgs = append(d.nodeType.NodeArgs, &ast.FunctionParameter{
+				Identifier: &ast.Identifier{Identifier: arg.Name},
+			})
+			if arg.Default != nil {
+				if d.nodeType.Defaults == nil {
+					d.nodeType.Defaults = map[string]object.Object{}
+				}
+				d.nodeType.Defaults[arg.Name] = arg.Default
+			}
+		}
+	}
+	return d.nodeType
+}
+
+func (d *NodeTypeDef) Arg(name string) (ArgDef, bool) {
+	for _, a := range d.Args {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return ArgDef{}, false
+}
+
+func (d *NodeTypeDef) Input(name string) (SlotDef, bool) {
+	return findSlot(d.Inputs, name)
+}
+
+func (d *NodeTypeDef) Output(name string) (SlotDef, bool) {
+	return findSlot(d.Outputs, name)
+}
+
+func findSlot(slots []SlotDef, name string) (SlotDef, bool) {
+	for _, s := range slots {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return SlotDef{}, false
+}
+
+func slotNames(slots []SlotDef) []string {
+	names := make([]string, 0, len(slots))
+	for _, s := range slots {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// Catalog ////////////////////////////////////////////////////////////////////
+
+type Catalog struct {
+	types map[string]*NodeTypeDef
+}
+
+func New() *Catalog {
+	return &Catalog{types: map[string]*NodeTypeDef{}}
+}
+
+// MustNew builds a catalog from definitions that are known to be valid,
+// panicking otherwise.
+func MustNew(defs ...*NodeTypeDef) *Catalog {
+	c := New()
+	for _, def := range defs {
+		if err := c.Add(def); err != nil {
+			panic(err)
+		}
+	}
+	return c
+}
+
+func (c *Catalog) Add(def *NodeTypeDef) error {
+	if def.Name == "" {
+		return fmt.Errorf("node type has no name")
+	} else if _, have := c.types[def.Name]; have {
+		return fmt.Errorf("node type '%s' already defined", def.Name)
+	}
+	c.types[def.Name] = def
+	return nil
+}
+
+// Set adds a definition, replacing any existing definition with the same
+// name.
+func (c *Catalog) Set(def *NodeTypeDef) {
+	c.types[def.Name] = def
+}
+
+// Merge adds every definition from another catalog, replacing definitions
+// with the same name.
+func (c *Catalog) Merge(other *Catalog) {
+	for _, def := range other.types {
+		c.Set(def)
+	}
+}
+
+func (c *Catalog) Lookup(name string) (*NodeTypeDef, bool) {
+	def, ok := c.types[name]
+	return def, ok
+}
+
+// Names returns the name of every node type, sorted.
+func (c *Catalog) Names() []string {
+	names := make([]string, 0, len(c.types))
+	for n := range c.types {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PureTypes returns the name of every pure node type, sorted.
+func (c *Catalog) PureTypes() []string {
+	names := []string{}
+	for _, n := range c.Names() {
+		if c.types[n].Pure {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// NodeTypes returns the node type for every definition, keyed by name.
+func (c *Catalog) NodeTypes() map[string]*object.NodeType {
+	types := map[string]*object.NodeType{}
+	for name, def := range c.types {
+		types[name] = def.NodeType()
+	}
+	return types
+}
+
+// Resolve returns the node type for an internal, which makes a catalog usable
+// as an evaluator's resolver.
+func (c *Catalog) Resolve(name string) (object.Object, error) {
+	if def, ok := c.types[name]; ok {
+		return def.NodeType(), nil
+	}
+	return nil, eval.UnknownInternal(name)
+}
@@ -0,0 +1,218 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nirosys/stitch/object"
+	"gopkg.in/yaml.v2"
+)
+
+// Manifests describe node types in YAML, or JSON:
+//
+//	types:
+//	  - name: snmp:get
+//	    doc: Gets the value of an OID for each input.
+//	    args:
+//	      - name: oid
+//	        type: string
+//	      - name: timeout
+//	        type: int
+//	        default: 5
+//	    inputs: [Input]
+//	    outputs:
+//	      - name: Output
+//	        schema:
+//	          - {name: value, type: any}
+//	      - Error
+//
+// Arguments without a default are required. Slots can be given as just their
+// name, and inputs are only required when marked with `required: true`. Types
+// marked `pure: true` have no side effects, so the prune pass can remove them.
+type manifest struct {
+	Types []manifestType `yaml:"types" json:"types"`
+}
+
+type manifestType struct {
+	Name    string         `yaml:"name" json:"name"`
+	Doc     string         `yaml:"doc" json:"doc"`
+	Pure    bool           `yaml:"pure" json:"pure"`
+	Args    []manifestArg  `yaml:"args" json:"args"`
+	Inputs  []manifestSlot `yaml:"inputs" json:"inputs"`
+	Outputs []manifestSlot `yaml:"outputs" json:"outputs"`
+}
+
+type manifestArg struct {
+	Name    string      `yaml:"name" json:"name"`
+	Doc     string      `yaml:"doc" json:"doc"`
+	Type    string      `yaml:"type" json:"type"`
+	Default interface{} `yaml:"default" json:"default"`
+}
+
+type manifestSlot struct {
+	Name     string     `yaml:"name" json:"name"`
+	Doc      string     `yaml:"doc" json:"doc"`
+	Required bool       `yaml:"required" json:"required"`
+	Schema   []FieldDef `yaml:"schema" json:"schema"`
+}
+
+// Allows a slot to be written as just its name.
+func (s *manifestSlot) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		s.Name = name
+		return nil
+	}
+	type plain manifestSlot
+	return unmarshal((*plain)(s))
+}
+
+func (s *manifestSlot) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		s.Name = name
+		return nil
+	}
+	type plain manifestSlot
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*plain)(s))
+}
+
+// Load reads a manifest into the catalog, replacing any definitions with the
+// same name. The decoder is chosen based on the file extension, with anything
+// other than ".json" read as YAML.
+func (c *Catalog) Load(r io.Reader, filename string) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	m := &manifest{}
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(m)
+	} else {
+		err = yaml.UnmarshalStrict(data, m)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	loaded := New()
+	for i, mt := range m.Types {
+		def, err := mt.definition()
+		if err != nil {
+			return fmt.Errorf("%s: type %d: %w", filename, i, err)
+		} else if err := loaded.Add(def); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	c.Merge(loaded)
+	return nil
+}
+
+// LoadFile reads a manifest file into the catalog.
+func (c *Catalog) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Load(f, path)
+}
+
+func (mt *manifestType) definition() (*NodeTypeDef, error) {
+	if mt.Name == "" {
+		return nil, fmt.Errorf("node type has no name")
+	}
+	def := &NodeTypeDef{Name: mt.Name, Doc: mt.Doc, Pure: mt.Pure}
+
+	for _, ma := range mt.Args {
+		if ma.Name == "" {
+			return nil, fmt.Errorf("%s: argument has no name", mt.Name)
+		}
+		kind := Kind(ma.Type)
+		if ma.Type == "" {
+			kind = KindAny
+		} else if !kind.valid() {
+			return nil, fmt.Errorf("%s: argument '%s' has unknown type '%s'", mt.Name, ma.Name, ma.Type)
+		}
+		arg := ArgDef{Name: ma.Name, Doc: ma.Doc, Kind: kind, Required: ma.Default == nil}
+		if ma.Default != nil {
+			if obj, err := defaultValue(kind, ma.Default); err != nil {
+				return nil, fmt.Errorf("%s: argument '%s': %w", mt.Name, ma.Name, err)
+			} else {
+				arg.Default = obj
+			}
+		} else if len(def.Args) > 0 && !def.Args[len(def.Args)-1].Required {
+			// Arguments are positional, so only trailing arguments can be
+			// left off.
+			return nil, fmt.Errorf("%s: required argument '%s' follows an argument with a default", mt.Name, ma.Name)
+		}
+		def.Args = append(def.Args, arg)
+	}
+
+	for _, slots := range []struct {
+		from []manifestSlot
+		to   *[]SlotDef
+	}{{mt.Inputs, &def.Inputs}, {mt.Outputs, &def.Outputs}} {
+		for _, ms := range slots.from {
+			if ms.Name == "" {
+				return nil, fmt.Errorf("%s: slot has no name", mt.Name)
+			}
+			*slots.to = append(*slots.to, SlotDef{
+				Name:     ms.Name,
+				Doc:      ms.Doc,
+				Required: ms.Required,
+				Schema:   ms.Schema,
+			})
+		}
+	}
+	return def, nil
+}
+
+func (k Kind) valid() bool {
+	switch k {
+	case KindAny, KindString, KindInt, KindBool, KindOID:
+		return true
+	}
+	return false
+}
+
+// Converts a decoded default value to its object. JSON decodes every number
+// as a float64, so integral floats are accepted as ints.
+func defaultValue(kind Kind, value interface{}) (object.Object, error) {
+	var obj object.Object
+	switch t := value.(type) {
+	case string:
+		obj = &object.String{Value: t}
+	case bool:
+		obj = object.NewBoolObject(t)
+	case int:
+		obj = &object.Integer{Value: int64(t)}
+	case int64:
+		obj = &object.Integer{Value: t}
+	case uint64:
+		obj = &object.Integer{Value: int64(t)}
+	case float64:
+		if t != math.Trunc(t) {
+			return nil, fmt.Errorf("non-integer default %v not supported", t)
+		}
+		obj = &object.Integer{Value: int64(t)}
+	default:
+		return nil, fmt.Errorf("default of type %T not supported", value)
+	}
+	if !kind.Accepts(obj) {
+		return nil, fmt.Errorf("default must be %s, found %s", kind, obj.Type())
+	}
+	return obj, nil
+}
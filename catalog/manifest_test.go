@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nirosys/stitch/object"
+)
+
+func Test_LoadManifest(t *testing.T) {
+	tests := []struct {
+		filename string
+		src      string
+	}{
+		{"types.yaml", `
+types:
+  - name: snmp:table
+    doc: Reads a table.
+    pure: true
+    args:
+      - {name: oid, type: string}
+      - {name: retries, type: int, default: 3}
+    inputs: [Input]
+    outputs:
+      - name: Output
+        schema: [{name: row, type: map}]
+      - Error
+`},
+		{"types.json", `{"types": [{
+	"name": "snmp:table",
+	"doc": "Reads a table.",
+	"pure": true,
+	"args": [{"name": "oid", "type": "string"}, {"name": "retries", "type": "int", "default": 3}],
+	"inputs": ["Input"],
+	"outputs": [{"name": "Output", "schema": [{"name": "row", "type": "map"}]}, "Error"]
+}]}`},
+	}
+
+	for i, test := range tests {
+		c := Standard()
+		if err := c.Load(strings.NewReader(test.src), test.filename); err != nil {
+			t.Fatalf("[%d] %s", i, err)
+		}
+		def, ok := c.Lookup("snmp:table")
+		if !ok {
+			t.Fatalf("[%d] snmp:table not loaded", i)
+		} else if _, ok := c.Lookup("snmp:get"); !ok {
+			t.Errorf("[%d] standard types lost", i)
+		}
+
+		if pure := c.PureTypes(); strings.Join(pure, ",") != "snmp:table,std:passthru" {
+			t.Errorf("[%d] unexpected pure types: %v", i, pure)
+		}
+		if def.Doc != "Reads a table." || len(def.Args) != 2 || len(def.Inputs) != 1 || len(def.Outputs) != 2 {
+			t.Errorf("[%d] unexpected definition: %+v", i, def)
+		} else if !def.Args[0].Required || def.Args[1].Required {
+			t.Errorf("[%d] expected only 'oid' to be required", i)
+		} else if len(def.Outputs[0].Schema) != 1 || def.Outputs[0].Schema[0].Name != "row" {
+			t.Errorf("[%d] unexpected schema: %+v", i, def.Outputs[0].Schema)
+		}
+
+		// Defaulted arguments can be left off.
+		obj, err := def.NodeType().Construct([]object.Object{&object.String{Value: "ifTable"}})
+		if err != nil {
+			t.Fatalf("[%d] %s", i, err)
+		}
+		if args := obj.(*object.Node).Arguments; len(args) != 2 || args[1].Inspect() != "3" {
+			t.Errorf("[%d] default not applied: %v", i, args)
+		}
+	}
+}
+
+func Test_LoadManifestInvalid(t *testing.T) {
+	tests := []string{
+		"types:\n  - doc: no name\n",
+		"types:\n  - name: a\n    args: [{name: x, type: float}]\n",
+		"types:\n  - name: a\n    args: [{name: x, type: int, default: yes}]\n",
+		"types:\n  - name: a\n    args: [{name: x, default: 1}, {name: y}]\n",
+		"types:\n  - name: a\n  - name: a\n",
+		"types:\n  - name: a\n    colour: blue\n",
+	}
+	for i, src := range tests {
+		if err := New().Load(strings.NewReader(src), "types.yaml"); err == nil {
+			t.Errorf("[%d] expected error loading manifest", i)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package catalog
+
+import (
+	"strings"
+)
+
+// The node types every gaufre runtime provides. Runtimes with more node types
+// publish their own manifests, which are loaded on top of these.
+const standardManifest = `
+types:
+  - name: snmp:get
+    doc: Gets the value of an OID, once for each input.
+    args:
+      - name: oid
+        type: oid
+        doc: OID to get, templated with the input (eg. "ifInOctets.{{ .Input.Key }}").
+    inputs: [Input]
+    outputs:
+      - name: Output
+        schema:
+          - {name: oid, type: string}
+          - {name: value, type: any}
+      - name: Error
+        doc: Requests that failed.
+      - name: Missing
+        doc: OIDs the agent has no value for.
+
+  - name: snmp:walk
+    doc: Walks the OID tree, sending each value found.
+    args:
+      - name: oid
+        type: oid
+        doc: Root of the tree to walk.
+    inputs: [Input]
+    outputs:
+      - name: Output
+        schema:
+          - {name: oid, type: string}
+          - {name: key, type: string, doc: OID suffix below the root.}
+          - {name: value, type: any}
+      - name: Error
+        doc: Requests that failed.
+
+  - name: std:passthru
+    doc: Sends its input on unchanged.
+    pure: true
+    inputs: [Input]
+    outputs: [Output]
+`
+
+// Standard returns a new catalog holding the standard node types.
+func Standard() *Catalog {
+	c := New()
+	if err := c.Load(strings.NewReader(standardManifest), "standard.yaml"); err != nil {
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,132 @@
+package catalog
+
+import (
+	"sort"
+
+	"github.com/nirosys/stitch/diagnostic"
+	"github.com/nirosys/stitch/object"
+)
+
+// Validate checks every node reachable from the roots against the catalog:
+// the node's type must exist, its arguments must be present and of the right
+// kind, its required inputs must be connected, and every slot used in a
+// connection must be declared. Every problem found is returned, rather than
+// only the first.
+//
+// The roots are fed by the runtime, so their inputs are never required.
+func (c *Catalog) Validate(roots []*object.Node) []diagnostic.Diagnostic {
+	v := &validator{catalog: c, upstream: map[*object.Node]map[string]bool{}}
+	visited := map[*object.Node]bool{}
+	for _, root := range roots {
+		v.visit(root, visited)
+	}
+	for _, n := range v.nodes {
+		v.checkNode(n, containsNode(roots, n))
+	}
+	return v.diags
+}
+
+type validator struct {
+	catalog  *Catalog
+	nodes    []*object.Node
+	upstream map[*object.Node]map[string]bool // Connected input slots of each node.
+	diags    []diagnostic.Diagnostic
+}
+
+func (v *validator) visit(n *object.Node, visited map[*object.Node]bool) {
+	if visited[n] {
+		return
+	}
+	visited[n] = true
+	v.nodes = append(v.nodes, n)
+
+	conns := n.GetConnections()
+	sort.SliceStable(conns, func(i, j int) bool {
+		return conns[i].Start.Name < conns[j].Start.Name
+	})
+	for _, conn := range conns {
+		if v.upstream[conn.End.Node] == nil {
+			v.upstream[conn.End.Node] = map[string]bool{}
+		}
+		v.upstream[conn.End.Node][conn.End.Name] = true
+		v.visit(conn.End.Node, visited)
+	}
+}
+
+func (v *validator) errorf(n *object.Node, format string, args ...interface{}) {
+	v.diags = append(v.diags, diagnostic.Errorf(n.Position, format, args...))
+}
+
+func (v *validator) checkNode(n *object.Node, isRoot bool) {
+	if n.NodeType == nil {
+		v.errorf(n, "node has no type, and can't be run by gaufre")
+		return
+	}
+	def, ok := v.catalog.Lookup(n.NodeType.Name)
+	if !ok {
+		v.errorf(n, "unknown node type '%s'", n.NodeType.Name)
+		return
+	}
+
+	// Arguments are matched up by the names the node type declared them with.
+	given := map[string]object.Object{}
+	for i, param := range n.NodeType.NodeArgs {
+		name := param.Identifier.String()
+		if i >= len(n.Arguments) {
+			break
+		} else if _, known := def.Arg(name); !known {
+			v.errorf(n, "%s: unknown argument '%s'", def.Name, name)
+			continue
+		}
+		given[name] = n.Arguments[i]
+	}
+	for _, arg := range def.Args {
+		if value, have := given[arg.Name]; !have {
+			if arg.Required {
+				v.errorf(n, "%s: missing required argument '%s'", def.Name, arg.Name)
+			}
+		} else if !arg.Kind.Accepts(value) {
+			v.errorf(n, "%s: argument '%s' must be %s, found %s", def.Name, arg.Name, arg.Kind, value.Type())
+		}
+	}
+
+	if !isRoot {
+		for _, in := range def.Inputs {
+			if in.Required && !v.upstream[n][in.Name] {
+				v.errorf(n, "%s: required input '%s' is not connected", def.Name, in.Name)
+			}
+		}
+	}
+	for _, slot := range sortedSet(v.upstream[n]) {
+		if _, ok := def.Input(slot); !ok {
+			v.errorf(n, "%s: no input slot '%s'", def.Name, slot)
+		}
+	}
+	outputs := map[string]bool{}
+	for _, conn := range n.GetConnections() {
+		outputs[conn.Start.Name] = true
+	}
+	for _, slot := range sortedSet(outputs) {
+		if _, ok := def.Output(slot); !ok {
+			v.errorf(n, "%s: no output slot '%s'", def.Name, slot)
+		}
+	}
+}
+
+func sortedSet(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for n := range set {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsNode(nodes []*object.Node, n *object.Node) bool {
+	for _, v := range nodes {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/nirosys/stitch/object"
+)
+
+var testCatalog = MustNew(
+	&NodeTypeDef{
+		Name:    "snmp:get",
+		Args:    []ArgDef{{Name: "oid", Kind: KindString, Required: true}},
+		Inputs:  []SlotDef{{Name: "Input", Required: true}},
+		Outputs: []SlotDef{{Name: "Output"}, {Name: "Error"}},
+	},
+	&NodeTypeDef{
+		Name:    "std:join",
+		Inputs:  []SlotDef{{Name: "Left", Required: true}, {Name: "Right", Required: true}},
+		Outputs: []SlotDef{{Name: "Output"}},
+	},
+)
+
+func construct(t *testing.T, nt *object.NodeType, args ...object.Object) *object.Node {
+	obj, err := nt.Construct(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*object.Node)
+}
+
+func Test_Validate(t *testing.T) {
+	get, _ := testCatalog.Lookup("snmp:get")
+	join, _ := testCatalog.Lookup("std:join")
+
+	root := construct(t, get.NodeType(), &object.String{Value: "ifType"})
+	badArg := construct(t, get.NodeType(), &object.Integer{Value: 1})
+	// Nodes built from another manifest's snmp:get, which took no arguments,
+	// are missing the ones the catalog requires.
+	older := &NodeTypeDef{Name: "snmp:get", Inputs: get.Inputs, Outputs: get.Outputs}
+	missingArg := construct(t, older.NodeType())
+	joined := construct(t, join.NodeType())
+	unknown := construct(t, &object.NodeType{Name: "std:nope", InputSlots: []string{"Input"}})
+
+	root.ConnectSlots("Output", badArg.GetSlot("Input"))
+	root.ConnectSlots("Output", missingArg.GetSlot("Input"))
+	root.ConnectSlots("Error", joined.GetSlot("Left"))
+	root.ConnectSlots("Missing", unknown.GetSlot("Input"))
+
+	expected := []string{
+		"line 0 column 0: error: snmp:get: no output slot 'Missing'",
+		"line 0 column 0: error: std:join: required input 'Right' is not connected",
+		"line 0 column 0: error: unknown node type 'std:nope'",
+		"line 0 column 0: error: snmp:get: argument 'oid' must be string, found INTEGER",
+		"line 0 column 0: error: snmp:get: missing required argument 'oid'",
+	}
+	diags := testCatalog.Validate([]*object.Node{root})
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics, found %d: %v", len(expected), len(diags), diags)
+	}
+	for i, d := range diags {
+		if d.String() != expected[i] {
+			t.Errorf("[%d] expected %q, found %q", i, expected[i], d.String())
+		}
+	}
+}
+
+func Test_KindAccepts(t *testing.T) {
+	oid, _ := object.NewOID("1.3.6.1.2.1.1.3.0")
+	ip, _ := object.NewIP("10.0.0.1")
+	tests := []struct {
+		kind     Kind
+		value    object.Object
+		expected bool
+	}{
+		{KindOID, oid, true},
+		{KindOID, &object.String{Value: "1.3.6.1.2.1.1.3.0"}, true},
+		{KindOID, &object.String{Value: ".1.3.6.1"}, true},
+		{KindOID, &object.String{Value: "ifInOctets.{{ .Input.Key }}"}, true},
+		{KindOID, &object.String{Value: "ifInOctets"}, true},
+		{KindOID, &object.String{Value: "1.3..6"}, false},
+		{KindOID, &object.String{Value: "1.3.6.x"}, false},
+		{KindOID, &object.String{Value: ""}, false},
+		{KindOID, &object.Integer{Value: 1}, false},
+		{KindOID, ip, false},
+		{KindString, oid, true},
+		{KindString, ip, true},
+		{KindAny, ip, true},
+		{KindInt, oid, false},
+	}
+	for i, test := range tests {
+		if accepted := test.kind.Accepts(test.value); accepted != test.expected {
+			t.Errorf("[%d] expected %s accepting %s to be %v", i, test.kind, test.value.Inspect(), test.expected)
+		}
+	}
+}
@@ -1,32 +1,39 @@
 package subcmd
 
-// TODO: This subcmd needs to be reworked..
-
 import (
-	//	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 
 	"github.com/nirosys/stitch"
+	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal"
+	"github.com/nirosys/stitch/export"
+	"github.com/nirosys/stitch/optimize"
 
 	"github.com/spf13/cobra"
 )
 
 var compileCmd = &cobra.Command{
-	Use:   "compile",
-	Short: "Compile a stitch program to a gaufre graph.",
+	Use:   "compile <file>",
+	Short: "Compile a stitch program to a gaufre graph, or another graph format.",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return errors.New("invalid arguments")
 		}
 		return nil
 	},
-	RunE: compile,
+	SilenceUsage: true,
+	RunE:         compile,
 }
 
 func init() {
+	compileCmd.Flags().StringP("format", "f", "gaufre", fmt.Sprintf("Output format %v", export.Formats()))
+	compileCmd.Flags().StringP("root", "r", "", "Identifier of the root node (defaults to the program's result)")
+	compileCmd.Flags().StringSliceP("optimize", "O", nil, fmt.Sprintf("Optimization passes to run %v, or 'all'", optimize.Names()))
+	compileCmd.Flags().Bool("validate", true, "Validate the graph against the node type catalog")
 	RootCmd.AddCommand(compileCmd)
 }
 
@@ -36,32 +43,58 @@ func compile(cmd *cobra.Command, args []string) error {
 	if args[0] == "-" {
 		source = os.Stdin
 	} else if f, err := os.Open(args[0]); err != nil {
-		fmt.Printf("ERROR Opening File: %s\n", err.Error())
-		return nil
+		return err
 	} else {
+		defer f.Close()
 		source = f
 	}
-	prog := stitch.NewProgram(source)
-	if prog == nil {
-		fmt.Printf("ERROR\n")
-		for _, e := range prog.Errors() {
-			fmt.Printf("   %s\n", e)
+	code, err := ioutil.ReadAll(source)
+	if err != nil {
+		return err
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	exporter, err := export.Lookup(format)
+	if err != nil {
+		return err
+	}
+
+	cat, err := loadCatalog(cmd)
+	if err != nil {
+		return err
+	}
+
+	passes, _ := cmd.Flags().GetStringSlice("optimize")
+	if len(passes) == 1 && passes[0] == "all" {
+		passes = optimize.Names()
+	}
+	root, _ := cmd.Flags().GetString("root")
+	validate, _ := cmd.Flags().GetBool("validate")
+
+	sources := []stitch.Source{{Name: args[0], Code: string(code)}}
+	result, err := stitch.Compile(context.Background(), sources, stitch.Options{
+		Resolver:   internal.NewResolver(cat),
+		Catalog:    cat,
+		NoValidate: !validate,
+		Root:       root,
+		Optimize:   passes,
+	})
+	// Diagnostics and changes are reported on stderr, so they don't end up in
+	// the graph.
+	if result != nil {
+		for _, d := range result.Diagnostics {
+			fmt.Fprintln(os.Stderr, d)
 		}
-	} else {
-		//eval := stitch.NewEvaluator()
-		//eval.Resolver = internal.NewResolver()
-		//if graph, err := eval.Compile(prog); err != nil {
-		//	fmt.Printf("ERROR: %s\n", err.Error())
-		//	return nil
-		//} else {
-		//	b, err := json.Marshal(graph)
-		//	if err != nil {
-		//		fmt.Printf("ERROR: %s\n", err.Error())
-		//		return nil
-		//	}
-		//	os.Stdout.Write(b)
-		//}
+		for _, change := range result.Changes {
+			fmt.Fprintf(os.Stderr, "optimize: %s\n", change)
+		}
+	}
+	var compileErr *stitch.CompileError
+	if errors.As(err, &compileErr) {
+		return fmt.Errorf("unable to compile '%s'", args[0])
+	} else if err != nil {
+		return err
 	}
 
-	return nil
+	return exporter.Export(os.Stdout, result.Graph)
 }
@@ -2,14 +2,18 @@ package subcmd
 
 import (
 	"errors"
-	//"fmt"
-	//"os"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nirosys/stitch/format"
+	"github.com/nirosys/stitch/parsing"
 
 	"github.com/spf13/cobra"
 )
 
 var fmtCmd = &cobra.Command{
-	Use:   "fmt",
+	Use:   "fmt <file>",
 	Short: "Format a stitch program",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
@@ -17,23 +21,25 @@ var fmtCmd = &cobra.Command{
 		}
 		return nil
 	},
-	RunE: format,
+	SilenceUsage: true,
+	RunE:         formatSource,
 }
 
 func init() {
 	RootCmd.AddCommand(fmtCmd)
 }
 
-func format(cmd *cobra.Command, args []string) error {
-	/*
-		if f, err := os.Open(args[0]); err != nil {
-			fmt.Printf("ERROR Opening File: %s\n", err.Error())
-			return nil
-		} else {
-			//parser := parsing.NewParser(f)
-			//prog := parser.ParseProgram()
-			//os.Stdout.WriteString(prog.String())
-		}
-	*/
-	return nil
+func formatSource(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	parser := parsing.NewParser(f)
+	tree := parser.Parse()
+	if tree == nil {
+		return fmt.Errorf("unable to parse '%s':\n   %s", args[0], strings.Join(parser.Errors(), "\n   "))
+	}
+	return format.Fprint(os.Stdout, tree)
 }
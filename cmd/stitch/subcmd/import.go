@@ -0,0 +1,73 @@
+package subcmd
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/nirosys/stitch/catalog"
+	"github.com/nirosys/stitch/importing"
+
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import-graph <file>",
+	Short: "Convert a gaufre JSON graph, or legacy YAML config, into stitch source.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("invalid arguments")
+		}
+		return nil
+	},
+	SilenceUsage: true,
+	RunE:         importGraph,
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+}
+
+func importGraph(cmd *cobra.Command, args []string) error {
+	var source io.Reader
+	if args[0] == "-" {
+		source = os.Stdin
+	} else if f, err := os.Open(args[0]); err != nil {
+		return err
+	} else {
+		defer f.Close()
+		source = f
+	}
+
+	g, err := importing.Load(source, args[0])
+	if err != nil {
+		return err
+	}
+
+	cat, err := loadCatalog(cmd)
+	if err != nil {
+		return err
+	}
+
+	src, err := g.Source(importing.Options{ArgOrder: catalogArgOrder(cat)})
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.WriteString(src)
+	return err
+}
+
+// Arguments are positional, so they're written in the order the catalog
+// declares them.
+func catalogArgOrder(c *catalog.Catalog) func(string) []string {
+	return func(nodeType string) []string {
+		if def, ok := c.Lookup(nodeType); ok {
+			order := make([]string, 0, len(def.Args))
+			for _, arg := range def.Args {
+				order = append(order, arg.Name)
+			}
+			return order
+		}
+		return nil
+	}
+}
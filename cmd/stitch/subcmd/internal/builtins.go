@@ -3,108 +3,36 @@ package internal
 import (
 	"fmt"
 
-	"github.com/nirosys/stitch/ast"
-	"github.com/nirosys/stitch/object"
+	"github.com/nirosys/stitch/catalog"
+	"github.com/nirosys/stitch/eval"
+	"github.com/nirosys/stitch/host"
 )
 
-var HostedFuncs = map[string]*object.NativeFunction{
-	"std:println": &object.NativeFunction{
-		Fn: builtin_println,
-		Params: []*ast.FunctionParameter{
-			&ast.FunctionParameter{Identifier: &ast.Identifier{Identifier: "msg"}},
-		},
-		//Params: []object.ObjectType{object.StringObjectType},
-	}}
+// HostedFuncs are the functions available to programs as internals: the
+// standard host library, and a few more for the command line.
+var HostedFuncs = hostedLibrary()
 
-var HostedNodeTypes = map[string]*object.NodeType{
-	"snmp:get": &object.NodeType{
-		Name: "snmp:get", NodeArgs: []*ast.FunctionParameter{
-			&ast.FunctionParameter{
-				Identifier: &ast.Identifier{Identifier: "oid"},
-			},
-		},
-		InputSlots:  []string{"Input"},
-		OutputSlots: []string{"Output", "Error", "Missing"},
-	},
-	"snmp:walk": &object.NodeType{
-		Name: "snmp:walk",
-		NodeArgs: []*ast.FunctionParameter{
-			&ast.FunctionParameter{
-				Identifier: &ast.Identifier{Identifier: "oid"},
-			},
-		},
-		InputSlots:  []string{"Input"},
-		OutputSlots: []string{"Output", "Error"},
-	},
-	"std:passthru": &object.NodeType{
-		Name:        "std:passthru",
-		NodeArgs:    []*ast.FunctionParameter{},
-		InputSlots:  []string{"Input"},
-		OutputSlots: []string{"Output"},
-	},
+func hostedLibrary() *host.Library {
+	lib := host.Standard()
+	lib.MustRegisterFunc("std:println", func(msg string) { fmt.Printf("%s\n", msg) }, "msg")
+	lib.MustRegisterFunc("std:strlen", func(s string) int { return len(s) }, "s")
+	return lib
 }
 
-type Resolver struct{}
-
-func NewResolver() *Resolver {
-	return &Resolver{}
-}
-
-func (r *Resolver) Resolve(name string) (object.Object, error) {
-	if fn, ok := HostedFuncs[name]; ok {
-		return &object.InternalFunction{Fn: fn, Env: nil}, nil
-	} else if nodeType, ok := HostedNodeTypes[name]; ok {
-		return nodeType, nil
-	} else {
-		return nil, fmt.Errorf("unknown internal \"%s\"", name)
-	}
-}
-
-// TODO: Need to use reflection to handle these..
-func builtin_println(env *object.Environment, args []object.Object) (object.Object, error) {
-	if len(args) != 1 {
-		return nil, fmt.Errorf("invalid number of arguments")
-	}
-	msg := args[0]
-	if msg.Type() != object.StringObjectType {
-		return nil, fmt.Errorf("type mismatch: expected STRING, received %s", msg.Type())
-	}
-	s, _ := msg.(*object.String)
-
-	fmt.Printf("%s\n", s.Value)
-	return nil, nil
+// NewResolver resolves internals to the hosted functions, or to the node
+// types of a catalog.
+func NewResolver(c *catalog.Catalog) *eval.ChainResolver {
+	return eval.Chain(HostedFuncs, c)
 }
 
-func builtin_snmpget(env *object.Environment, args []object.Object) (object.Object, error) {
-	if len(args) != 1 {
-		return nil, fmt.Errorf("invalid number of arguments")
+// LoadCatalog returns the standard catalog, extended with the node types from
+// each manifest.
+func LoadCatalog(paths []string) (*catalog.Catalog, error) {
+	c := catalog.Standard()
+	for _, path := range paths {
+		if err := c.LoadFile(path); err != nil {
+			return nil, err
+		}
 	}
-	msg := args[0]
-	if msg.Type() != object.StringObjectType {
-		return nil, fmt.Errorf("type mismatch: expected STRING, received %s", msg.Type())
-	}
-
-	node := object.NewNode()
-	node.Arguments = args
-	node.InputSlots = map[string]struct{}{"Input": struct{}{}}
-	node.OutputSlots = map[string]struct{}{"Output": struct{}{}, "Error": struct{}{}}
-
-	return node, nil
-}
-
-func builtin_snmpwalk(env *object.Environment, args []object.Object) (object.Object, error) {
-	if len(args) != 1 {
-		return nil, fmt.Errorf("invalid number of arguments")
-	}
-	msg := args[0]
-	if msg.Type() != object.StringObjectType {
-		return nil, fmt.Errorf("type mismatch: expected STRING, received %s", msg.Type())
-	}
-
-	node := object.NewNode()
-	node.Arguments = args
-	node.InputSlots = map[string]struct{}{"Input": struct{}{}}
-	node.OutputSlots = map[string]struct{}{"Output": struct{}{}, "Error": struct{}{}}
-
-	return node, nil
+	return c, nil
 }
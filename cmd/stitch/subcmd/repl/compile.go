@@ -2,10 +2,12 @@ package repl
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal/shellcmd"
+	"github.com/nirosys/stitch/diagnostic"
 	"github.com/nirosys/stitch/object"
 )
 
@@ -23,7 +25,9 @@ func (r *Repl) compile(cmd *shellcmd.Command, args []string) error {
 		if obj, have := r.env.Get(args[0]); !have {
 			fmt.Printf("invalid identifier: '%s'", args[0])
 		} else if node, ok := obj.(*object.Node); ok {
-			if g, err := r.evaluator.CompileObject(node); err != nil {
+			if err := r.validate(node); err != nil {
+				return err
+			} else if g, err := r.evaluator.CompileObject(node); err != nil {
 				return err
 			} else if b, err := json.Marshal(g); err != nil {
 				return err
@@ -35,3 +39,16 @@ func (r *Repl) compile(cmd *shellcmd.Command, args []string) error {
 	}
 	return nil
 }
+
+// Validates the graph rooted at the node against the catalog, printing every
+// diagnostic found.
+func (r *Repl) validate(node *object.Node) error {
+	diags := r.catalog.Validate([]*object.Node{node})
+	for _, d := range diags {
+		fmt.Printf("%s\n", d)
+	}
+	if diagnostic.HasErrors(diags) {
+		return errors.New("graph failed validation")
+	}
+	return nil
+}
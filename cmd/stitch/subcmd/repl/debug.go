@@ -0,0 +1,104 @@
+package repl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal/shellcmd"
+	"github.com/nirosys/stitch/eval"
+	"github.com/nirosys/stitch/object"
+)
+
+func (r *Repl) debugCommand() *shellcmd.Command {
+	var debugCommand = &shellcmd.Command{
+		Use:   "debug",
+		Short: "Toggle stepping through the statements of each evaluation",
+		RunE: func(cmd *shellcmd.Command, args []string) error {
+			r.debug = !r.debug
+			fmt.Printf("Debug mode: %t\n", r.debug)
+			if r.debug {
+				fmt.Printf("At each statement: <enter> steps, 'p <ident>' prints, 'c' continues, 'q' stops\n")
+			}
+			return nil
+		},
+	}
+	return debugCommand
+}
+
+// debugHook pauses before each statement, of the program or of any block,
+// until told to step or continue.
+type debugHook struct {
+	eval.BaseHook
+	repl     *Repl
+	nodes    []ast.Node // The nodes being evaluated, innermost last.
+	finished bool       // Continue to the end, without pausing.
+}
+
+// Statements are the nodes evaluated directly by a program, or a block.
+func (d *debugHook) isStatement() bool {
+	if len(d.nodes) == 0 {
+		return true
+	}
+	_, ok := d.nodes[len(d.nodes)-1].(*ast.BlockExpression)
+	return ok
+}
+
+func (d *debugHook) Enter(n ast.Node, env *object.Environment) error {
+	pause := !d.finished && d.isStatement()
+	d.nodes = append(d.nodes, n)
+	if !pause {
+		return nil
+	}
+
+	pos := ast.PositionOf(n)
+	fmt.Printf("line %d: %s\n", pos.Line, n.String())
+	for {
+		line, err := d.repl.readLine("debug> ")
+		if err != nil {
+			return context.Canceled
+		}
+		cmd := strings.Fields(line)
+		switch {
+		case len(cmd) == 0 || cmd[0] == "s":
+			return nil
+		case cmd[0] == "c":
+			d.finished = true
+			return nil
+		case cmd[0] == "q":
+			return context.Canceled
+		case cmd[0] == "p" && len(cmd) == 2:
+			if obj, have := env.Get(cmd[1]); have {
+				fmt.Printf("%s\n", traceString(obj))
+			} else {
+				fmt.Printf("unknown identifier '%s'\n", cmd[1])
+			}
+		default:
+			fmt.Printf("<enter> steps, 'p <ident>' prints, 'c' continues, 'q' stops\n")
+		}
+	}
+}
+
+func (d *debugHook) Leave(n ast.Node, result object.Object, err error) {
+	d.nodes = d.nodes[:len(d.nodes)-1]
+}
+
+// Reads a line from the terminal, or from stdin when there's no session yet,
+// as when running the init script.
+func (r *Repl) readLine(prompt string) (string, error) {
+	if r.line != nil {
+		return r.line.Prompt(prompt)
+	}
+	if r.stdin == nil {
+		r.stdin = bufio.NewReader(os.Stdin)
+	}
+	fmt.Print(prompt)
+	line, err := r.stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
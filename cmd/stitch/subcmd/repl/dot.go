@@ -1,26 +1,58 @@
 package repl
 
 import (
+	"fmt"
 	"os"
 
-	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal"
 	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal/shellcmd"
+	"github.com/nirosys/stitch/export"
+	"github.com/nirosys/stitch/object"
 )
 
 func (r *Repl) dotCommand() *shellcmd.Command {
-	var dotCommand = &shellcmd.Command{
-		Use:   "dot [obj]",
-		Short: "Describe the graph in dot syntax",
+	return r.exportCommand("dot", "Describe the graph in dot syntax")
+}
+
+func (r *Repl) mermaidCommand() *shellcmd.Command {
+	return r.exportCommand("mermaid", "Describe the graph as a mermaid flowchart")
+}
+
+func (r *Repl) graphMLCommand() *shellcmd.Command {
+	return r.exportCommand("graphml", "Describe the graph in GraphML (yEd)")
+}
+
+func (r *Repl) irCommand() *shellcmd.Command {
+	return r.exportCommand("ir", "Describe the graph in the stitch JSON IR")
+}
+
+func (r *Repl) exportCommand(format, short string) *shellcmd.Command {
+	return &shellcmd.Command{
+		Use:   format + " [obj]",
+		Short: short,
 		RunE: func(cmd *shellcmd.Command, args []string) error {
-			trav := internal.NewTraverser(r.env)
-			if len(args) == 0 {
-				trav.RenderDotAll(os.Stdout)
-			} else {
-				// TODO: Implement me.
-			}
-			return nil
+			return r.exportGraph(format, args)
 		},
 	}
+}
+
+// Renders the whole environment, or only the graph rooted at the given
+// identifier.
+func (r *Repl) exportGraph(format string, args []string) error {
+	exporter, err := export.Lookup(format)
+	if err != nil {
+		return err
+	}
+
+	var roots []*object.Node
+	if len(args) == 0 || args[0] == "" {
+		roots = export.EnvironmentRoots(r.env)
+	} else if obj, have := r.env.Get(args[0]); !have {
+		return fmt.Errorf("invalid identifier: '%s'", args[0])
+	} else if node, ok := obj.(*object.Node); !ok {
+		return fmt.Errorf("'%s' is not a node object", args[0])
+	} else {
+		roots = []*object.Node{node}
+	}
 
-	return dotCommand
+	return exporter.Export(os.Stdout, export.NewGraph(roots))
 }
@@ -7,11 +7,12 @@ import (
 
 	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal"
 	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal/shellcmd"
+	"github.com/nirosys/stitch/eval"
 )
 
 func (r *Repl) listCommand() *shellcmd.Command {
 	var listCommand = &shellcmd.Command{
-		Use:   "ls [pkg]",
+		Use:   "ls [pkg|internal]",
 		Short: "List all objects in a scope.",
 		RunE:  r.list,
 	}
@@ -41,6 +42,11 @@ func (r *Repl) list(cmd *shellcmd.Command, args []string) error {
 
 	scope := r.env
 
+	// internal is a keyword, so it can't be the name of a package.
+	if len(args) > 0 && args[0] == "internal" {
+		return r.listInternals(table)
+	}
+
 	if len(args) > 0 { // assume we have a package name
 		pkg := args[0]
 		fmt.Printf("Listing package: %s\n", pkg)
@@ -76,3 +82,15 @@ func (r *Repl) list(cmd *shellcmd.Command, args []string) error {
 	}
 	return nil
 }
+
+// Lists every internal the evaluator's resolver can resolve.
+func (r *Repl) listInternals(table *internal.TableWriter) error {
+	for _, n := range eval.ResolverNames(r.evaluator.Resolver) {
+		if obj, err := r.evaluator.Resolver.Resolve(n); err == nil {
+			table.AddRow([]string{n, string(obj.Type())})
+		}
+	}
+	fmt.Printf("Internals:\n")
+	table.Write(os.Stdout)
+	return nil
+}
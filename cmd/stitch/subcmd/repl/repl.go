@@ -1,15 +1,22 @@
 package repl
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"unicode"
 
 	"github.com/nirosys/stitch"
 	"github.com/nirosys/stitch/analysis"
+	"github.com/nirosys/stitch/catalog"
 	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal"
 	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal/shellcmd"
 	"github.com/nirosys/stitch/eval"
@@ -50,28 +57,43 @@ func NewMatchCounts() MatchCounts {
 
 type Repl struct {
 	evaluator *eval.Evaluator
+	catalog   *catalog.Catalog
 	env       *object.Environment
 	symbols   *analysis.SymbolTable
 	commander *shellcmd.Parser
 	quiet     bool
+	trace     bool
+	debug     bool
 	matches   MatchCounts
 	inString  bool
 	escaped   bool
 	quit      bool
+
+	line  *liner.State  // The session's terminal, once it is running.
+	stdin *bufio.Reader // Read from when there is no session.
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // Cancels the running evaluation, if any.
 }
 
-func NewRepl() *Repl {
+func NewRepl(c *catalog.Catalog) *Repl {
 	repl := &Repl{
 		env:       object.NewEnvironment(),
 		evaluator: eval.NewEvaluator(),
+		catalog:   c,
 		commander: shellcmd.NewParser(),
 		quit:      false,
 	}
-	repl.evaluator.Resolver = repl
+	repl.evaluator.Resolver = internal.NewResolver(c)
 	repl.commander.Prefix = "."
 	repl.commander.AddCommand(repl.listCommand())
 	repl.commander.AddCommand(repl.quitCommand())
+	repl.commander.AddCommand(repl.traceCommand())
+	repl.commander.AddCommand(repl.debugCommand())
 	repl.commander.AddCommand(repl.dotCommand())
+	repl.commander.AddCommand(repl.mermaidCommand())
+	repl.commander.AddCommand(repl.graphMLCommand())
+	repl.commander.AddCommand(repl.irCommand())
 	repl.commander.AddCommand(repl.compileCommand())
 	repl.commander.AddCommand(&shellcmd.Command{
 		Use:   "quiet",
@@ -91,7 +113,9 @@ func (r *Repl) Run() error {
 
 	l := liner.NewLiner()
 	defer l.Close()
+	r.line = l
 	l.SetHighlighter(r)
+	l.SetWordCompleter(r.completeWord)
 
 	l.SetCtrlCAborts(true)
 	l.SetMultiLineMode(true)
@@ -126,7 +150,8 @@ REPLOOP:
 				tmpLines = append(tmpLines, line)
 			}
 
-			resultLine := strings.Join(tmpLines, "")
+			// Lines stay apart, for multi-line strings.
+			resultLine := strings.Join(tmpLines, "\n")
 			l.AppendHistory(resultLine)
 			tmpLines = nil
 
@@ -188,12 +213,9 @@ func (r *Repl) handleCommands(line string, l *liner.State) (bool, bool) {
 	case ".ls":
 		r.ListEnv(args)
 		return true, false
-	case ".dot":
-		trav := internal.NewTraverser(r.env)
-		if len(args) == 0 {
-			trav.RenderDotAll(os.Stdout)
-		} else {
-			//trav.RenderDot(os.Stdout, args[0])
+	case ".dot", ".mermaid", ".graphml", ".ir":
+		if err := r.exportGraph(strings.TrimPrefix(cmd, "."), args); err != nil {
+			fmt.Printf("ERROR: %s\n", err.Error())
 		}
 		return true, false
 	case ".quiet":
@@ -205,7 +227,9 @@ func (r *Repl) handleCommands(line string, l *liner.State) (bool, bool) {
 			if obj, have := r.env.Get(args[0]); !have {
 				fmt.Printf("invalid identifier: '%s'", args[0])
 			} else if node, ok := obj.(*object.Node); ok {
-				if g, err := r.evaluator.CompileObject(node); err != nil {
+				if err := r.validate(node); err != nil {
+					fmt.Printf("ERROR: %s\n", err.Error())
+				} else if g, err := r.evaluator.CompileObject(node); err != nil {
 					fmt.Printf("ERROR: %s\n", err.Error())
 				} else if b, err := json.Marshal(g); err != nil {
 					fmt.Printf("ERROR: %s\n", err.Error())
@@ -236,16 +260,38 @@ func (r *Repl) ExecuteCode(reader io.Reader) error {
 			fmt.Printf("   %s\n", e)
 		}
 	} else {
+		for _, w := range prog.Warnings() {
+			fmt.Printf("WARNING: %s\n", w)
+		}
 		r.symbols = prog.Symbols
-		if obj, err := r.evaluator.EvalProgram(prog, r.env); err != nil {
+		ctx, done := r.startEval()
+		defer done()
+		r.evaluator.Hooks = r.hooks()
+		if obj, err := r.evaluator.EvalProgram(ctx, prog.Tree, r.env); errors.Is(err, context.Canceled) {
+			fmt.Printf("Stopped\n")
+		} else if pos, ok := eval.ErrorPosition(err); ok {
+			fmt.Printf("ERROR: line %d column %d: %s\n", pos.Line, pos.Column, err.Error())
+		} else if err != nil {
 			fmt.Printf("ERROR: %s\n", err.Error())
-		} else if obj != nil && !r.quiet {
+		} else if obj != nil && obj != object.None && !r.quiet {
 			fmt.Printf("%s\n", obj.Inspect())
 		}
 	}
 	return nil
 }
 
+// Returns fresh hooks for the modes that are on.
+func (r *Repl) hooks() []eval.EvalHook {
+	hooks := []eval.EvalHook{}
+	if r.trace {
+		hooks = append(hooks, &traceHook{out: os.Stdout})
+	}
+	if r.debug {
+		hooks = append(hooks, &debugHook{repl: r})
+	}
+	return hooks
+}
+
 func (r *Repl) LoadFile(path string) error {
 	if f, err := os.Open(path); err != nil {
 		return err
@@ -262,6 +308,8 @@ func (r *Repl) Highlight(text string) (string, error) {
 	keywords := color.FgGreen.Render
 	strs := color.FgCyan.Render
 	literals := color.FgCyan.Render
+	interpolations := color.FgYellow.Render
+	templates := color.FgMagenta.Render
 
 	var buffer strings.Builder
 
@@ -276,10 +324,29 @@ func (r *Repl) Highlight(text string) (string, error) {
 		case tok.Type == lexing.L_INTEGER || tok.Type == lexing.K_TRUE || tok.Type == lexing.K_FALSE:
 			buffer.WriteString(literals(tok.Text))
 			offset += len(tok.Text)
-		case tok.Type == lexing.L_STRING:
-			buffer.WriteString(strs(fmt.Sprintf("\"%s\"", tok.Text)))
-			offset += len(tok.Text) + 2
-		case tok.Type >= lexing.K_LET && tok.Type <= lexing.K_IN:
+		case tok.Type == lexing.L_STRING || tok.Type == lexing.L_INTERP:
+			// Escapes make the text shorter than the source, so the source is
+			// taken up to where the lexer stopped.
+			end := lex.Position().Column
+			if end <= offset || end > len(text) {
+				break
+			}
+			for _, part := range tok.Parts {
+				start, stop := part.Position.Column, part.End.Column
+				if part.Kind == lexing.PartText || start < offset || stop > end {
+					continue
+				}
+				buffer.WriteString(strs(text[offset:start]))
+				if part.Kind == lexing.PartInterpolation {
+					buffer.WriteString(interpolations(text[start:stop]))
+				} else {
+					buffer.WriteString(templates(text[start:stop]))
+				}
+				offset = stop
+			}
+			buffer.WriteString(strs(text[offset:end]))
+			offset = end
+		case tok.Type.IsKeyword():
 			buffer.WriteString(keywords(tok.Text))
 			offset += len(tok.Text)
 		}
@@ -297,28 +364,52 @@ func (r *Repl) Highlight(text string) (string, error) {
 // is on, and then return all words that can match.. while also determining
 // what would result in the head, and tail, of the new line should one of those
 // words be substituted..
+//
+// Inside the string of an internal expression the words are the resolver's
+// internals, otherwise they are the names in the global scope.
 func (r *Repl) completeWord(line string, pos int) (string, []string, string) {
-	return line, nil, ""
+	head, tail := line[:pos], line[pos:]
+
+	if quote := strings.LastIndexByte(head, '"'); quote >= 0 && strings.Count(head, "\"")%2 == 1 {
+		if !strings.HasSuffix(strings.TrimSpace(head[:quote]), "internal") {
+			return head, nil, tail
+		}
+		prefix := head[quote+1:]
+		return head[:quote+1], matchingWords(eval.ResolverNames(r.evaluator.Resolver), prefix), tail
+	}
+
+	start := strings.LastIndexFunc(head, func(c rune) bool {
+		return !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_')
+	}) + 1
+	return head[:start], matchingWords(r.env.GetNames(), head[start:]), tail
 }
 
-func (r *Repl) Resolve(name string) (object.Object, error) {
-	if fn, ok := internal.HostedFuncs[name]; ok {
-		return &object.InternalFunction{Fn: fn, Env: nil}, nil
-	} else if nodeType, ok := internal.HostedNodeTypes[name]; ok {
-		return nodeType, nil
-	} else {
-		return nil, fmt.Errorf("unknown internal \"%s\"", name)
+func matchingWords(words []string, prefix string) []string {
+	matches := []string{}
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			matches = append(matches, w)
+		}
 	}
+	sort.Strings(matches)
+	return matches
 }
 
 func printReplHelp() {
 	fmt.Printf(`Stitch REPL Help
    Commands:
       .ls [pkg]    - List named variables, and unnamed nodes in global scope, or package.
+      .ls internal - List the internals available to the program.
       .dot [var]   - Render the current graph (or graph rooted by var) in dot syntax.
+      .mermaid [var] - Render the current graph as a mermaid flowchart.
+      .graphml [var] - Render the current graph as GraphML, for yEd.
+      .ir [var]    - Render the current graph in the stitch JSON IR.
       .quiet       - Turn off auto-inspect when evaluating expressions.
 		.compile <ident> - Compile a given node to its gaufre graph.
 		.run <ident> - Compile a node, and run it.
-		.stop        - Stop running the current graph.
+      .trace       - Toggle printing the calls, and connections, each evaluation makes.
+      .debug       - Toggle stepping through the statements of each evaluation.
+
+   Ctrl-C stops the running evaluation, and returns to the prompt.
 `)
 }
@@ -0,0 +1,49 @@
+package repl
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// Stop cancels the running evaluation, returning false if there isn't one.
+// Code is evaluated while the prompt waits, so there's no command to stop it:
+// it's stopped with Ctrl-C, or from another goroutine.
+func (r *Repl) Stop() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel == nil {
+		return false
+	}
+	r.cancel()
+	return true
+}
+
+// Returns the context to evaluate with, which is cancelled by Ctrl-C, or by
+// Stop, until done is called.
+func (r *Repl) startEval() (ctx context.Context, done func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	r.cancel = cancel
+	r.mu.Unlock()
+
+	// Liner only handles Ctrl-C while reading a line, so while evaluating it
+	// would otherwise end the session.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupts:
+			r.Stop()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(interrupts)
+		r.mu.Lock()
+		r.cancel = nil
+		r.mu.Unlock()
+		cancel()
+	}
+}
@@ -0,0 +1,90 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal/shellcmd"
+	"github.com/nirosys/stitch/eval"
+	"github.com/nirosys/stitch/object"
+)
+
+func (r *Repl) traceCommand() *shellcmd.Command {
+	var traceCommand = &shellcmd.Command{
+		Use:   "trace",
+		Short: "Toggle printing the calls, and connections, made by each evaluation",
+		RunE: func(cmd *shellcmd.Command, args []string) error {
+			r.trace = !r.trace
+			fmt.Printf("Trace mode: %t\n", r.trace)
+			return nil
+		},
+	}
+	return traceCommand
+}
+
+// traceHook prints the function calls of an evaluation as a tree, with their
+// results, along with the connections they make.
+type traceHook struct {
+	eval.BaseHook
+	out   io.Writer
+	depth int
+}
+
+func (t *traceHook) indent() string {
+	return strings.Repeat("  ", t.depth)
+}
+
+func (t *traceHook) Enter(n ast.Node, env *object.Environment) error {
+	if call, ok := n.(*ast.CallExpression); ok {
+		fmt.Fprintf(t.out, "%s%s\n", t.indent(), call.String())
+		t.depth++
+	}
+	return nil
+}
+
+func (t *traceHook) Leave(n ast.Node, result object.Object, err error) {
+	if _, ok := n.(*ast.CallExpression); !ok {
+		return
+	}
+	t.depth--
+	if err != nil {
+		fmt.Fprintf(t.out, "%s=> error: %s\n", t.indent(), err)
+	} else {
+		fmt.Fprintf(t.out, "%s=> %s\n", t.indent(), traceString(result))
+	}
+}
+
+func (t *traceHook) Connect(from, to object.Object) {
+	fmt.Fprintf(t.out, "%sconnect %s -> %s\n", t.indent(), traceString(from), traceString(to))
+}
+
+// A short description of an object, since nodes inspect to all of their
+// slots and arguments.
+func traceString(obj object.Object) string {
+	switch t := obj.(type) {
+	case nil:
+		return "nothing"
+	case *object.Node:
+		name := "node"
+		if t.NodeType != nil {
+			name = t.NodeType.Name
+		}
+		args := make([]string, 0, len(t.Arguments))
+		for _, arg := range t.Arguments {
+			args = append(args, traceString(arg))
+		}
+		return fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))
+	case *object.NodeSlot:
+		return fmt.Sprintf("%s.%s", traceString(t.Node), t.Name)
+	case *object.List:
+		objs := make([]string, 0, len(t.Contents))
+		for _, o := range t.Contents {
+			objs = append(objs, traceString(o))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(objs, ", "))
+	default:
+		return obj.Inspect()
+	}
+}
@@ -3,6 +3,8 @@ package subcmd
 import (
 	"fmt"
 
+	"github.com/nirosys/stitch/catalog"
+	"github.com/nirosys/stitch/cmd/stitch/subcmd/internal"
 	"github.com/nirosys/stitch/cmd/stitch/subcmd/repl"
 
 	"github.com/spf13/cobra"
@@ -16,11 +18,22 @@ var RootCmd = &cobra.Command{
 }
 
 func init() {
+	RootCmd.PersistentFlags().StringSlice("catalog", nil, "Node type manifests to load, on top of the standard node types")
 	RootCmd.Flags().StringP("init-with", "i", "", "Specify a script to run at the start of the session")
 }
 
+// Loads the catalog shared by every command, from the `--catalog` manifests.
+func loadCatalog(cmd *cobra.Command) (*catalog.Catalog, error) {
+	paths, _ := cmd.Flags().GetStringSlice("catalog")
+	return internal.LoadCatalog(paths)
+}
+
 func do_repl(cmd *cobra.Command, args []string) error {
-	repl := repl.NewRepl()
+	c, err := loadCatalog(cmd)
+	if err != nil {
+		return err
+	}
+	repl := repl.NewRepl(c)
 
 	if v, err := cmd.Flags().GetString("init-with"); err == nil && v != "" {
 		if err := repl.LoadFile(v); err != nil {
@@ -0,0 +1,257 @@
+package stitch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nirosys/stitch/analysis"
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/catalog"
+	"github.com/nirosys/stitch/diagnostic"
+	"github.com/nirosys/stitch/eval"
+	"github.com/nirosys/stitch/export"
+	"github.com/nirosys/stitch/host"
+	"github.com/nirosys/stitch/lexing"
+	"github.com/nirosys/stitch/object"
+	"github.com/nirosys/stitch/optimize"
+	"github.com/nirosys/stitch/parsing"
+)
+
+// Source /////////////////////////////////////////////////////////////////////
+
+// Source is a named piece of stitch code. The name is only used to report
+// diagnostics.
+type Source struct {
+	Name string
+	Code string
+}
+
+// FromFS reads the files matching any of the patterns, in name order. With no
+// patterns every *.stitch file in the root of fsys is read.
+func FromFS(fsys fs.FS, patterns ...string) ([]Source, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"*.stitch"}
+	}
+	seen := map[string]bool{}
+	names := []string{}
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				names = append(names, m)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	sources := make([]Source, 0, len(names))
+	for _, name := range names {
+		if code, err := fs.ReadFile(fsys, name); err != nil {
+			return nil, err
+		} else {
+			sources = append(sources, Source{Name: path.Clean(name), Code: string(code)})
+		}
+	}
+	return sources, nil
+}
+
+// Options ////////////////////////////////////////////////////////////////////
+
+type Options struct {
+	// Resolver resolves internals. Defaults to the functions of host.Standard,
+	// then the node types of Catalog. Use eval.Filter to limit the internals
+	// untrusted programs can use.
+	Resolver eval.ObjectResolver
+	// Policy limits what the program can do while it is evaluated.
+	Policy *eval.Policy
+	// Catalog describes the node types the graph is validated against.
+	// Defaults to catalog.Standard().
+	Catalog *catalog.Catalog
+	// NoValidate skips validating the graph against Catalog.
+	NoValidate bool
+	// Root is the identifier of the root node. Defaults to the result of the
+	// last source, which must then be a node.
+	Root string
+	// Optimize names the optimization passes to run, see optimize.Names.
+	Optimize []string
+	// Globals are bound in the environment before any source is evaluated,
+	// after being converted with object.FromGo.
+	Globals map[string]interface{}
+}
+
+// Result /////////////////////////////////////////////////////////////////////
+
+type Result struct {
+	Graph       *export.Graph
+	Root        *object.Node
+	Env         *object.Environment
+	Changes     []optimize.Change
+	Diagnostics []diagnostic.Diagnostic
+}
+
+// CompileError is returned when compiling produces error diagnostics.
+type CompileError struct {
+	Diagnostics []diagnostic.Diagnostic
+}
+
+func (e *CompileError) Error() string {
+	msgs := []string{}
+	for _, d := range e.Diagnostics {
+		if d.Severity == diagnostic.Error {
+			msgs = append(msgs, d.String())
+		}
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Compile ////////////////////////////////////////////////////////////////////
+
+// Compile parses, evaluates, optimizes and validates the sources as one
+// program, in order, and returns the graph rooted at the selected node.
+//
+// Problems with the program are reported as diagnostics on the result, along
+// with a *CompileError if any of them is an error. That includes ctx being done
+// while a source is evaluated, which is reported against the statement it
+// stopped. Otherwise an error comes with a nil result: for invalid options or
+// Globals, for ctx being done between sources, or for an optimization pass
+// that fails.
+func Compile(ctx context.Context, sources []Source, opts Options) (*Result, error) {
+	cat := opts.Catalog
+	if cat == nil {
+		cat = catalog.Standard()
+	}
+	resolver := opts.Resolver
+	if resolver == nil {
+		resolver = eval.Chain(host.Standard(), cat)
+	}
+	pipeline, err := optimize.NewPipeline(optimize.Config{Pure: cat.PureTypes()}, opts.Optimize...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Env: object.NewEnvironment()}
+	for name, value := range opts.Globals {
+		if obj, err := object.FromGo(value); err != nil {
+			return nil, fmt.Errorf("global '%s': %w", name, err)
+		} else {
+			result.Env.Put(name, obj)
+		}
+	}
+
+	evaluator := eval.NewEvaluator()
+	evaluator.Resolver = resolver
+	evaluator.Policy = opts.Policy
+	symbols := analysis.NewSymbolTable()
+	ctx, cancel := evaluator.Start(ctx)
+	defer cancel()
+
+	var last object.Object
+	for _, src := range sources {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		obj, diags := evalSource(ctx, src, evaluator, symbols, result.Env)
+		result.Diagnostics = append(result.Diagnostics, diags...)
+		if diagnostic.HasErrors(diags) {
+			return result, &CompileError{Diagnostics: result.Diagnostics}
+		}
+		last = obj
+	}
+
+	if root, d := selectRoot(opts.Root, last, result.Env); d != nil {
+		result.Diagnostics = append(result.Diagnostics, *d)
+		return result, &CompileError{Diagnostics: result.Diagnostics}
+	} else {
+		result.Root = root
+	}
+
+	roots, changes, err := pipeline.Run([]*object.Node{result.Root})
+	if err != nil {
+		return nil, err
+	}
+	result.Root, result.Changes = roots[0], changes
+
+	if !opts.NoValidate {
+		result.Diagnostics = append(result.Diagnostics, cat.Validate(roots)...)
+		if diagnostic.HasErrors(result.Diagnostics) {
+			return result, &CompileError{Diagnostics: result.Diagnostics}
+		}
+	}
+
+	result.Graph = export.NewGraph(roots)
+	return result, nil
+}
+
+// Parses, analyzes and evaluates a single source, stopping at the first
+// statement that fails.
+func evalSource(ctx context.Context, src Source, e *eval.Evaluator, symbols *analysis.SymbolTable, env *object.Environment) (object.Object, []diagnostic.Diagnostic) {
+	parser := parsing.NewParser(strings.NewReader(src.Code))
+	tree := parser.Parse()
+	if tree == nil {
+		diags := parser.Diagnostics()
+		for i := range diags {
+			diags[i].Source = src.Name
+		}
+		if len(diags) == 0 {
+			diags = []diagnostic.Diagnostic{sourceError(src, lexing.Position{}, "%s", ErrInvalidSyntax)}
+		}
+		return nil, diags
+	}
+
+	// Only warnings remain once the source has parsed.
+	diags := parser.Diagnostics()
+	for i := range diags {
+		diags[i].Source = src.Name
+	}
+
+	var obj object.Object
+	for _, stmt := range tree.Statements {
+		pos := ast.PositionOf(stmt)
+		single := &ast.ASTree{Statements: []ast.Statement{stmt}}
+		if _, err := analysis.AnalyzeWithSymbols(single, symbols); err != nil {
+			return nil, append(diags, sourceError(src, pos, "%s", err))
+		}
+		// Analysis folds constants, so the statement may have been rewritten.
+		if o, err := e.EvalStatement(ctx, single.Statements[0], env); err != nil {
+			if at, ok := eval.ErrorPosition(err); ok {
+				pos = at
+			}
+			return nil, append(diags, sourceError(src, pos, "%s", err))
+		} else {
+			obj = o
+		}
+	}
+	return obj, diags
+}
+
+func sourceError(src Source, pos lexing.Position, format string, args ...interface{}) diagnostic.Diagnostic {
+	d := diagnostic.Errorf(pos, format, args...)
+	d.Source = src.Name
+	return d
+}
+
+func selectRoot(root string, last object.Object, env *object.Environment) (*object.Node, *diagnostic.Diagnostic) {
+	var d diagnostic.Diagnostic
+	if root != "" {
+		if obj, have := env.Get(root); !have {
+			d = diagnostic.Errorf(lexing.Position{}, "unknown identifier '%s'", root)
+		} else if node, ok := obj.(*object.Node); !ok {
+			d = diagnostic.Errorf(lexing.Position{}, "'%s' is not a node, found %s", root, obj.Type())
+		} else {
+			return node, nil
+		}
+	} else if node, ok := last.(*object.Node); ok {
+		return node, nil
+	} else {
+		d = diagnostic.Errorf(lexing.Position{}, "program must end with the root node, or name one")
+	}
+	return nil, &d
+}
@@ -0,0 +1,215 @@
+package stitch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/diagnostic"
+	"github.com/nirosys/stitch/eval"
+	"github.com/nirosys/stitch/export"
+)
+
+func Test_Compile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1-types.stitch": {Data: []byte(`
+let snmp_get = internal "snmp:get"
+let snmp_walk = internal "snmp:walk"
+let std_passthru = internal "std:passthru"
+`)},
+		"2-graph.stitch": {Data: []byte(`
+let walk = snmp_walk(root_oid)
+walk -> std_passthru() -> [snmp_get("ifInOctets"), snmp_get("ifOutOctets")]
+walk
+`)},
+		"notes.txt": {Data: []byte("not stitch")},
+	}
+	sources, err := FromFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(sources) != 2 || sources[0].Name != "1-types.stitch" {
+		t.Fatalf("unexpected sources: %v", sources)
+	}
+
+	result, err := Compile(context.Background(), sources, Options{
+		Optimize: []string{"passthru"},
+		Globals:  map[string]interface{}{"root_oid": "ifType"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Root.NodeType.Name != "snmp:walk" || result.Root.Arguments[0].Inspect() != `"ifType"` {
+		t.Errorf("unexpected root: %s", result.Root.Inspect())
+	}
+	if len(result.Graph.Nodes) != 3 || len(result.Graph.Edges) != 2 {
+		t.Errorf("expected 3 nodes and 2 edges, found %d and %d", len(result.Graph.Nodes), len(result.Graph.Edges))
+	}
+	if len(result.Changes) != 1 {
+		t.Errorf("expected passthru to be collapsed, found %v", result.Changes)
+	}
+}
+
+func Test_CompileConstants(t *testing.T) {
+	src := `
+const prefix = "ifHC"
+const debug = false
+const oid = prefix + "InOctets"
+let walk = internal "snmp:walk"
+let get = internal "snmp:get"
+let w = walk(oid)
+w -> get(if debug { "sysDescr" } else { "${prefix}OutOctets" })
+w
+`
+	prog := NewProgram(strings.NewReader(src))
+	if prog.Symbols == nil {
+		t.Fatalf("unexpected errors: %v", prog.Errors())
+	}
+	if let := prog.Tree.Statements[2].(*ast.LetStatement); let.Value.String() != `"ifHCInOctets"` {
+		t.Errorf("expected the constant to be folded, found %s", let.Value)
+	}
+
+	result, err := Compile(context.Background(), []Source{{Name: "a.stitch", Code: src}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dot bytes.Buffer
+	if err := (&export.DotExporter{}).Export(&dot, result.Graph); err != nil {
+		t.Fatal(err)
+	}
+	for _, label := range []string{`oid=\"ifHCInOctets\"`, `oid=\"ifHCOutOctets\"`} {
+		if !strings.Contains(dot.String(), label) {
+			t.Errorf("expected %s in the graph, found:\n%s", label, dot.String())
+		}
+	}
+}
+
+func Test_CompileStandardLibrary(t *testing.T) {
+	src := `
+let oid = internal "std:oid"
+let range = internal "std:range"
+let walk = internal "snmp:walk"
+let get = internal "snmp:get"
+let w = walk(oid("1.3.6.1.2.1.2.2.1"))
+foreach i in range(10, 0, -5) { w -> get(oid("1.3.6.1.2.1.2.2.1").append(i)) }
+w
+`
+	result, err := Compile(context.Background(), []Source{{Name: "a.stitch", Code: src}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Graph.Nodes) != 3 || len(result.Graph.Edges) != 2 {
+		t.Errorf("expected 3 nodes and 2 edges, found %d and %d", len(result.Graph.Nodes), len(result.Graph.Edges))
+	}
+}
+
+// Whatever analysis accepts about ranges evaluates, and the rest is rejected
+// before it's evaluated.
+func Test_CompileRanges(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"let l = (1..3) + [4]\n", ""},
+		{"let l = [1] + (2..3)\n", ""},
+		{"let l = (1..2) + (3..4)\n", ""},
+		{"let x = (1..3)[0] + 1\n", ""},
+		{"let l = (1..3) - [1]\n", "a.stitch: line 0 column 0: error: type mismatch: operator '-' not defined for LIST"},
+		{"let l = [1] * (2..3)\n", "a.stitch: line 0 column 0: error: type mismatch: operator '*' not defined for LIST"},
+	}
+	for i, test := range tests {
+		src := test.src + "let walk = internal \"snmp:walk\"\nwalk(\"ifType\")\n"
+		result, err := Compile(context.Background(), []Source{{Name: "a.stitch", Code: src}}, Options{})
+		if test.expected == "" {
+			if err != nil {
+				t.Errorf("[%d] unexpected error: %s", i, err)
+			}
+		} else if err == nil || len(result.Diagnostics) == 0 || result.Diagnostics[0].String() != test.expected {
+			t.Errorf("[%d] expected %q, found %v", i, test.expected, err)
+		}
+	}
+}
+
+func Test_CompileDiagnostics(t *testing.T) {
+	tests := []struct {
+		sources  []Source
+		opts     Options
+		expected string
+	}{
+		{
+			[]Source{{Name: "bad.stitch", Code: "let = 1\n"}},
+			Options{},
+			"bad.stitch: line 0 column 4: error: expected IDENTIFIER; have '='",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "let x = 1\n"}, {Name: "b.stitch", Code: "let y = x\ny -> z\n"}},
+			Options{},
+			"b.stitch: line 1 column 5: error: unknown identifier 'z'",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "let x = 1\n"}},
+			Options{},
+			"line 0 column 0: error: program must end with the root node, or name one",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "let x = 1\nlet y = 99999999999999999999\n"}},
+			Options{},
+			"a.stitch: line 1 column 8: error: integer 99999999999999999999 doesn't fit in 64 bits",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "let x = 1\n"}},
+			Options{Root: "x"},
+			"line 0 column 0: error: 'x' is not a node, found INTEGER",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "let get = internal \"snmp:get\"\nget(1)\n"}},
+			Options{},
+			"line 1 column 3: error: snmp:get: argument 'oid' must be oid, found INTEGER",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "let walk = internal \"snmp:walk\"\nwalk(\"ifType\")\n"}},
+			Options{Policy: &eval.Policy{Internals: []string{"snmp:get"}}},
+			"a.stitch: line 0 column 0: error: policy violation: internal \"snmp:walk\" is not allowed",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "let l = [1, 2]\nl.join(1)\n"}},
+			Options{},
+			"a.stitch: line 1 column 6: error: type mismatch: join expects STRING for separator, found INTEGER",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "const port = 161\n"}, {Name: "b.stitch", Code: "fn f() {\n  port = 162\n  port\n}\n"}},
+			Options{},
+			"b.stitch: line 0 column 0: error: cannot assign to constant: port",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "const port = 161\nlet port = 162\n"}},
+			Options{},
+			"a.stitch: line 1 column 0: error: symbol already exists: port",
+		},
+		{
+			[]Source{{Name: "a.stitch", Code: "let s = \"a\"\ns.keys()\n"}},
+			Options{},
+			"a.stitch: line 1 column 6: error: type mismatch: STRING has no method 'keys'",
+		},
+	}
+	for i, test := range tests {
+		result, err := Compile(context.Background(), test.sources, test.opts)
+		var compileErr *CompileError
+		if !errors.As(err, &compileErr) {
+			t.Errorf("[%d] expected a compile error, found %v", i, err)
+			continue
+		}
+		if !diagnostic.HasErrors(result.Diagnostics) || result.Diagnostics[0].String() != test.expected {
+			t.Errorf("[%d] expected %q, found %v", i, test.expected, result.Diagnostics)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Compile(ctx, []Source{{Name: "a.stitch", Code: "1"}}, Options{}); err != context.Canceled {
+		t.Errorf("expected context.Canceled, found %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+// Package diagnostic holds the problems found in a stitch program, so they
+// can be reported all at once rather than stopping at the first one.
+package diagnostic
+
+import (
+	"fmt"
+
+	"github.com/nirosys/stitch/lexing"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Source   string // Name of the source the problem is in, if known.
+	Position lexing.Position
+	Message  string
+}
+
+func Errorf(pos lexing.Position, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: Error, Position: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func Warningf(pos lexing.Position, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: Warning, Position: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (d Diagnostic) String() string {
+	msg := fmt.Sprintf("line %d column %d: %s: %s", d.Position.Line, d.Position.Column, d.Severity, d.Message)
+	if d.Source != "" {
+		return d.Source + ": " + msg
+	}
+	return msg
+}
+
+// HasErrors reports whether any of the diagnostics is an error.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,11 @@
 package eval
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 
-	"github.com/nirosys/stitch"
 	"github.com/nirosys/stitch/ast"
 	"github.com/nirosys/stitch/object"
 
@@ -13,6 +15,10 @@ import (
 // Evaluator //////////////////////////////////////////////////////////////////
 type Evaluator struct {
 	Resolver ObjectResolver
+	Policy   *Policy    // Limits on the program, or nil for none.
+	Hooks    []EvalHook // Told about each step of the evaluation.
+
+	usage usage
 }
 
 func NewEvaluator() *Evaluator {
@@ -21,11 +27,11 @@ func NewEvaluator() *Evaluator {
 	}
 }
 
-func (e *Evaluator) evalNamedNode(i *ast.NamedNodeExpression, env *object.Environment) (object.Object, error) {
+func (e *Evaluator) evalNamedNode(ctx context.Context, i *ast.NamedNodeExpression, env *object.Environment) (object.Object, error) {
 	// Valid uses:
 	//   left is identifier, right is Node
 	//   left is tag identifier, right is Node
-	rightObj, err := e.eval(i.Expression, env)
+	rightObj, err := e.eval(ctx, i.Expression, env)
 	if err != nil {
 		return nil, err
 	}
@@ -44,22 +50,47 @@ func (e *Evaluator) evalNamedNode(i *ast.NamedNodeExpression, env *object.Enviro
 	return nil, fmt.Errorf("invalid named expression")
 }
 
-func (e *Evaluator) evalConditional(c *ast.ConditionalExpression, env *object.Environment) (object.Object, error) {
-	cond, err := e.eval(c.Condition, env)
+func (e *Evaluator) evalConditional(ctx context.Context, c *ast.ConditionalExpression, env *object.Environment) (object.Object, error) {
+	cond, err := e.eval(ctx, c.Condition, env)
 	if err != nil {
 		return nil, err
 	}
 	if b, ok := cond.(*object.BoolObject); !ok {
 		return nil, fmt.Errorf("expected boolean expression, found %s", cond.Type())
 	} else if bool(*b) {
-		return e.eval(c.Block, env)
+		return e.eval(ctx, c.Block, env)
 	} else if c.Else != nil {
-		return e.eval(c.Else, env)
+		return e.eval(ctx, c.Else, env)
 	}
-	return nil, nil
+	return object.None, nil
 }
 
-func (e *Evaluator) evalInternalFunc(l *ast.InternalExpression, env *object.Environment) (object.Object, error) {
+func (e *Evaluator) evalMatch(ctx context.Context, m *ast.MatchExpression, env *object.Environment) (object.Object, error) {
+	subject, err := e.eval(ctx, m.Subject, env)
+	if err != nil {
+		return nil, err
+	}
+	for _, arm := range m.Arms {
+		if arm.IsDefault() {
+			return e.eval(ctx, arm.Body, env)
+		}
+		for _, p := range arm.Patterns {
+			if pattern, err := e.eval(ctx, p, env); err != nil {
+				return nil, err
+			} else if matches, err := object.Equal(subject, pattern); err != nil {
+				return nil, err
+			} else if matches {
+				return e.eval(ctx, arm.Body, env)
+			}
+		}
+	}
+	return object.None, nil
+}
+
+func (e *Evaluator) evalInternalFunc(ctx context.Context, l *ast.InternalExpression, env *object.Environment) (object.Object, error) {
+	if err := e.checkInternal(l.Name.Value); err != nil {
+		return nil, err
+	}
 	if obj, err := e.Resolver.Resolve(l.Name.Value); err != nil {
 		return nil, err
 	} else {
@@ -71,13 +102,13 @@ func (e *Evaluator) evalInternalFunc(l *ast.InternalExpression, env *object.Envi
 	}
 }
 
-func (e *Evaluator) evalList(l *ast.ListLiteral, env *object.Environment) (object.Object, error) {
+func (e *Evaluator) evalList(ctx context.Context, l *ast.ListLiteral, env *object.Environment) (object.Object, error) {
 	list := &object.List{}
 	var tpe object.ObjectType = object.UnknownObjectType
 	contents := make([]object.Object, 0, len(l.Contents))
 
 	for _, exp := range l.Contents {
-		if obj, err := e.eval(exp, env); err != nil {
+		if obj, err := e.eval(ctx, exp, env); err != nil {
 			return nil, err
 		} else {
 			if tpe == object.UnknownObjectType {
@@ -93,23 +124,65 @@ func (e *Evaluator) evalList(l *ast.ListLiteral, env *object.Environment) (objec
 	return list, nil
 }
 
-func (e *Evaluator) evalMap(m *ast.MapLiteral, env *object.Environment) (object.Object, error) {
-	mapObj := &object.MapObject{}
-	mapObj.Fields = make(map[string]object.Object)
+func (e *Evaluator) evalMap(ctx context.Context, m *ast.MapLiteral, env *object.Environment) (object.Object, error) {
+	mapObj := object.NewMap()
 	for _, assign := range m.Assignments {
-		if obj, err := e.eval(assign.Value, env); err != nil {
+		if obj, err := e.eval(ctx, assign.Value, env); err != nil {
 			return nil, err
 		} else {
-			mapObj.Fields[assign.Identifier.String()] = obj
+			mapObj.Set(assign.Identifier.String(), obj)
 		}
 	}
 	return mapObj, nil
 }
 
-func (e *Evaluator) evalBlockExpression(b *ast.BlockExpression, env *object.Environment) (object.Object, error) {
+// Keys are strings or integers, and integer keys stay integers, so 1 and "1"
+// are different keys.
+func (e *Evaluator) evalHash(ctx context.Context, h *ast.HashLiteral, env *object.Environment) (object.Object, error) {
+	mapObj := object.NewMap()
+	for i := range h.Keys {
+		key, err := e.eval(ctx, h.Keys[i], env)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := mapObj.Get(key); err != nil {
+			return nil, err
+		}
+		if obj, err := e.eval(ctx, h.Values[i], env); err != nil {
+			return nil, err
+		} else if obj == nil {
+			return nil, fmt.Errorf("no value for key %s", key.Inspect())
+		} else if err := mapObj.Put(key, obj); err != nil {
+			return nil, err
+		}
+	}
+	return mapObj, nil
+}
+
+// Strings interpolate as their text, and everything else as it inspects.
+func (e *Evaluator) evalInterpolatedString(ctx context.Context, str *ast.InterpolatedString, env *object.Environment) (object.Object, error) {
+	var buffer strings.Builder
+	for _, part := range str.Parts {
+		obj, err := e.eval(ctx, part, env)
+		if err != nil {
+			return nil, err
+		}
+		switch t := obj.(type) {
+		case nil:
+			return nil, fmt.Errorf("nothing to interpolate from '%s'", part.String())
+		case *object.String:
+			buffer.WriteString(t.Value)
+		default:
+			buffer.WriteString(t.Inspect())
+		}
+	}
+	return &object.String{Value: buffer.String()}, nil
+}
+
+func (e *Evaluator) evalBlockExpression(ctx context.Context, b *ast.BlockExpression, env *object.Environment) (object.Object, error) {
 	var last object.Object
 	for _, stmt := range b.Statements {
-		if obj, err := e.eval(stmt, env); err != nil {
+		if obj, err := e.eval(ctx, stmt, env); err != nil {
 			return nil, err
 		} else {
 			last = obj
@@ -119,7 +192,7 @@ func (e *Evaluator) evalBlockExpression(b *ast.BlockExpression, env *object.Envi
 	return last, nil
 }
 
-func (e *Evaluator) evalFunctionDefinition(fun *ast.FunctionLiteral, env *object.Environment) (object.Object, error) {
+func (e *Evaluator) evalFunctionDefinition(ctx context.Context, fun *ast.FunctionLiteral, env *object.Environment) (object.Object, error) {
 	fn := &object.Function{
 		Parameters: fun.Parameters,
 		Body:       fun.Body,
@@ -133,8 +206,8 @@ func (e *Evaluator) evalFunctionDefinition(fun *ast.FunctionLiteral, env *object
 	}
 }
 
-func (e *Evaluator) evalNotExpression(in *ast.NotExpression, env *object.Environment) (object.Object, error) {
-	exp, err := e.eval(in.Expression, env)
+func (e *Evaluator) evalNotExpression(ctx context.Context, in *ast.NotExpression, env *object.Environment) (object.Object, error) {
+	exp, err := e.eval(ctx, in.Expression, env)
 	if err != nil {
 		return nil, err
 	}
@@ -146,37 +219,51 @@ func (e *Evaluator) evalNotExpression(in *ast.NotExpression, env *object.Environ
 	}
 }
 
-func (e *Evaluator) evalInfixComparison(in *ast.InfixExpression, env *object.Environment) (object.Object, error) {
-	leftObj, err := e.eval(in.Left, env)
+func (e *Evaluator) evalInfixComparison(ctx context.Context, in *ast.InfixExpression, env *object.Environment) (object.Object, error) {
+	leftObj, err := e.eval(ctx, in.Left, env)
 	if err != nil {
 		return nil, err
 	}
 
-	rightObj, err := e.eval(in.Right, env)
+	rightObj, err := e.eval(ctx, in.Right, env)
 	if err != nil {
 		return nil, err
 	}
 
-	left, lok := leftObj.(object.Comparable)
-	if !lok {
-		return nil, fmt.Errorf("%s is not comparable", leftObj.Type())
-	}
-	right, rok := rightObj.(object.Comparable)
-	if !rok {
-		return nil, fmt.Errorf("%s is not comparable", rightObj.Type())
+	if leftObj == object.None || rightObj == object.None {
+		// None is only ever equal to itself.
+		switch in.Operator {
+		case "==":
+			return object.NewBoolObject(leftObj == rightObj), nil
+		case "!=":
+			return object.NewBoolObject(leftObj != rightObj), nil
+		}
 	}
 
-	if !left.IsComparable(right) {
+	left, lok := leftObj.(object.Comparable)
+	right, rok := rightObj.(object.Comparable)
+	if lok && rok && !left.IsComparable(right) {
 		return nil, fmt.Errorf("%s is not comparable to %s", left.Type(), right.Type())
 	}
 
 	switch in.Operator {
-	case "==":
-		if v, err := left.Equals(right); err != nil {
+	case "==", "!=":
+		// Lists and ranges aren't Comparable, but are equal item by item.
+		if v, err := object.Equal(leftObj, rightObj); err != nil {
 			return nil, err
 		} else {
-			return object.NewBoolObject(v), nil
+			return object.NewBoolObject(v == (in.Operator == "==")), nil
 		}
+	}
+
+	if !lok {
+		return nil, fmt.Errorf("%s is not comparable", leftObj.Type())
+	}
+	if !rok {
+		return nil, fmt.Errorf("%s is not comparable", rightObj.Type())
+	}
+
+	switch in.Operator {
 	case ">=": // Same as !(l < )
 		if v, err := left.LessThan(right); err != nil {
 			return nil, err
@@ -201,12 +288,6 @@ func (e *Evaluator) evalInfixComparison(in *ast.InfixExpression, env *object.Env
 		} else {
 			return object.NewBoolObject(v), nil
 		}
-	case "!=":
-		if v, err := left.Equals(right); err != nil {
-			return nil, err
-		} else {
-			return object.NewBoolObject(!v), nil
-		}
 	case "and":
 		lbool, lok := left.(*object.BoolObject)
 		if !lok {
@@ -232,12 +313,22 @@ func (e *Evaluator) evalInfixComparison(in *ast.InfixExpression, env *object.Env
 	return nil, nil
 }
 
-func (e *Evaluator) evalInfixComputation(in *ast.InfixExpression, env *object.Environment) (object.Object, error) {
-	leftObj, err := e.eval(in.Left, env)
+func (e *Evaluator) evalInfixComputation(ctx context.Context, in *ast.InfixExpression, env *object.Environment) (object.Object, error) {
+	leftObj, err := e.eval(ctx, in.Left, env)
 	if err != nil {
 		return nil, err
 	}
 
+	switch in.Operator {
+	case "??":
+		if leftObj != object.None {
+			return leftObj, nil
+		}
+		return e.eval(ctx, in.Right, env)
+	case "is":
+		return object.NewBoolObject(leftObj == object.None), nil
+	}
+
 	if in.Operator == "." {
 		if i, ok := in.Right.(*ast.Identifier); !ok {
 			return nil, fmt.Errorf("expected identifier but found '%s'", in.Right.String())
@@ -246,18 +337,16 @@ func (e *Evaluator) evalInfixComputation(in *ast.InfixExpression, env *object.En
 		}
 	}
 
-	rightObj, err := e.eval(in.Right, env)
+	rightObj, err := e.eval(ctx, in.Right, env)
 	if err != nil {
 		return nil, err
 	}
 
-	if lStr, ok := leftObj.(*object.String); ok && rightObj.Type().IsPrimitive() {
-		switch r := rightObj.(type) {
-		case *object.String:
-			return &object.String{Value: lStr.Value + r.Value}, nil
-		default:
-			return &object.String{Value: lStr.Value + r.Inspect()}, nil
-		}
+	switch in.Operator {
+	case "&", "|", "^", "<<", ">>", "**":
+		return evalIntegral(in.Operator, leftObj, rightObj)
+	case "..", "..<":
+		return evalRange(in.Operator, leftObj, rightObj)
 	}
 
 	var left object.Computable
@@ -290,11 +379,48 @@ func (e *Evaluator) evalInfixComputation(in *ast.InfixExpression, env *object.En
 	}
 }
 
-func (e *Evaluator) evalExpressions(expr []ast.Expression, env *object.Environment) ([]object.Object, error) {
+func evalIntegral(operator string, leftObj, rightObj object.Object) (object.Object, error) {
+	left, ok := leftObj.(object.Integral)
+	if !ok {
+		return nil, fmt.Errorf("operator '%s' not defined for type %s", operator, typeOf(leftObj))
+	} else if _, ok := rightObj.(object.Integral); !ok {
+		return nil, fmt.Errorf("operator '%s' not defined for type %s", operator, typeOf(rightObj))
+	}
+
+	switch operator {
+	case "&":
+		return left.And(rightObj)
+	case "|":
+		return left.Or(rightObj)
+	case "^":
+		return left.Xor(rightObj)
+	case "<<":
+		return left.ShiftLeft(rightObj)
+	case ">>":
+		return left.ShiftRight(rightObj)
+	default:
+		return left.Power(rightObj)
+	}
+}
+
+// 1..4 counts up to and including 4, 1..<4 stops short of it.
+func evalRange(operator string, leftObj, rightObj object.Object) (object.Object, error) {
+	start, ok := leftObj.(*object.Integer)
+	if !ok {
+		return nil, fmt.Errorf("range bounds must be INTEGER, found %s", typeOf(leftObj))
+	}
+	end, ok := rightObj.(*object.Integer)
+	if !ok {
+		return nil, fmt.Errorf("range bounds must be INTEGER, found %s", typeOf(rightObj))
+	}
+	return object.NewIntegerRange(start.Value, end.Value, operator == "..")
+}
+
+func (e *Evaluator) evalExpressions(ctx context.Context, expr []ast.Expression, env *object.Environment) ([]object.Object, error) {
 	list := make([]object.Object, len(expr), len(expr))
 
 	for i, exp := range expr {
-		if ret, err := e.eval(exp, env); err != nil {
+		if ret, err := e.eval(ctx, exp, env); err != nil {
 			return nil, err
 		} else {
 			list[i] = ret
@@ -304,7 +430,7 @@ func (e *Evaluator) evalExpressions(expr []ast.Expression, env *object.Environme
 	return list, nil
 }
 
-func (e *Evaluator) evalNodeStatement(n *ast.NodeStatement, env *object.Environment) (object.Object, error) {
+func (e *Evaluator) evalNodeStatement(ctx context.Context, n *ast.NodeStatement, env *object.Environment) (object.Object, error) {
 	nodeType := &object.NodeType{}
 	nodeType.Name = n.Identifier.String()
 
@@ -331,24 +457,107 @@ func (e *Evaluator) evalNodeStatement(n *ast.NodeStatement, env *object.Environm
 	return nodeType, nil
 }
 
-func (e *Evaluator) evalForeachStatement(f *ast.ForeachStatement, env *object.Environment) (object.Object, error) {
-	if obj, err := e.eval(f.List, env); err != nil {
+// Signal break and continue up to the loop they are in. If they get any
+// further, there is no loop, and these are the errors reported.
+var (
+	errBreak    = errors.New("break outside of foreach")
+	errContinue = errors.New("continue outside of foreach")
+)
+
+func (e *Evaluator) evalForeachStatement(ctx context.Context, f *ast.ForeachStatement, env *object.Environment) (object.Object, error) {
+	obj, err := e.eval(ctx, f.List, env)
+	if err != nil {
 		return nil, err
-	} else if obj.Type() != object.ListObjectType {
-		return nil, fmt.Errorf("invalid type, expected a LIST, got a %s", obj.Type())
-	} else if list, ok := obj.(*object.List); ok {
+	}
+
+	// Each iteration takes its key and value from at, so ranges, CIDRs and
+	// integers are never made into lists.
+	var count int64
+	var at func(i int64) (object.Object, object.Object)
+	switch t := obj.(type) {
+	case *object.List:
+		count = int64(len(t.Contents))
+		at = func(i int64) (object.Object, object.Object) {
+			return &object.Integer{Value: i}, t.Contents[i]
+		}
+	case *object.MapObject:
+		keys := t.KeyObjects()
+		count = int64(len(keys))
+		at = func(i int64) (object.Object, object.Object) {
+			if f.KeyVar == nil { // A single variable takes the keys.
+				return keys[i], keys[i]
+			}
+			value, _, _ := t.Get(keys[i])
+			return keys[i], value
+		}
+	case *object.Range:
+		count = t.Len()
+		at = func(i int64) (object.Object, object.Object) {
+			return &object.Integer{Value: i}, t.At(i)
+		}
+	case *object.CIDR:
+		n, err := t.Len()
+		if err != nil {
+			return nil, err
+		}
+		count = n
+		at = func(i int64) (object.Object, object.Object) {
+			return &object.Integer{Value: i}, t.At(i)
+		}
+	case *object.Integer:
+		if f.KeyVar != nil {
+			return nil, fmt.Errorf("cannot loop over INTEGER with two variables")
+		}
+		count = t.Value
+		at = func(i int64) (object.Object, object.Object) {
+			return nil, &object.Integer{Value: i}
+		}
+	default:
+		return nil, fmt.Errorf("cannot loop over %s, expected a LIST, MAP, RANGE, CIDR or INTEGER", obj.Type())
+	}
+
+	results := &object.List{InnerType: object.UnknownObjectType, Contents: []object.Object{}}
+	for i := int64(0); i < count; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		} else if err := e.addIteration(); err != nil {
+			return nil, err
+		}
+
+		key, value := at(i)
 		scope := env.Clone()
-		for _, o := range list.Contents {
-			scope.Put(f.LoopVar.Identifier, o)
-			e.evalBlockExpression(f.Block, scope)
+		if f.KeyVar != nil {
+			scope.PutLocal(f.KeyVar.Identifier, key)
+		}
+		scope.PutLocal(f.LoopVar.Identifier, value)
+
+		result, err := e.eval(ctx, f.Block, scope)
+		if errors.Is(err, errBreak) {
+			break
+		} else if errors.Is(err, errContinue) || (err == nil && (result == nil || result == object.None)) {
+			continue
+		} else if err != nil {
+			return nil, err
 		}
+
+		if results.InnerType == object.UnknownObjectType {
+			results.InnerType = result.Type()
+		} else if results.InnerType != result.Type() {
+			return nil, fmt.Errorf("mixed types for list, %s and %s", results.InnerType, result.Type())
+		}
+		results.Contents = append(results.Contents, result)
+		env.PutUnboundNode(result)
 	}
-	return nil, nil
+	return results, nil
 }
 
-func (e *Evaluator) evalNodeLiteral(n *ast.NodeLiteral, env *object.Environment) (object.Object, error) {
+func (e *Evaluator) evalNodeLiteral(ctx context.Context, n *ast.NodeLiteral, env *object.Environment) (object.Object, error) {
 	// TODO: Fix this.
+	if err := e.addNode(); err != nil {
+		return nil, err
+	}
 	obj := object.NewNode()
+	e.hookConstruct(obj)
 	//for _, assign := range n.Properties {
 	//	ident := assign.Identifier.String()
 	//	switch ident {
@@ -374,9 +583,9 @@ func (e *Evaluator) evalNodeLiteral(n *ast.NodeLiteral, env *object.Environment)
 	return obj, nil
 }
 
-func (e *Evaluator) evalConnectExpression(c *ast.ArrowExpression, env *object.Environment) (object.Object, error) {
+func (e *Evaluator) evalConnectExpression(ctx context.Context, c *ast.ArrowExpression, env *object.Environment) (object.Object, error) {
 	var right object.Connectable
-	if r, err := e.eval(c.Right, env); err != nil {
+	if r, err := e.eval(ctx, c.Right, env); err != nil {
 		return nil, err
 	} else if t, ok := r.(object.Connectable); !ok {
 		return nil, fmt.Errorf("connections can not be with type %s", r.Type())
@@ -385,7 +594,7 @@ func (e *Evaluator) evalConnectExpression(c *ast.ArrowExpression, env *object.En
 	}
 
 	var left object.Connectable
-	if l, err := e.eval(c.Left, env); err != nil {
+	if l, err := e.eval(ctx, c.Left, env); err != nil {
 		return nil, err
 	} else if t, ok := l.(object.Connectable); !ok {
 		return nil, fmt.Errorf("connections can not be with type %s", l.Type())
@@ -393,18 +602,56 @@ func (e *Evaluator) evalConnectExpression(c *ast.ArrowExpression, env *object.En
 		left = t
 	}
 
+	if err := e.addConnections(left, right); err != nil {
+		return nil, err
+	}
+
 	_, err := left.Connect(right)
+	if err == nil {
+		e.hookConnect(left, right)
+	}
 
 	return left, err
 }
 
-func (e *Evaluator) eval(n ast.Node, env *object.Environment) (object.Object, error) {
-	//fmt.Printf("stmt: %#v\n", n)
+func (e *Evaluator) eval(ctx context.Context, n ast.Node, env *object.Environment) (object.Object, error) {
+	if len(e.Hooks) == 0 {
+		obj, err := e.evalNode(ctx, n, env)
+		return valueOf(n, obj, err)
+	}
+	if err := e.hookEnter(n, env); err != nil {
+		return nil, err
+	}
+	obj, err := e.evalNode(ctx, n, env)
+	obj, err = valueOf(n, obj, err)
+	e.hookLeave(n, obj, err)
+	return obj, err
+}
+
+// Expressions always have a value, none when there's nothing else, so nothing
+// using one has to check for nil. Statements, like let, have no value.
+func valueOf(n ast.Node, obj object.Object, err error) (object.Object, error) {
+	if err != nil {
+		return nil, atPosition(err, n)
+	} else if _, ok := n.(ast.Expression); ok && obj == nil {
+		return object.None, nil
+	}
+	return obj, nil
+}
+
+func (e *Evaluator) evalNode(ctx context.Context, n ast.Node, env *object.Environment) (object.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	switch t := n.(type) {
 	case *ast.CommentStatement:
 		return nil, nil // Do nothing..
 	case *ast.ConditionalExpression:
-		return e.evalConditional(t, env)
+		return e.evalConditional(ctx, t, env)
+	case *ast.MatchExpression:
+		return e.evalMatch(ctx, t, env)
+	case *ast.TryExpression:
+		return e.evalTry(ctx, t, env)
 		/*
 			case *ast.ImportStatement:
 				// TODO: Track directory, where import should base out of
@@ -424,14 +671,10 @@ func (e *Evaluator) eval(n ast.Node, env *object.Environment) (object.Object, er
 					parser := parsing.NewParser(f)
 					prog := parser.ParseProgram()
 					if prog == nil {
-						//fmt.Printf("Parser error(s):\n")
-						for _, e := range parser.Errors() {
-							fmt.Printf("%s: %s\n", p, e)
-						}
-						return nil, fmt.Errorf("parser error(s), check stdout for errors")
+						return nil, fmt.Errorf("%s: %s", p, strings.Join(parser.Errors(), "; "))
 					} else {
 						pkgEnv := object.NewEnvironment()
-						if _, err := e.EvalProgram(prog, pkgEnv); err != nil {
+						if _, err := e.EvalProgram(ctx, prog, pkgEnv); err != nil {
 							return nil, err
 						} else {
 							pkg, err := object.NewPackage(name, pkgEnv)
@@ -445,36 +688,40 @@ func (e *Evaluator) eval(n ast.Node, env *object.Environment) (object.Object, er
 				return nil, nil // TODO: Implement me.
 		*/
 	case *ast.LetStatement:
-		if obj, err := e.eval(t.Value, env); err == nil {
-			env.Put(t.Name.String(), obj)
-			return nil, nil
-		} else {
+		if obj, err := e.eval(ctx, t.Value, env); err != nil {
 			return nil, err
+		} else if t.Const {
+			return nil, env.PutConst(t.Name.String(), obj)
+		} else {
+			return nil, env.Assign(t.Name.String(), obj)
 		}
 	case *ast.Identifier:
 		if obj, ok := env.Get(t.String()); ok {
 			return obj, nil
 		}
-		return nil, fmt.Errorf("%d: unknown identifier '%s'", t.Token.Position.Line, t.String())
+		return nil, fmt.Errorf("unknown identifier '%s'", t.String())
 	case *ast.CallExpression:
-		obj, err := e.eval(t.Function, env) // TODO: rename function 'identifier'
+		obj, err := e.eval(ctx, t.Function, env) // TODO: rename function 'identifier'
 		if err != nil {
 			return nil, err
 		}
 
 		switch tpe := obj.(type) {
 		case object.Constructable:
-			params := tpe.Arguments()
-			if len(t.Arguments) != len(params) {
-				return nil, fmt.Errorf("expected %d arguments but found %d", len(params), len(t.Arguments))
-			}
-			if args, err := e.evalExpressions(t.Arguments, env); err != nil {
+			// Constructables check their own arguments, since some can be left
+			// off.
+			if args, err := e.evalExpressions(ctx, t.Arguments, env); err != nil {
 				return nil, err
 			} else {
 				if obj, err := tpe.Construct(args); err != nil {
 					return nil, err
 				} else {
-					if obj.Type() == object.NodeObjectType {
+					if node, ok := obj.(*object.Node); ok {
+						if err := e.addNode(); err != nil {
+							return nil, err
+						}
+						node.Position = t.Token.Position
+						e.hookConstruct(node)
 						env.PutUnboundNode(obj)
 					}
 					return obj, nil
@@ -482,83 +729,126 @@ func (e *Evaluator) eval(n ast.Node, env *object.Environment) (object.Object, er
 			}
 		case object.Callable:
 			params := tpe.FuncParameters()
-			if len(t.Arguments) != len(params) {
+			if fn, ok := tpe.(*object.InternalFunction); ok && fn.Fn.Variadic {
+				if len(t.Arguments) < len(params)-1 {
+					return nil, fmt.Errorf("expected at least %d arguments but found %d", len(params)-1, len(t.Arguments))
+				}
+			} else if len(t.Arguments) != len(params) {
 				return nil, fmt.Errorf("expected %d arguments but found %d", len(params), len(t.Arguments))
 			}
-			if args, err := e.evalExpressions(t.Arguments, env); err != nil {
+			if args, err := e.evalExpressions(ctx, t.Arguments, env); err != nil {
 				return nil, err
 			} else {
-				return e.applyFunction(env, tpe, args)
+				return e.applyFunction(ctx, env, tpe, args)
 			}
 		default:
 			return nil, fmt.Errorf("'%s' is not a function", obj.Type())
 		}
 	case *ast.AssignmentExpression:
 		target := t.Identifier.Identifier
-		if obj, err := e.eval(t.Value, env); err != nil {
+		if obj, err := e.eval(ctx, t.Value, env); err != nil {
 			return nil, err
 		} else if _, have := env.Get(target); !have {
 			return nil, fmt.Errorf("unknown identifier '%s'", target)
+		} else if err := env.Assign(target, obj); err != nil {
+			return nil, err
 		} else {
-			env.Put(target, obj)
 			return obj, nil
 		}
 	case *ast.NodeStatement:
-		return e.evalNodeStatement(t, env)
+		return e.evalNodeStatement(ctx, t, env)
 	case *ast.ForeachStatement:
-		return e.evalForeachStatement(t, env)
+		return e.evalForeachStatement(ctx, t, env)
+	case *ast.BreakStatement:
+		return nil, errBreak
+	case *ast.ContinueStatement:
+		return nil, errContinue
 	case *ast.NodeLiteral:
-		return e.evalNodeLiteral(t, env)
+		return e.evalNodeLiteral(ctx, t, env)
 	case *ast.StringLiteral:
 		return &object.String{Value: t.Value}, nil
+	case *ast.InterpolatedString:
+		return e.evalInterpolatedString(ctx, t, env)
 	case *ast.BoolLiteral:
 		obj := object.BoolObject(t.Value)
 		return &obj, nil
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: t.Value}, nil
+	case *ast.NoneLiteral:
+		return object.None, nil
 	case *ast.InfixExpression:
 		switch t.Operator {
 		case "==", "<", "<=", ">", ">=", "!=", "and", "or":
-			return e.evalInfixComparison(t, env)
+			return e.evalInfixComparison(ctx, t, env)
 		default:
-			return e.evalInfixComputation(t, env)
+			return e.evalInfixComputation(ctx, t, env)
 		}
 	case *ast.NamedNodeExpression:
-		return e.evalNamedNode(t, env)
+		return e.evalNamedNode(ctx, t, env)
 	case *ast.ArrowExpression:
-		return e.evalConnectExpression(t, env)
+		return e.evalConnectExpression(ctx, t, env)
 	case *ast.FunctionLiteral:
-		return e.evalFunctionDefinition(t, env)
+		return e.evalFunctionDefinition(ctx, t, env)
 	case *ast.BlockExpression:
-		return e.evalBlockExpression(t, env)
+		return e.evalBlockExpression(ctx, t, env)
 	case *ast.ListLiteral:
-		return e.evalList(t, env)
+		return e.evalList(ctx, t, env)
 	case *ast.MapLiteral:
-		return e.evalMap(t, env)
+		return e.evalMap(ctx, t, env)
+	case *ast.HashLiteral:
+		return e.evalHash(ctx, t, env)
 	case *ast.InternalExpression:
-		return e.evalInternalFunc(t, env)
+		return e.evalInternalFunc(ctx, t, env)
 	case *ast.NotExpression:
-		return e.evalNotExpression(t, env)
+		return e.evalNotExpression(ctx, t, env)
+	case *ast.PrefixExpression:
+		return e.evalPrefix(ctx, t, env)
+	case *ast.IndexExpression:
+		return e.evalIndex(ctx, t, env)
+	case *ast.SliceExpression:
+		return e.evalSlice(ctx, t, env)
+	case *ast.IndexAssignment:
+		return e.evalIndexAssignment(ctx, t, env)
 	default:
 		return nil, fmt.Errorf("unknown node type: %T", n)
 	}
 }
 
-func (e *Evaluator) applyFunction(scope *object.Environment, callable object.Callable, args []object.Object) (object.Object, error) {
+func (e *Evaluator) applyFunction(ctx context.Context, scope *object.Environment, callable object.Callable, args []object.Object) (object.Object, error) {
 	var retObj object.Object
 	var err error
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e.hookCall(callable, args)
+
 	switch fn := callable.(type) { // Allow for other callables
 	case *object.Function:
+		defer e.exitCall()
+		if err := e.enterCall(); err != nil {
+			return nil, err
+		}
 		env := extendFunctionEnv(fn, args)
-		retObj, err = e.eval(fn.Body, env)
+		retObj, err = e.eval(ctx, fn.Body, env)
 	case *object.InternalFunction:
 		retObj, err = fn.Fn.Fn(fn.Env, args)
+	case *object.BuiltinMethod:
+		// Each call of a method's function counts as a loop iteration.
+		retObj, err = fn.Call(func(f object.Callable, a []object.Object) (object.Object, error) {
+			if err := e.addIteration(); err != nil {
+				return nil, err
+			}
+			return e.applyFunction(ctx, scope, f, a)
+		}, args)
 	default:
 		return nil, fmt.Errorf("not a function")
 	}
 
-	if err != nil {
+	if errors.Is(err, errBreak) || errors.Is(err, errContinue) {
+		// Loops don't reach into the functions called from them.
+		return nil, fmt.Errorf("%s", err)
+	} else if err != nil {
 		return nil, err
 	} else if retObj != nil {
 		// If we're returning a node, we need to start tracking it.
@@ -569,23 +859,29 @@ func (e *Evaluator) applyFunction(scope *object.Environment, callable object.Cal
 		}
 		return retObj, nil
 	}
-	return nil, nil
+	return object.None, nil
 }
 
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
 	env := fn.Env.Clone()
 	params := fn.FuncParameters()
 	for i, p := range args {
-		env.Put(params[i].Identifier.String(), p)
+		env.PutLocal(params[i].Identifier.String(), p)
 	}
 	return env
 }
 
-func (e *Evaluator) EvalProgram(prog *stitch.Program, env *object.Environment) (object.Object, error) {
+func (e *Evaluator) EvalProgram(ctx context.Context, tree *ast.ASTree, env *object.Environment) (object.Object, error) {
+	ctx, cancel := e.Start(ctx)
+	defer cancel()
+
 	var obj object.Object
-	for _, stmt := range prog.Tree.Statements {
-		if o, err := e.eval(stmt, env); err != nil {
-			return nil, err
+	for _, stmt := range tree.Statements {
+		if err := ctx.Err(); err != nil {
+			return nil, e.checkTimeout(err)
+		}
+		if o, err := e.eval(ctx, stmt, env); err != nil {
+			return nil, e.checkTimeout(err)
 		} else {
 			obj = o
 		}
@@ -593,6 +889,14 @@ func (e *Evaluator) EvalProgram(prog *stitch.Program, env *object.Environment) (
 	return obj, nil
 }
 
+// EvalStatement evaluates a single statement of a program, for callers that
+// need to know which statement failed. The program's statements share the
+// context and policy limits given by Start.
+func (e *Evaluator) EvalStatement(ctx context.Context, stmt ast.Statement, env *object.Environment) (object.Object, error) {
+	obj, err := e.eval(ctx, stmt, env)
+	return obj, e.checkTimeout(err)
+}
+
 /*
 func (e *Evaluator) Compile(prog *ast.Program) (*graph.Graph, error) {
 	env := object.NewEnvironment()
@@ -655,6 +959,8 @@ func visitNode(n *object.Node, visited map[*object.Node]int, g *graph.Graph) (in
 				args[ident] = t.Value
 			case *object.BoolObject:
 				args[ident] = bool(*t)
+			case *object.OID, *object.IP, *object.CIDR:
+				args[ident] = t.Inspect() // Gaufre takes them as strings.
 			default:
 				return 0, fmt.Errorf("%s not supported as node argument", obj.Type())
 			}
@@ -684,10 +990,14 @@ func visitNode(n *object.Node, visited map[*object.Node]int, g *graph.Graph) (in
 			if endNode, err := g.NodeById(uint(endNodeId)); err != nil {
 				return 0, err
 			} else {
-				endRef, _ := endNode.OutputRefByName(conn.End.Name)
-
-				slotId, _ := endRef.SocketId()
-				g.Connect(&startNode, 0, endNode, slotId)
+				// Gaufre nodes only have a single input, so the output slot is
+				// the only socket that needs resolving.
+				startRef, err := startNode.OutputRefByName(conn.Start.Name)
+				if err != nil {
+					return 0, fmt.Errorf("unknown output slot '%s' on %s", conn.Start.Name, startNode.Type)
+				}
+				slotId, _ := startRef.SocketId()
+				g.Connect(&startNode, slotId, endNode, 0)
 			}
 		}
 	}
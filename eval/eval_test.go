@@ -0,0 +1,591 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/object"
+	"github.com/nirosys/stitch/parsing"
+)
+
+func evalSource(t *testing.T, e *Evaluator, src string) (object.Object, error) {
+	return evalSourceContext(context.Background(), t, e, src)
+}
+
+func evalSourceContext(ctx context.Context, t *testing.T, e *Evaluator, src string) (object.Object, error) {
+	parser := parsing.NewParser(strings.NewReader(src))
+	tree := parser.Parse()
+	if tree == nil {
+		t.Fatalf("unable to parse %q: %v", src, parser.Errors())
+	}
+	return e.EvalProgram(ctx, tree, object.NewEnvironment())
+}
+
+// evalTest is a program, and what it evaluates to, as inspected, or the end of
+// the error it fails with.
+type evalTest struct {
+	src      string
+	expected string
+	err      string
+}
+
+// runEvalTests evaluates each test with a new evaluator and environment, which
+// setup can add to first.
+func runEvalTests(t *testing.T, tests []evalTest, setup ...func(e *Evaluator, env *object.Environment)) {
+	t.Helper()
+	for i, test := range tests {
+		e, env := NewEvaluator(), object.NewEnvironment()
+		for _, s := range setup {
+			s(e, env)
+		}
+		parser := parsing.NewParser(strings.NewReader(test.src))
+		tree := parser.Parse()
+		if tree == nil {
+			t.Errorf("[%d] unable to parse %q: %v", i, test.src, parser.Errors())
+			continue
+		}
+		obj, err := e.EvalProgram(context.Background(), tree, env)
+		if test.err != "" {
+			if err == nil || !strings.HasSuffix(err.Error(), test.err) {
+				t.Errorf("[%d] expected error %q, found %v", i, test.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+		} else if obj.Inspect() != test.expected {
+			t.Errorf("[%d] expected %s, found %s", i, test.expected, obj.Inspect())
+		}
+	}
+}
+
+func Test_Cancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := evalSourceContext(ctx, t, NewEvaluator(), "fn f(x) { f(x) }\nf(1)"); err != context.DeadlineExceeded {
+		t.Errorf("expected recursion to be cancelled, found %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := evalSourceContext(ctx, t, NewEvaluator(), "foreach i in [1, 2] { i }"); err != context.Canceled {
+		t.Errorf("expected evaluation to be cancelled, found %v", err)
+	}
+}
+
+type recordingHook struct {
+	BaseHook
+	events []string
+}
+
+func (r *recordingHook) Call(fn object.Callable, args []object.Object) {
+	r.events = append(r.events, fmt.Sprintf("call %d", len(args)))
+}
+
+func (r *recordingHook) Construct(node *object.Node) {
+	r.events = append(r.events, "construct "+node.NodeType.Name)
+}
+
+func (r *recordingHook) Connect(from, to object.Object) {
+	r.events = append(r.events, fmt.Sprintf("connect %s -> %s", from.Type(), to.Type()))
+}
+
+func Test_Hooks(t *testing.T) {
+	e := NewEvaluator()
+	e.Resolver = mapResolver{
+		"snmp:get": &object.NodeType{Name: "snmp:get", InputSlots: []string{"Input"}, OutputSlots: []string{"Output"}},
+	}
+	hook := &recordingHook{}
+	e.Hooks = []EvalHook{hook}
+
+	src := `let g = internal "snmp:get"
+fn pair(n) { n -> [g(), g()] }
+pair(g())`
+	if _, err := evalSource(t, e, src); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"construct snmp:get",
+		"call 1",
+		"construct snmp:get",
+		"construct snmp:get",
+		"connect NODE -> LIST",
+	}
+	if !reflect.DeepEqual(hook.events, expected) {
+		t.Errorf("expected events %v, found %v", expected, hook.events)
+	}
+
+	stop := errors.New("stop")
+	e.Hooks = []EvalHook{stopHook{err: stop}}
+	if _, err := evalSource(t, e, src); err != stop {
+		t.Errorf("expected the hook to stop evaluation, found %v", err)
+	}
+}
+
+// Connections leave from the output slot they were made from, and arrive at
+// the single input every gaufre node has.
+func Test_CompileObject(t *testing.T) {
+	e := NewEvaluator()
+	e.Resolver = mapResolver{
+		"snmp:walk": &object.NodeType{Name: "snmp:walk", InputSlots: []string{"Input"}, OutputSlots: []string{"Output", "Error"}},
+	}
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"w.Output -> walk()", `"connections":[{"start":"$ref:/node/0/output/0","end":"$ref:/node/1/input/0"}]`},
+		{"w.Error -> walk()", `"connections":[{"start":"$ref:/node/0/output/1","end":"$ref:/node/1/input/0"}]`},
+	}
+	for i, test := range tests {
+		env := object.NewEnvironment()
+		tree := parsing.NewParser(strings.NewReader("let walk = internal \"snmp:walk\"\nlet w = walk()\n" + test.src)).Parse()
+		if _, err := e.EvalProgram(context.Background(), tree, env); err != nil {
+			t.Fatalf("[%d] unexpected error: %s", i, err)
+		}
+		w, _ := env.Get("w")
+		g, err := e.CompileObject(w.(*object.Node))
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+			continue
+		}
+		if b, err := json.Marshal(g); err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+		} else if !strings.Contains(string(b), test.expected) {
+			t.Errorf("[%d] expected %s in %s", i, test.expected, b)
+		}
+	}
+
+	// Programs can't connect from undeclared slots, but nodes built in Go can.
+	walk, _ := e.Resolver.Resolve("snmp:walk")
+	from, _ := walk.(*object.NodeType).Construct(nil)
+	to, _ := walk.(*object.NodeType).Construct(nil)
+	from.(*object.Node).ConnectSlots("Missing", to.(*object.Node).GetSlot("Input"))
+	if _, err := e.CompileObject(from.(*object.Node)); err == nil || err.Error() != "unknown output slot 'Missing' on snmp:walk" {
+		t.Errorf("expected an error for an unknown output slot, found %v", err)
+	}
+}
+
+type stopHook struct {
+	BaseHook
+	err error
+}
+
+func (s stopHook) Enter(n ast.Node, env *object.Environment) error {
+	return s.err
+}
+
+func Test_Foreach(t *testing.T) {
+	tests := []evalTest{
+		{`foreach i in [1, 2, 3] { i * 2 }`, "[2, 4, 6]", ""},
+		{`foreach i in 5 { if i == 1 { continue }; if i == 3 { break }; i }`, "[0, 2]", ""},
+		{`foreach k, v in {b = 2; a = 1} { k + "=" + v }`, `["b=2", "a=1"]`, ""},
+		{`foreach k in {b = 2; a = 1} { k }`, `["b", "a"]`, ""},
+		{`foreach i, v in ["x", "y"] { i }`, "[0, 1]", ""},
+		{`let i = "outer"` + "\n" + `foreach i in 2 { let x = i }` + "\n" + `i`, `"outer"`, ""},
+		{`foreach i in 2 { let x = i }` + "\n" + `x`, "", "unknown identifier 'x'"},
+		{`foreach i in [1, 2] { undefined }`, "", "unknown identifier 'undefined'"},
+		{`foreach i in "abc" { i }`, "", "cannot loop over STRING, expected a LIST, MAP, RANGE, CIDR or INTEGER"},
+		{`foreach i in [1, 2] { if i == 1 { "a" } else { 2 } }`, "", "mixed types for list, STRING and INTEGER"},
+		{`break`, "", "break outside of foreach"},
+		{`fn f() { continue }` + "\n" + `foreach i in 2 { f() }`, "", "continue outside of foreach"},
+	}
+	runEvalTests(t, tests)
+
+	// Nodes made in a loop are roots of the graph, unless they have a name.
+	e := NewEvaluator()
+	e.Resolver = mapResolver{"snmp:get": &object.NodeType{Name: "snmp:get", InputSlots: []string{"Input"}, OutputSlots: []string{"Output"}}}
+	roots := []struct {
+		src     string
+		unbound int
+	}{
+		{"foreach i in 2 { get() }", 2},
+		{"let a = get()\nforeach i in 2 { a }", 0},
+		{"get()\nforeach i in 2 { let n = get()\nn }", 3},
+	}
+	for i, test := range roots {
+		env := object.NewEnvironment()
+		tree := parsing.NewParser(strings.NewReader("let get = internal \"snmp:get\"\n" + test.src)).Parse()
+		if _, err := e.EvalProgram(context.Background(), tree, env); err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+		} else if n := len(env.GetUnboundNodes()); n != test.unbound {
+			t.Errorf("[%d] expected %d unbound nodes, found %d", i, test.unbound, n)
+		}
+	}
+}
+
+func Test_Match(t *testing.T) {
+	tests := []evalTest{
+		{`match "b" { "a" => 1, "b" => 2, _ => 3 }`, "2", ""},
+		{`match 4 { 1 | 2 => "low", 3 | 4 => "high" }`, `"high"`, ""},
+		{`match 9 { 1 => "one", _ => "other" }`, `"other"`, ""},
+		{`let x = 2` + "\n" + `match x == 2 { true => "yes", false => "no" }`, `"yes"`, ""},
+		{`match 1 { "1" => "string", 1 => "integer" }`, `"integer"`, ""},
+	}
+	runEvalTests(t, tests)
+
+	if obj, err := evalSource(t, NewEvaluator(), `match 5 { 1 => 2 }`); err != nil || obj != object.None {
+		t.Errorf("expected none without a matching arm, found %v, %v", obj, err)
+	}
+}
+
+func Test_Try(t *testing.T) {
+	types := mapResolver{
+		"snmp:walk": &object.NodeType{Name: "snmp:walk", InputSlots: []string{"Input"}, OutputSlots: []string{"Output"}},
+	}
+	tests := []evalTest{
+		{`try { 1 } catch { 2 }`, "1", ""},
+		{`try { undefined } catch { 2 }`, "2", ""},
+		{`try { internal "host:fastwalk" } catch { internal "snmp:walk" }`, "node snmp:walk {Inputs:[Input],Outputs:[Output],Arguments:[]}", ""},
+		{`try { let a = 1` + "\n" + `  missing } catch err { err.message }`, `"unknown identifier 'missing'"`, ""},
+		{`fn f() {` + "\n" + `  missing }` + "\n" + `try { f() } catch err { "${err.line}/${err.column}: ${err.message}" }`, `"1/2: unknown identifier 'missing'"`, ""},
+		{`try {` + "\n" + `  internal "host:x" } catch err { [err.line, err.column] }`, "[1, 2]", ""},
+		{`try { missing } catch err { err.nope }`, "", "'nope' not defined for error"},
+		{`let e = 1` + "\n" + `try { missing } catch e { e }` + "\n" + `e`, "1", ""},
+		{`foreach i in 3 { try { if i == 1 { break }; i } catch { 0 } }`, "[0]", ""},
+	}
+	runEvalTests(t, tests, func(e *Evaluator, env *object.Environment) {
+		e.Resolver = types
+	})
+
+	// Policy violations can't be caught.
+	e := NewEvaluator()
+	e.Resolver = types
+	e.Policy = &Policy{Internals: []string{"std:*"}}
+	if _, err := evalSource(t, e, `try { internal "snmp:walk" } catch { 1 }`); !errors.Is(err, ErrPolicy) {
+		t.Errorf("expected a policy error, found %v", err)
+	}
+}
+
+func Test_Index(t *testing.T) {
+	tests := []evalTest{
+		{`[1, 2, 3][0]`, "1", ""},
+		{`[1, 2, 3][-1]`, "3", ""},
+		{`[1, 2, 3, 4][1:3]`, "[2, 3]", ""},
+		{`[1, 2, 3, 4][:-1]`, "[1, 2, 3]", ""},
+		{`[1, 2, 3, 4][2:]`, "[3, 4]", ""},
+		{`"ifHCInOctets"[0:4]`, `"ifHC"`, ""},
+		{`"héllo"[1]`, `"é"`, ""},
+		{`let m = {a = 1}` + "\n" + `m["a"]`, "1", ""},
+		{`let m = {a = 1}` + "\n" + `let k = "ifHCInOctets"` + "\n" + `m[k] = 2` + "\n" + `m[k] + m["a"]`, "3", ""},
+		{`let l = [1, 2]` + "\n" + `l[-1] = 5` + "\n" + `l`, "[1, 5]", ""},
+		{`-[1, 2][0]`, "-1", ""},
+		{`[1, 2, 3][3]`, "", "index 3 out of range for length 3"},
+		{`[1, 2, 3]` + "\n" + `[1, 2][-3]`, "", "index -3 out of range for length 2"},
+		{`[1, 2][2:1]`, "", "slice bounds out of range [2:1]"},
+		{`[1, 2][0:3]`, "", "slice bound 3 out of range for length 2"},
+		{`{a = 1}["b"]`, "", "key \"b\" not found"},
+		{`{a = 1}[0]`, "", "key 0 not found"},
+		{`{a = 1}[true]`, "", "map keys are STRING or INTEGER, found BOOL"},
+		{`[1, 2]["a"]`, "", "index must be INTEGER, found STRING"},
+		{`let l = [1, 2]` + "\n" + `l[0] = "a"`, "", "cannot put STRING in a list of INTEGER"},
+		{`"abc"[0] = "x"`, "", "cannot assign to an index of STRING"},
+		{`5[0]`, "", "cannot index INTEGER"},
+	}
+	runEvalTests(t, tests)
+}
+
+func Test_Maps(t *testing.T) {
+	tests := []evalTest{
+		{`#{"b": 1, "a": 2}`, "{b=1;a=2;}", ""},
+		{`#{}`, "{}", ""},
+		{`#{"ifHCInOctets": 1, 2: "two", "a b": [1]}`, `{ifHCInOctets=1;2="two";"a b"=[1];}`, ""},
+		{`#{1: "a", "1": "b"}.len()`, "2", ""},
+		{`let m = #{1: "a", 2: "b"}` + "\n" + `m.keys()[0] + 1`, "2", ""},
+		{`let m = #{1: "a"}` + "\n" + `foreach k, v in m { k * 10 }`, "[10]", ""},
+		{`#{1: "a"}.contains(1)`, "true", ""},
+		{`#{1: "a", "b": 2}.keys()`, `[1, "b"]`, ""},
+		{`#{1: "a", "b": 2}.values()`, `["a", 2]`, ""},
+		{`let k = #{1: "a", "b": 2}.keys()` + "\n" + `k[0] = true` + "\n" + `k`, `[true, "b"]`, ""},
+		{`#{1: "a", "b": 2}.keys().sort()`, "", "cannot sort a list of mixed types"},
+		{`#{1: "a"}.contains("1")`, "false", ""},
+		{`let m = #{1: "a", "b": 2}` + "\n" + `m.delete(1)` + "\n" + `m`, "{b=2;}", ""},
+		{`#{"a": 1}.contains(true)`, "", "map keys are STRING or INTEGER, found BOOL"},
+		{`let k = "x"` + "\n" + `#{k: 1}["x"]`, "1", ""},
+		{`#{1: "one"}[1]`, `"one"`, ""},
+		{`#{"a": 1, "b": 2} + #{"b": 3, "c": 4}`, "{a=1;b=3;c=4;}", ""},
+		{`#{"a": 1, "b": [1, 2]} == #{"b": [1, 2], "a": 1}`, "true", ""},
+		{`#{"a": 1} == #{"a": 2}`, "false", ""},
+		{`#{"a": 1} == {a = 1}`, "true", ""},
+		{`let m = #{"a": 1, "b": 2}` + "\n" + `m.delete("a")` + "\n" + `m`, "{b=2;}", ""},
+		{`let m = #{"a": 1}` + "\n" + `m.delete("b")`, "false", ""},
+		{`let m = #{"z": 1, "a": 2}` + "\n" + `m["m"] = 3` + "\n" + `m.keys()`, `["z", "a", "m"]`, ""},
+		{`#{true: 1}`, "", "map keys are STRING or INTEGER, found BOOL"},
+		{`#{"a": 1} - #{"a": 1}`, "", "operator '-' not defined for MAP"},
+		{`#{"a": 1} < #{"a": 1}`, "", "cannot compare map relatively"},
+	}
+	runEvalTests(t, tests)
+}
+
+func Test_Strings(t *testing.T) {
+	tests := []evalTest{
+		{`let vendor = "cisco"` + "\n" + `vendor == "cisco"`, "true", ""},
+		{`"cisco" != "juniper"`, "true", ""},
+		{`"a" < "b"`, "true", ""},
+		{`"b" >= "a"`, "true", ""},
+		{`"Z" < "a"`, "true", ""},
+		{`"if" + "Index" + 1`, `"ifIndex1"`, ""},
+		{`"a\"b\n"`, `"a\"b\n"`, ""},
+		{"`\\d+`.len()", "3", ""},
+		{`match "cisco" { "juniper" => 1, "cisco" => 2, _ => 3 }`, "2", ""},
+		{`let idx = 3` + "\n" + `"ifInOctets.${idx}"`, `"ifInOctets.3"`, ""},
+		{`let m = #{"a": "x"}` + "\n" + `"${m["a"]}: ${[1, 2].map(fn(x): x * 2)}"`, `"x: [2, 4]"`, ""},
+		{`let host = "r1"` + "\n" + `"${host} {{ .Value }}"`, `"r1 {{ .Value }}"`, ""},
+		{`"\${idx}"`, `"${idx}"`, ""},
+		{`let n = 1` + "\n" + `"{{ .Input.Key.Token - ${n} }}"`, `"{{ .Input.Key.Token - 1 }}"`, ""},
+		{`"{{ .Input.Key.Token - \${n} }}"`, `"{{ .Input.Key.Token - ${n} }}"`, ""},
+		{`"${nope}"`, "", "unknown identifier 'nope'"},
+		{`"a" == 1`, "", "STRING is not comparable to INTEGER"},
+		{`"a" - "b"`, "", "operator '-' not defined for STRING"},
+		{`"-" * 3`, "", "operator '*' not defined for STRING"},
+	}
+	runEvalTests(t, tests)
+}
+
+func Test_Operators(t *testing.T) {
+	tests := []evalTest{
+		{`let x = 3` + "\n" + `x * -2`, "-6", ""},
+		{`+3 - -3`, "6", ""},
+		{`~0`, "-1", ""},
+		{`-2 ** 2`, "-4", ""},
+		{`(-2) ** 2`, "4", ""},
+		{`2 ** 3 ** 2`, "512", ""},
+		{`2 ** 32`, "4294967296", ""},
+		{`2 ** 64`, "0", ""},
+		{`2 ** 63`, "-9223372036854775808", ""},
+		{`9223372036854775807 + 1`, "-9223372036854775808", ""},
+		{`6 & 3 | 8 ^ 1`, "11", ""},
+		{`1 << 4 >> 1`, "8", ""},
+		{`-16 >> 2`, "-4", ""},
+		{`1 << 64`, "0", ""},
+		{`1 + 2 == 3 & 3`, "true", ""},
+		{`[1, 2] == [1, 2]`, "true", ""},
+		{`[1, 2] != [2, 1]`, "true", ""},
+		{`[[1], [2]] == [[1], [2]]`, "true", ""},
+		{`[1] == 1`, "false", ""},
+		{`match 3 { 1 | 3 => "odd", _ => "even" }`, `"odd"`, ""},
+		{`match 3 { (1 | 2) => "three", _ => "other" }`, `"three"`, ""},
+		{`1 / 0`, "", "division by zero"},
+		{`1 % 0`, "", "modulus by zero"},
+		{`2 ** -1`, "", "negative exponent -1"},
+		{`1 << -1`, "", "negative shift count -1"},
+		{`-"a"`, "", "operator '-' not defined for STRING"},
+		{`[1] & 1`, "", "operator '&' not defined for type LIST"},
+	}
+	runEvalTests(t, tests)
+}
+
+func Test_Ranges(t *testing.T) {
+	tests := []evalTest{
+		{`1..4`, "1..4", ""},
+		{`foreach i in 1..4 { i * 2 }`, "[2, 4, 6, 8]", ""},
+		{`foreach i in 0..<3 { i }`, "[0, 1, 2]", ""},
+		{`foreach i, v in 5..6 { [i, v] }`, "[[0, 5], [1, 6]]", ""},
+		{`foreach i in 3..1 { i }`, "[]", ""},
+		{`foreach i in 2..<2 { i }`, "[]", ""},
+		{`(1..48).len()`, "48", ""},
+		{`(0..<1000000000000).len()`, "1000000000000", ""},
+		{`(1..3).map(fn(x): x * x)`, "[1, 4, 9]", ""},
+		{`(1..5)[-1]`, "5", ""},
+		{`(1..5)[1:3]`, "2..<4", ""},
+		{`[(0..<1000000000000)[2:5]].contains([2, 3, 4])`, "true", ""},
+		{`(0..<1000000000000).contains(999999999999)`, "true", ""},
+		{`(0..10).contains(11)`, "false", ""},
+		{`[1..3].contains(1..<4)`, "true", ""},
+		{`1..3 == 1..<4`, "true", ""},
+		{`1..3 != 2..3`, "true", ""},
+		{`(0..<1000000000000) == (0..<1000000000000)`, "true", ""},
+		{`1..3 == [1, 2, 3]`, "true", ""},
+		{`[1, 2] == 1..3`, "false", ""},
+		{`(0..<1000000000000).sort()`, "", "0..<1000000000000 has 1000000000000 integers, too many for a list"},
+		{`[[1, 2, 3]].contains(1..3)`, "true", ""},
+		{`(1..10).filter(fn(x): x % 4 == 0)`, "[4, 8]", ""},
+		{`let n = 2` + "\n" + `[10, 20, 30][0..<n]`, "", "index must be INTEGER, found RANGE"},
+		{`(1..5)[5]`, "", "index 5 out of range for length 5"},
+		{`1.."a"`, "", "range bounds must be INTEGER, found STRING"},
+		{`(1..3) + [4]`, "[1, 2, 3, 4]", ""},
+		{`[1] + (2..3)`, "[1, 2, 3]", ""},
+		{`(1..2) + (3..<5)`, "[1, 2, 3, 4]", ""},
+		{`(1..3)[0] + 1`, "2", ""},
+		{`(1..3) - [1]`, "", "operator '-' not defined for RANGE"},
+		{`[1] * 2`, "", "operator '*' not defined for LIST"},
+		{`(0..<1000000000000) + [1]`, "", "0..<1000000000000 has 1000000000000 integers, too many for a list"},
+		// Ranges as long as an int64 allows, at either end of one.
+		{`(0..<9223372036854775807).len()`, "9223372036854775807", ""},
+		{`(-9223372036854775807 - 1..<-1).len()`, "9223372036854775807", ""},
+		{`(1..9223372036854775807)[-1]`, "9223372036854775807", ""},
+		{`(1..9223372036854775807)[9223372036854775805:]`, "9223372036854775806..9223372036854775807", ""},
+		{`(-9223372036854775807 - 1..0).contains(-9223372036854775807 - 1)`, "", "has more than 9223372036854775807 integers"},
+		{`(-9223372036854775807..<0).contains(-9223372036854775807)`, "true", ""},
+		{`foreach i in 9223372036854775806..9223372036854775807 { i }`, "[9223372036854775806, 9223372036854775807]", ""},
+		{`(0..9223372036854775807).len()`, "", "0..9223372036854775807 has more than 9223372036854775807 integers"},
+		{`foreach i in -1..9223372036854775807 { i }`, "", "-1..9223372036854775807 has more than 9223372036854775807 integers"},
+	}
+	runEvalTests(t, tests)
+
+	// Stepped ranges, as made by std:range.
+	steps := []struct {
+		start, end, step int64
+		expected         string
+	}{
+		{0, 10, 3, "[0, 3, 6, 9]"},
+		{10, 0, -4, "[10, 6, 2]"},
+		{0, 10, -1, "[]"},
+	}
+	for i, test := range steps {
+		r, err := object.NewRange(test.start, test.end, test.step)
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+		} else if list, err := r.List(); err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+		} else if list.Inspect() != test.expected {
+			t.Errorf("[%d] expected %s, found %s", i, test.expected, list.Inspect())
+		}
+	}
+	if _, err := object.NewRange(0, 1, 0); err == nil {
+		t.Errorf("expected an error for a step of 0")
+	}
+	if _, err := object.NewRange(9223372036854775807, -9223372036854775807-1, -2); err == nil {
+		t.Errorf("expected an error for a range longer than an int64 allows")
+	}
+	if r, err := object.NewRange(9223372036854775807, -9223372036854775807-1, -3); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if r.Len() != 6148914691236517205 || !r.Contains(9223372036854775804) || r.Contains(9223372036854775806) {
+		t.Errorf("expected every third int64 from the largest down, found %d integers", r.Len())
+	}
+}
+
+func Test_NetworkTypes(t *testing.T) {
+	oid, _ := object.NewOID(".1.3.6.1.2.1.2.2.1.10.5")
+	ip, _ := object.NewIP("10.0.0.7")
+	cidr, _ := object.NewCIDR("10.0.0.5/30")
+	wide, _ := object.NewCIDR("2001:db8::/32")
+
+	tests := []evalTest{
+		{`oid`, "1.3.6.1.2.1.2.2.1.10.5", ""},
+		{`oid.len()`, "11", ""},
+		{`oid[-1]`, "5", ""},
+		{`oid.append(1)`, "1.3.6.1.2.1.2.2.1.10.5.1", ""},
+		{`oid.append("0.2")`, "1.3.6.1.2.1.2.2.1.10.5.0.2", ""},
+		{`oid.startswith("1.3.6.1.2.1.2")`, "true", ""},
+		{`oid.startswith(oid.append(1))`, "false", ""},
+		{`oid.index("1.3.6.1.2.1.2.2.1")`, "10.5", ""},
+		{`oid == oid.index("1")`, "false", ""},
+		{`oid < oid.append(0)`, "true", ""},
+		{`oid.index(oid)`, "", "1.3.6.1.2.1.2.2.1.10.5 is not below 1.3.6.1.2.1.2.2.1.10.5"},
+		{`oid.append(-1)`, "", "-1 is not an OID arc"},
+		{`oid.append("1.x")`, "", "invalid OID '1.x', 'x' is not an arc"},
+		{`ip.version()`, "4", ""},
+		{`cidr`, "10.0.0.4/30", ""},
+		{`cidr.len()`, "4", ""},
+		{`cidr.prefix()`, "30", ""},
+		{`cidr.network()`, "10.0.0.4", ""},
+		{`cidr.contains(ip)`, "true", ""},
+		{`cidr.contains("10.0.0.8")`, "false", ""},
+		{`foreach a in cidr { a }`, "[10.0.0.4, 10.0.0.5, 10.0.0.6, 10.0.0.7]", ""},
+		{`(foreach a in cidr { a }).contains(ip)`, "true", ""},
+		{`wide.contains("2001:db8::1")`, "true", ""},
+		{`foreach a in wide { a }`, "", "2001:db8::/32 has too many addresses to count"},
+		{`cidr.contains(1)`, "", "contains expects an IP, found INTEGER"},
+	}
+	runEvalTests(t, tests, func(e *Evaluator, env *object.Environment) {
+		env.Put("oid", oid)
+		env.Put("ip", ip)
+		env.Put("cidr", cidr)
+		env.Put("wide", wide)
+	})
+
+	for i, text := range []string{"", "1..3", "1.3.6.4294967296", "1.3.-6"} {
+		if _, err := object.NewOID(text); err == nil {
+			t.Errorf("[%d] expected an error for OID %q", i, text)
+		}
+	}
+	if _, err := object.NewIP("10.0.0.256"); err == nil {
+		t.Errorf("expected an error for an invalid IP address")
+	}
+	if _, err := object.NewCIDR("10.0.0.0/33"); err == nil {
+		t.Errorf("expected an error for an invalid CIDR")
+	}
+}
+
+func Test_None(t *testing.T) {
+	tests := []evalTest{
+		{`none`, "none", ""},
+		{`let x = if false { 1 }` + "\n" + `x`, "none", ""},
+		{`let x = if false { 1 }` + "\n" + `x + 1`, "", "operator '+' not defined for type NONE"},
+		{`let x = if false { 1 }` + "\n" + `x ?? 5`, "5", ""},
+		{`3 ?? 5`, "3", ""},
+		{`none ?? none ?? "c"`, `"c"`, ""},
+		{`1 ?? undefined`, "1", ""},
+		{`fn f() { let a = 1 }` + "\n" + `f()`, "none", ""},
+		{`fn f() { let a = 1 }` + "\n" + `f() is none`, "true", ""},
+		{`0 is none`, "false", ""},
+		{`none == none`, "true", ""},
+		{`1 != none`, "true", ""},
+		{`match none { 1 => "one", none => "none" }`, `"none"`, ""},
+		{`match 2 { 1 => "one" } ?? "other"`, `"other"`, ""},
+		{`foreach i in 4 { if i % 2 == 0 { i } }`, "[0, 2]", ""},
+		{`[none].contains(none)`, "true", ""},
+		{`"${none}"`, `"none"`, ""},
+		{`none.x`, "", "'x' not defined for none"},
+		{`none < 1`, "", "NONE is not comparable"},
+		{`-none`, "", "operator '-' not defined for NONE"},
+	}
+	runEvalTests(t, tests)
+}
+
+// Constants hold without analysis, as when a program is evaluated on its own.
+func Test_Const(t *testing.T) {
+	tests := []evalTest{
+		{`const port = 161` + "\n" + `port`, "161", ""},
+		{`const port = 161` + "\n" + `fn f(port) { port = 2` + "\n" + `port }` + "\n" + `f(1)`, "2", ""},
+		{`const i = 1` + "\n" + `foreach i in [5] { i = i + 1 }`, "[6]", ""},
+		{`const port = 161` + "\n" + `port = 162`, "", "cannot assign to constant: port"},
+		{`const port = 161` + "\n" + `let port = 162`, "", "cannot assign to constant: port"},
+		{`const port = 161` + "\n" + `fn f() { port = 162 }` + "\n" + `f()`, "", "cannot assign to constant: port"},
+		{`const port = 161` + "\n" + `try { port = 162 } catch e { e.message }`, `"cannot assign to constant: port"`, ""},
+	}
+	runEvalTests(t, tests)
+}
+
+func Test_Methods(t *testing.T) {
+	tests := []evalTest{
+		{`[3, 1, 2].len()`, "3", ""},
+		{`[1, 2, 3].map(fn(x): x * 2)`, "[2, 4, 6]", ""},
+		{`[1, 2, 3].filter(fn(x): x > 1)`, "[2, 3]", ""},
+		{`[1, 2, 3].reduce(fn(acc, x): acc + x, 10)`, "16", ""},
+		{`[1, 2, 3].contains(2)`, "true", ""},
+		{`["a", "b"].contains(1)`, "false", ""},
+		{`["ifInOctets", "ifOutOctets"].join(",")`, `"ifInOctets,ifOutOctets"`, ""},
+		{`[3, 1, 2].sort()`, "[1, 2, 3]", ""},
+		{`["b", "a"].sort()`, `["a", "b"]`, ""},
+		{`[1, 2, 1, 3, 2].unique()`, "[1, 2, 3]", ""},
+		{`let m = {b = 2; a = 1}` + "\n" + `m.keys()`, `["b", "a"]`, ""},
+		{`let m = {b = 2; a = 1}` + "\n" + `m.values()`, "[2, 1]", ""},
+		{`let m = {a = 1}` + "\n" + `m.contains("a")`, "true", ""},
+		{`let m = {b = 2; a = 1}` + "\n" + `m.map(fn(k, v): k + "=" + v)`, `["b=2", "a=1"]`, ""},
+		{`let m = {b = 2; a = 1}` + "\n" + `m.filter(fn(k, v): v > 1).keys()`, `["b"]`, ""},
+		{`let m = {len = 5}` + "\n" + `m.len`, "5", ""},
+		{`"1.3.6.1".split(".").len()`, "4", ""},
+		{`"ifHCInOctets".contains("HC")`, "true", ""},
+		{`"héllo".len()`, "5", ""},
+		{`[1, 2].map(fn(a, b): a)`, "", "map expects a function of 1 arguments, found 2"},
+		{`[1, 2].filter(fn(x): x)`, "", "filter function must return BOOL, found INTEGER"},
+		{`[1, 2].map(fn(x): if x == 1 { "a" } else { 2 })`, "", "mixed types for list, STRING and INTEGER"},
+		{`[1, 2].map(1)`, "", "map expects a function for fn, found INTEGER"},
+		{`[1, 2].join()`, "", "expected 1 arguments but found 0"},
+		{`[{a = 1}, {b = 2}].sort()`, "", "cannot sort a list of MAP"},
+		{`[1].nope`, "", "'nope' not defined for list"},
+	}
+	runEvalTests(t, tests)
+
+	e := NewEvaluator()
+	e.Policy = &Policy{MaxIterations: 2}
+	if _, err := evalSource(t, e, `[1, 2, 3].map(fn(x): x)`); !errors.Is(err, ErrPolicy) {
+		t.Errorf("expected method calls to count as iterations, found %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package eval
+
+import (
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/object"
+)
+
+// EvalHook ///////////////////////////////////////////////////////////////////
+
+// EvalHook is told about each step of an evaluation, for tracing and
+// debugging programs.
+type EvalHook interface {
+	// Enter is called before a node of the tree is evaluated. Returning an
+	// error stops the evaluation with that error.
+	Enter(n ast.Node, env *object.Environment) error
+	// Leave is called after a node of the tree is evaluated, with its result.
+	Leave(n ast.Node, result object.Object, err error)
+	// Call is called before a function is applied to its arguments.
+	Call(fn object.Callable, args []object.Object)
+	// Construct is called for each node the program creates.
+	Construct(node *object.Node)
+	// Connect is called for each connection made, with what was connected.
+	Connect(from, to object.Object)
+}
+
+// BaseHook does nothing, so hooks can embed it and implement only the
+// callbacks they need.
+type BaseHook struct{}
+
+func (BaseHook) Enter(n ast.Node, env *object.Environment) error   { return nil }
+func (BaseHook) Leave(n ast.Node, result object.Object, err error) {}
+func (BaseHook) Call(fn object.Callable, args []object.Object)     {}
+func (BaseHook) Construct(node *object.Node)                       {}
+func (BaseHook) Connect(from, to object.Object)                    {}
+
+func (e *Evaluator) hookEnter(n ast.Node, env *object.Environment) error {
+	for _, h := range e.Hooks {
+		if err := h.Enter(n, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) hookLeave(n ast.Node, result object.Object, err error) {
+	for _, h := range e.Hooks {
+		h.Leave(n, result, err)
+	}
+}
+
+func (e *Evaluator) hookCall(fn object.Callable, args []object.Object) {
+	for _, h := range e.Hooks {
+		h.Call(fn, args)
+	}
+}
+
+func (e *Evaluator) hookConstruct(node *object.Node) {
+	for _, h := range e.Hooks {
+		h.Construct(node)
+	}
+}
+
+func (e *Evaluator) hookConnect(from, to object.Object) {
+	for _, h := range e.Hooks {
+		h.Connect(from, to)
+	}
+}
@@ -0,0 +1,211 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/object"
+)
+
+func (e *Evaluator) evalPrefix(ctx context.Context, p *ast.PrefixExpression, env *object.Environment) (object.Object, error) {
+	right, err := e.eval(ctx, p.Right, env)
+	if err != nil {
+		return nil, err
+	}
+	if i, ok := right.(*object.Integer); ok {
+		switch p.Operator {
+		case "-":
+			return &object.Integer{Value: -i.Value}, nil
+		case "+":
+			return i, nil
+		case "~":
+			return &object.Integer{Value: ^i.Value}, nil
+		}
+	}
+	return nil, fmt.Errorf("operator '%s' not defined for %s", p.Operator, typeOf(right))
+}
+
+// Indexing ///////////////////////////////////////////////////////////////////
+
+// Lists, ranges, OIDs and strings are indexed by position, counting back from
+// the end for negative indexes. Strings are indexed by character, not byte, and
+// OIDs by arc.
+func (e *Evaluator) evalIndex(ctx context.Context, in *ast.IndexExpression, env *object.Environment) (object.Object, error) {
+	left, index, err := e.evalIndexOperands(ctx, in, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := left.(type) {
+	case *object.List:
+		if i, err := position(index, len(t.Contents)); err != nil {
+			return nil, err
+		} else {
+			return t.Contents[i], nil
+		}
+	case *object.Range:
+		if i, err := position(index, int(t.Len())); err != nil {
+			return nil, err
+		} else {
+			return t.At(int64(i)), nil
+		}
+	case *object.OID:
+		if i, err := position(index, len(t.Arcs)); err != nil {
+			return nil, err
+		} else {
+			return &object.Integer{Value: int64(t.Arcs[i])}, nil
+		}
+	case *object.String:
+		runes := []rune(t.Value)
+		if i, err := position(index, len(runes)); err != nil {
+			return nil, err
+		} else {
+			return &object.String{Value: string(runes[i])}, nil
+		}
+	case *object.MapObject:
+		if obj, have, err := t.Get(index); err != nil {
+			return nil, err
+		} else if !have {
+			return nil, fmt.Errorf("key %s not found", index.Inspect())
+		} else {
+			return obj, nil
+		}
+	default:
+		return nil, fmt.Errorf("cannot index %s", typeOf(left))
+	}
+}
+
+func (e *Evaluator) evalIndexOperands(ctx context.Context, in *ast.IndexExpression, env *object.Environment) (object.Object, object.Object, error) {
+	left, err := e.eval(ctx, in.Left, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	index, err := e.eval(ctx, in.Index, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	return left, index, nil
+}
+
+// Slices take the elements from low up to, but not including, high.
+func (e *Evaluator) evalSlice(ctx context.Context, s *ast.SliceExpression, env *object.Environment) (object.Object, error) {
+	left, err := e.eval(ctx, s.Left, env)
+	if err != nil {
+		return nil, err
+	}
+	var length int
+	switch t := left.(type) {
+	case *object.Range:
+		length = int(t.Len())
+	case *object.List:
+		length = len(t.Contents)
+	case *object.String:
+		length = len([]rune(t.Value))
+	default:
+		return nil, fmt.Errorf("cannot slice %s", typeOf(left))
+	}
+
+	low, high := 0, length
+	if s.Low != nil {
+		if low, err = e.sliceBound(ctx, s.Low, length, env); err != nil {
+			return nil, err
+		}
+	}
+	if s.High != nil {
+		if high, err = e.sliceBound(ctx, s.High, length, env); err != nil {
+			return nil, err
+		}
+	}
+	if low > high {
+		return nil, fmt.Errorf("slice bounds out of range [%d:%d]", low, high)
+	}
+
+	switch t := left.(type) {
+	case *object.Range: // Slices of ranges are ranges too.
+		return t.Slice(int64(low), int64(high)), nil
+	case *object.List:
+		contents := make([]object.Object, high-low)
+		copy(contents, t.Contents[low:high])
+		return &object.List{Contents: contents, InnerType: t.InnerType}, nil
+	default:
+		return &object.String{Value: string([]rune(left.(*object.String).Value)[low:high])}, nil
+	}
+}
+
+// Bounds can be anywhere from the start to the end, inclusive.
+func (e *Evaluator) sliceBound(ctx context.Context, exp ast.Expression, length int, env *object.Environment) (int, error) {
+	obj, err := e.eval(ctx, exp, env)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := obj.(*object.Integer)
+	if !ok {
+		return 0, fmt.Errorf("slice bounds must be INTEGER, found %s", typeOf(obj))
+	}
+	pos := int(i.Value)
+	if pos < 0 {
+		pos += length
+	}
+	if pos < 0 || pos > length {
+		return 0, fmt.Errorf("slice bound %d out of range for length %d", i.Value, length)
+	}
+	return pos, nil
+}
+
+func (e *Evaluator) evalIndexAssignment(ctx context.Context, a *ast.IndexAssignment, env *object.Environment) (object.Object, error) {
+	left, index, err := e.evalIndexOperands(ctx, a.Target, env)
+	if err != nil {
+		return nil, err
+	}
+	value, err := e.eval(ctx, a.Value, env)
+	if err != nil {
+		return nil, err
+	} else if value == nil {
+		return nil, fmt.Errorf("cannot assign nothing")
+	}
+
+	switch t := left.(type) {
+	case *object.List:
+		if i, err := position(index, len(t.Contents)); err != nil {
+			return nil, err
+		} else if len(t.Contents) > 1 && t.InnerType == object.UnknownObjectType { // Mixed lists take anything.
+			t.Contents[i] = value
+		} else if len(t.Contents) > 1 && t.InnerType != value.Type() { // A single element can change type.
+			return nil, fmt.Errorf("cannot put %s in a list of %s", value.Type(), t.InnerType)
+		} else {
+			t.Contents[i] = value
+			t.InnerType = value.Type()
+		}
+	case *object.MapObject:
+		if err := t.Put(index, value); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cannot assign to an index of %s", typeOf(left))
+	}
+	return value, nil
+}
+
+// Returns the position an index refers to, for something of the length.
+func position(index object.Object, length int) (int, error) {
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return 0, fmt.Errorf("index must be INTEGER, found %s", typeOf(index))
+	}
+	pos := int(i.Value)
+	if pos < 0 {
+		pos += length
+	}
+	if pos < 0 || pos >= length {
+		return 0, fmt.Errorf("index %d out of range for length %d", i.Value, length)
+	}
+	return pos, nil
+}
+
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return "nothing"
+	}
+	return obj.Type()
+}
@@ -0,0 +1,137 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nirosys/stitch/object"
+)
+
+// Policy /////////////////////////////////////////////////////////////////////
+
+// Policy limits what a program can do while it is evaluated, so programs from
+// outside can't hang, or exhaust the memory of, whatever evaluates them. Zero
+// values mean no limit.
+type Policy struct {
+	// Internals are path.Match patterns for the internals the program can
+	// use, eg. "snmp:*".
+	Internals []string
+	// MaxNodes is the number of nodes the program can create.
+	MaxNodes int
+	// MaxConnections is the number of connections the program can make.
+	MaxConnections int
+	// MaxDepth is how deeply function calls can nest.
+	MaxDepth int
+	// MaxIterations is the number of loop iterations, across every loop.
+	MaxIterations int
+	// Timeout is how long each program can be evaluated for.
+	Timeout time.Duration
+}
+
+// ErrPolicy is wrapped by every PolicyError.
+var ErrPolicy = errors.New("policy violation")
+
+// PolicyError is returned when evaluating a program would break its policy.
+type PolicyError struct {
+	Limit   string // The policy field that was exceeded.
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPolicy, e.Message)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return ErrPolicy
+}
+
+func policyErrorf(limit string, format string, args ...interface{}) error {
+	return &PolicyError{Limit: limit, Message: fmt.Sprintf(format, args...)}
+}
+
+// Tracks what a program has used of its policy.
+type usage struct {
+	nodes       int
+	connections int
+	depth       int
+	iterations  int
+	parent      context.Context // The caller's context, before the timeout.
+}
+
+// Start begins a program that's evaluated a statement at a time with
+// EvalStatement. The policy's limits start over, and the returned context is
+// done once the policy's timeout is up. EvalProgram starts each program
+// itself.
+func (e *Evaluator) Start(ctx context.Context) (context.Context, context.CancelFunc) {
+	e.usage = usage{parent: ctx}
+	if e.Policy == nil || e.Policy.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.Policy.Timeout)
+}
+
+// Running out of time is a policy violation when it's the policy's timeout,
+// rather than the caller's, that ran out.
+func (e *Evaluator) checkTimeout(err error) error {
+	if e.Policy == nil || e.Policy.Timeout <= 0 {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) && e.usage.parent != nil && e.usage.parent.Err() == nil {
+		return policyErrorf("Timeout", "evaluation took longer than %s", e.Policy.Timeout)
+	}
+	return err
+}
+
+func (e *Evaluator) checkInternal(name string) error {
+	if e.Policy == nil || len(e.Policy.Internals) == 0 || matchAny(e.Policy.Internals, name) {
+		return nil
+	}
+	return policyErrorf("Internals", "internal \"%s\" is not allowed", name)
+}
+
+func (e *Evaluator) addNode() error {
+	e.usage.nodes++
+	if e.Policy != nil && e.Policy.MaxNodes > 0 && e.usage.nodes > e.Policy.MaxNodes {
+		return policyErrorf("MaxNodes", "more than %d nodes created", e.Policy.MaxNodes)
+	}
+	return nil
+}
+
+// Adds the connections made by connecting left to right, where lists connect
+// each of their elements.
+func (e *Evaluator) addConnections(left, right object.Object) error {
+	e.usage.connections += connectionWidth(left) * connectionWidth(right)
+	if e.Policy != nil && e.Policy.MaxConnections > 0 && e.usage.connections > e.Policy.MaxConnections {
+		return policyErrorf("MaxConnections", "more than %d connections made", e.Policy.MaxConnections)
+	}
+	return nil
+}
+
+func connectionWidth(obj object.Object) int {
+	if l, ok := obj.(*object.List); ok {
+		return len(l.Contents)
+	}
+	return 1
+}
+
+func (e *Evaluator) enterCall() error {
+	e.usage.depth++
+	if e.Policy != nil && e.Policy.MaxDepth > 0 && e.usage.depth > e.Policy.MaxDepth {
+		return policyErrorf("MaxDepth", "calls nested more than %d deep", e.Policy.MaxDepth)
+	}
+	return nil
+}
+
+func (e *Evaluator) exitCall() {
+	e.usage.depth--
+}
+
+func (e *Evaluator) addIteration() error {
+	e.usage.iterations++
+	if e.Policy != nil && e.Policy.MaxIterations > 0 && e.usage.iterations > e.Policy.MaxIterations {
+		return policyErrorf("MaxIterations", "more than %d loop iterations", e.Policy.MaxIterations)
+	}
+	return nil
+}
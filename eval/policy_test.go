@@ -0,0 +1,85 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nirosys/stitch/object"
+	"github.com/nirosys/stitch/parsing"
+)
+
+func Test_Policy(t *testing.T) {
+	types := mapResolver{
+		"snmp:get": &object.NodeType{Name: "snmp:get", InputSlots: []string{"Input"}, OutputSlots: []string{"Output"}},
+		"std:join": &object.NodeType{Name: "std:join", InputSlots: []string{"Input"}, OutputSlots: []string{"Output"}},
+	}
+	tests := []struct {
+		src    string
+		policy Policy
+		limit  string
+	}{
+		{`let j = internal "std:join"`, Policy{Internals: []string{"snmp:*"}}, "Internals"},
+		{`let g = internal "snmp:get"` + "\n" + `g() -> g() -> g()`, Policy{MaxNodes: 2}, "MaxNodes"},
+		{`let g = internal "snmp:get"` + "\n" + `g() -> [g(), g()]`, Policy{MaxConnections: 1}, "MaxConnections"},
+		{`fn f(x) { f(x) }` + "\n" + `f(1)`, Policy{MaxDepth: 10}, "MaxDepth"},
+		{`foreach i in [1, 2, 3] { i }`, Policy{MaxIterations: 2}, "MaxIterations"},
+		{`fn f(x) { f(x) }` + "\n" + `f(1)`, Policy{Timeout: time.Millisecond}, "Timeout"},
+	}
+	for i, test := range tests {
+		e := NewEvaluator()
+		e.Resolver = types
+		e.Policy = &test.policy
+		_, err := evalSource(t, e, test.src)
+		var policyErr *PolicyError
+		if !errors.As(err, &policyErr) || !errors.Is(err, ErrPolicy) {
+			t.Errorf("[%d] expected a policy error, found %v", i, err)
+		} else if policyErr.Limit != test.limit {
+			t.Errorf("[%d] expected %s to be exceeded, found %s", i, test.limit, policyErr.Limit)
+		}
+	}
+
+	e := NewEvaluator()
+	e.Resolver = types
+	e.Policy = &Policy{Internals: []string{"snmp:*"}, MaxNodes: 3, MaxConnections: 2, MaxDepth: 2, MaxIterations: 3}
+	if _, err := evalSource(t, e, `let g = internal "snmp:get"`+"\n"+`g() -> [g(), g()]`); err != nil {
+		t.Errorf("unexpected error within policy: %s", err)
+	}
+
+	// Each program starts over, so evaluators can be kept for many programs.
+	e.Policy = &Policy{MaxIterations: 3, Timeout: 20 * time.Millisecond}
+	for i := 0; i < 3; i++ {
+		if _, err := evalSource(t, e, `foreach i in 3 { i }`); err != nil {
+			t.Errorf("[%d] unexpected error for a later program: %s", i, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The caller's deadline isn't a policy violation.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	e.Policy = &Policy{Timeout: time.Hour}
+	if _, err := evalSourceContext(ctx, t, e, `fn f(x) { f(x) }`+"\n"+`f(1)`); err != context.DeadlineExceeded {
+		t.Errorf("expected the caller's deadline to be exceeded, found %v", err)
+	}
+
+	// Without a policy timeout, a deadline set after Start is the caller's too.
+	e.Policy = nil
+	ctx, cancel = e.Start(context.Background())
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	tree := parsing.NewParser(strings.NewReader(`fn f(x) { f(x) }` + "\n" + `f(1)`)).Parse()
+	env := object.NewEnvironment()
+	var err error
+	for _, stmt := range tree.Statements {
+		if _, err = e.EvalStatement(ctx, stmt, env); err != nil {
+			break
+		}
+	}
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected the caller's deadline to be exceeded, found %v", err)
+	}
+}
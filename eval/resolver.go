@@ -0,0 +1,162 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nirosys/stitch/object"
+)
+
+// ErrUnknownInternal is returned, wrapped, by resolvers that have nothing by
+// the requested name. Chained resolvers only move on to the next resolver for
+// this error.
+var ErrUnknownInternal = errors.New("unknown internal")
+
+// ErrInternalNotAllowed is returned, wrapped, when a filter refuses a name.
+var ErrInternalNotAllowed = errors.New("internal not allowed")
+
+// UnknownInternal returns the error for a name a resolver doesn't know.
+func UnknownInternal(name string) error {
+	return fmt.Errorf("%w \"%s\"", ErrUnknownInternal, name)
+}
+
+// Lister is implemented by resolvers that can list the names they resolve.
+type Lister interface {
+	Names() []string
+}
+
+// ResolverNames returns the names a resolver resolves, sorted, or nil if the
+// resolver can't list them.
+func ResolverNames(r ObjectResolver) []string {
+	if l, ok := r.(Lister); ok {
+		names := l.Names()
+		sort.Strings(names)
+		return names
+	}
+	return nil
+}
+
+// ChainResolver //////////////////////////////////////////////////////////////
+
+// ChainResolver tries each of its resolvers in order, returning the first
+// that knows the name.
+type ChainResolver struct {
+	Resolvers []ObjectResolver
+}
+
+func Chain(resolvers ...ObjectResolver) *ChainResolver {
+	return &ChainResolver{Resolvers: resolvers}
+}
+
+func (c *ChainResolver) Resolve(name string) (object.Object, error) {
+	for _, r := range c.Resolvers {
+		if obj, err := r.Resolve(name); err == nil {
+			return obj, nil
+		} else if !errors.Is(err, ErrUnknownInternal) {
+			return nil, err
+		}
+	}
+	return nil, UnknownInternal(name)
+}
+
+// Names returns the names of every listable resolver in the chain. Names
+// shadowed by an earlier resolver are only listed once.
+func (c *ChainResolver) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, r := range c.Resolvers {
+		for _, n := range ResolverNames(r) {
+			if !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PrefixResolver /////////////////////////////////////////////////////////////
+
+// PrefixResolver mounts a resolver under a namespace, so "snmp:get" is
+// resolved as "get" by a resolver mounted at "snmp:".
+type PrefixResolver struct {
+	Prefix   string
+	Resolver ObjectResolver
+}
+
+func Mount(prefix string, r ObjectResolver) *PrefixResolver {
+	return &PrefixResolver{Prefix: prefix, Resolver: r}
+}
+
+func (p *PrefixResolver) Resolve(name string) (object.Object, error) {
+	if !strings.HasPrefix(name, p.Prefix) {
+		return nil, UnknownInternal(name)
+	}
+	obj, err := p.Resolver.Resolve(strings.TrimPrefix(name, p.Prefix))
+	if err != nil && errors.Is(err, ErrUnknownInternal) {
+		// Report the name the program used, not the mounted one.
+		return nil, UnknownInternal(name)
+	}
+	return obj, err
+}
+
+func (p *PrefixResolver) Names() []string {
+	names := []string{}
+	for _, n := range ResolverNames(p.Resolver) {
+		names = append(names, p.Prefix+n)
+	}
+	return names
+}
+
+// FilterResolver /////////////////////////////////////////////////////////////
+
+// FilterResolver limits the names a resolver resolves. Names are matched
+// against path.Match patterns, eg. "snmp:*". A name must match one of Allow,
+// if there are any, and none of Deny.
+type FilterResolver struct {
+	Resolver ObjectResolver
+	Allow    []string
+	Deny     []string
+}
+
+func Filter(r ObjectResolver, allow, deny []string) *FilterResolver {
+	return &FilterResolver{Resolver: r, Allow: allow, Deny: deny}
+}
+
+// Allowed reports whether the filter lets the name through.
+func (f *FilterResolver) Allowed(name string) bool {
+	if len(f.Allow) > 0 && !matchAny(f.Allow, name) {
+		return false
+	}
+	return !matchAny(f.Deny, name)
+}
+
+func (f *FilterResolver) Resolve(name string) (object.Object, error) {
+	if !f.Allowed(name) {
+		return nil, fmt.Errorf("%w: \"%s\"", ErrInternalNotAllowed, name)
+	}
+	return f.Resolver.Resolve(name)
+}
+
+func (f *FilterResolver) Names() []string {
+	names := []string{}
+	for _, n := range ResolverNames(f.Resolver) {
+		if f.Allowed(n) {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package eval
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nirosys/stitch/object"
+)
+
+type mapResolver map[string]object.Object
+
+func (m mapResolver) Resolve(name string) (object.Object, error) {
+	if obj, ok := m[name]; ok {
+		return obj, nil
+	}
+	return nil, UnknownInternal(name)
+}
+
+func (m mapResolver) Names() []string {
+	names := []string{}
+	for n := range m {
+		names = append(names, n)
+	}
+	return names
+}
+
+func Test_ComposedResolvers(t *testing.T) {
+	snmp := mapResolver{
+		"get":  &object.NodeType{Name: "snmp:get"},
+		"walk": &object.NodeType{Name: "snmp:walk"},
+	}
+	std := mapResolver{
+		"std:passthru": &object.NodeType{Name: "std:passthru"},
+		"std:println":  &object.NodeType{Name: "std:println"},
+	}
+	r := Filter(Chain(Mount("snmp:", snmp), std), nil, []string{"std:print*"})
+
+	expected := []string{"snmp:get", "snmp:walk", "std:passthru"}
+	if names := ResolverNames(r); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected names %v, found %v", expected, names)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+		err      error
+	}{
+		{"snmp:get", "snmp:get", nil},
+		{"std:passthru", "std:passthru", nil},
+		{"get", "", ErrUnknownInternal},
+		{"snmp:set", "", ErrUnknownInternal},
+		{"std:println", "", ErrInternalNotAllowed},
+	}
+	for i, test := range tests {
+		obj, err := r.Resolve(test.name)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("[%d] expected %v, found %v", i, test.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+		} else if nt := obj.(*object.NodeType); nt.Name != test.expected {
+			t.Errorf("[%d] expected %s, found %s", i, test.expected, nt.Name)
+		}
+	}
+
+	if _, err := r.Resolve("snmp:set"); err == nil || err.Error() != `unknown internal "snmp:set"` {
+		t.Errorf("expected the mounted name in the error, found %v", err)
+	}
+
+	allowed := Filter(Chain(Mount("snmp:", snmp), std), []string{"snmp:*"}, nil)
+	if names := ResolverNames(allowed); !reflect.DeepEqual(names, []string{"snmp:get", "snmp:walk"}) {
+		t.Errorf("unexpected allowed names: %v", names)
+	}
+}
@@ -0,0 +1,76 @@
+package eval
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/lexing"
+	"github.com/nirosys/stitch/object"
+)
+
+// Remembers the position of the innermost node an error was returned from, for
+// the error objects a try expression catches.
+type positionError struct {
+	err error
+	pos lexing.Position
+}
+
+func (p *positionError) Error() string { return p.err.Error() }
+func (p *positionError) Unwrap() error { return p.err }
+
+func atPosition(err error, n ast.Node) error {
+	var pe *positionError
+	if !catchable(err) || errors.As(err, &pe) {
+		return err
+	}
+	return &positionError{err: err, pos: ast.PositionOf(n)}
+}
+
+// Policy violations and cancellation stop the whole evaluation, and break and
+// continue belong to the enclosing loop, so none of them can be caught.
+func catchable(err error) bool {
+	switch {
+	case errors.Is(err, ErrPolicy):
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, errBreak), errors.Is(err, errContinue):
+		return false
+	}
+	return true
+}
+
+// ErrorPosition returns where in the source an evaluation error came from,
+// when it's known.
+func ErrorPosition(err error) (lexing.Position, bool) {
+	var pe *positionError
+	if errors.As(err, &pe) {
+		return pe.pos, true
+	}
+	return lexing.Position{}, false
+}
+
+// Errors don't carry their position in their text, it's kept in the error
+// object's own fields.
+func errorObject(err error) *object.Error {
+	obj := &object.Error{Message: err.Error()}
+	var pe *positionError
+	if errors.As(err, &pe) {
+		obj.Position = pe.pos
+	}
+	return obj
+}
+
+func (e *Evaluator) evalTry(ctx context.Context, t *ast.TryExpression, env *object.Environment) (object.Object, error) {
+	obj, err := e.eval(ctx, t.Block, env)
+	if err == nil || !catchable(err) {
+		return obj, err
+	}
+	scope := env
+	if t.ErrVar != nil {
+		scope = env.Clone()
+		scope.PutLocal(t.ErrVar.Identifier, errorObject(err))
+	}
+	return e.eval(ctx, t.Catch, scope)
+}
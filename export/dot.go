@@ -0,0 +1,56 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DotExporter renders the graph for graphviz, with each node drawn as a
+// record whose ports are the node's slots.
+type DotExporter struct{}
+
+func (d *DotExporter) Export(w io.Writer, g *Graph) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "digraph {\n   rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(out, "   n%d[label=%q, shape=\"Mrecord\"];\n", n.ID, dotLabel(n))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(out, "   n%d:%s -> n%d:%s;\n", e.From.ID, e.FromSlot, e.To.ID, e.ToSlot)
+	}
+	fmt.Fprintf(out, "\n}\n")
+
+	return out.Flush()
+}
+
+func dotLabel(n *Node) string {
+	inputs := []string{}
+	for _, s := range n.InputSlots() {
+		inputs = append(inputs, "<"+s+"> "+s)
+	}
+	outputs := []string{}
+	for _, s := range n.OutputSlots() {
+		outputs = append(outputs, "<"+s+"> "+s)
+	}
+	args := []string{}
+	for _, arg := range n.Args() {
+		// Braces are record delimiters in dot, so templates need to be masked.
+		value := strings.ReplaceAll(strings.ReplaceAll(arg.Value.Inspect(), "{{", `%%`), "}}", "%%")
+		args = append(args, arg.Name+"="+value)
+	}
+	if n.Object.TagName != nil {
+		args = append([]string{fmt.Sprintf("Tag=%q", *n.Object.TagName)}, args...)
+	} else if n.Object.FieldName != nil {
+		args = append([]string{fmt.Sprintf("Field=%q", *n.Object.FieldName)}, args...)
+	}
+
+	return fmt.Sprintf("{{%s}|%s\n%s|{%s}}",
+		strings.Join(inputs, "|"),
+		n.TypeName(),
+		strings.Join(args, "\n"),
+		strings.Join(outputs, "|"),
+	)
+}
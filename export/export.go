@@ -0,0 +1,39 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Exporter ///////////////////////////////////////////////////////////////////
+type Exporter interface {
+	Export(w io.Writer, g *Graph) error
+}
+
+var exporters = map[string]Exporter{
+	"dot":     &DotExporter{},
+	"gaufre":  &GaufreExporter{},
+	"graphml": &GraphMLExporter{},
+	"ir":      &IRExporter{},
+	"mermaid": &MermaidExporter{},
+}
+
+// Lookup returns the exporter registered for the given format name.
+func Lookup(format string) (Exporter, error) {
+	if e, ok := exporters[strings.ToLower(format)]; ok {
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown export format '%s' (expected one of: %s)", format, strings.Join(Formats(), ", "))
+}
+
+// Formats returns the names of all registered formats.
+func Formats() []string {
+	names := make([]string, 0, len(exporters))
+	for n := range exporters {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
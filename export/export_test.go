@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nirosys/stitch/catalog"
+	"github.com/nirosys/stitch/eval"
+	"github.com/nirosys/stitch/object"
+	"github.com/nirosys/stitch/parsing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const exportSource = `let walk = internal "snmp:walk"
+let get = internal "snmp:get"
+let w = @ifDescr:walk("ifDescr")
+w.Output -> octets:get("ifInOctets.{{ .Input.Key }}")
+w.Error -> get("sysUpTime.0")
+`
+
+// Evaluates exportSource, giving the graph from its walk node.
+func exportGraph(t *testing.T) *Graph {
+	tree := parsing.NewParser(strings.NewReader(exportSource)).Parse()
+	if tree == nil {
+		t.Fatalf("unable to parse the export source")
+	}
+	e := eval.NewEvaluator()
+	e.Resolver = catalog.Standard()
+	env := object.NewEnvironment()
+	if _, err := e.EvalProgram(context.Background(), tree, env); err != nil {
+		t.Fatal(err)
+	}
+	root, _ := env.Get("w")
+	return NewGraph([]*object.Node{root.(*object.Node)})
+}
+
+func Test_Exporters(t *testing.T) {
+	g := exportGraph(t)
+	for _, format := range Formats() {
+		x, err := Lookup(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buffer bytes.Buffer
+		if err := x.Export(&buffer, g); err != nil {
+			t.Errorf("%s: unexpected error: %s", format, err)
+			continue
+		}
+
+		golden := filepath.Join("testdata", format+".golden")
+		if *update {
+			if err := ioutil.WriteFile(golden, buffer.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if expected, err := ioutil.ReadFile(golden); err != nil {
+			t.Errorf("%s: %s", format, err)
+		} else if !bytes.Equal(buffer.Bytes(), expected) {
+			t.Errorf("%s: output doesn't match %s:\n%s", format, golden, buffer.String())
+		}
+	}
+}
+
+// Readers depend on the version, so it only changes along with the format.
+func Test_IRVersion(t *testing.T) {
+	if IRVersion != 1 {
+		t.Errorf("IR version changed to %d, update doc/stitch-ir.md and the golden file", IRVersion)
+	}
+	var buffer bytes.Buffer
+	if err := (&IRExporter{}).Export(&buffer, exportGraph(t)); err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(buffer.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	} else if doc["version"] != float64(IRVersion) {
+		t.Errorf("expected version %d, found %v", IRVersion, doc["version"])
+	}
+}
+
+func Test_GaufreSingleRoot(t *testing.T) {
+	g := exportGraph(t)
+	g.Roots = append(g.Roots, g.Nodes[1])
+	if err := (&GaufreExporter{}).Export(&bytes.Buffer{}, g); err == nil || err.Error() != "gaufre graphs require exactly one root node, found 2" {
+		t.Errorf("expected an error for two roots, found %v", err)
+	}
+}
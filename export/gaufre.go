@@ -0,0 +1,32 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/nirosys/stitch/eval"
+)
+
+// GaufreExporter renders the graph as the JSON graph that the Gaufre runtime
+// loads. Gaufre graphs have a single entry point, so only one root is allowed.
+type GaufreExporter struct{}
+
+func (x *GaufreExporter) Export(w io.Writer, g *Graph) error {
+	if len(g.Roots) != 1 {
+		return fmt.Errorf("gaufre graphs require exactly one root node, found %d", len(g.Roots))
+	}
+	compiled, err := eval.NewEvaluator().CompileObject(g.Roots[0].Object)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(compiled)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
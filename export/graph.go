@@ -0,0 +1,138 @@
+// Package export renders an evaluated stitch node graph into the various
+// formats consumed outside of stitch (graphviz, mermaid, yEd, etc.).
+package export
+
+import (
+	"sort"
+
+	"github.com/nirosys/stitch/object"
+)
+
+// Graph //////////////////////////////////////////////////////////////////////
+
+// Graph is a flattened view of every node reachable from a set of roots.
+// Node IDs are assigned in visit order, and connections are visited in slot
+// order, so the same program always produces the same output.
+type Graph struct {
+	Roots []*Node
+	Nodes []*Node
+	Edges []*Edge
+}
+
+type Node struct {
+	ID     int
+	Object *object.Node
+}
+
+type Edge struct {
+	From     *Node
+	FromSlot string
+	To       *Node
+	ToSlot   string
+}
+
+// Arg is a single node argument paired with the name it was declared with.
+type Arg struct {
+	Name  string
+	Value object.Object
+}
+
+func NewGraph(roots []*object.Node) *Graph {
+	g := &Graph{}
+	visited := map[*object.Node]*Node{}
+	for _, root := range roots {
+		g.Roots = append(g.Roots, g.visit(root, visited))
+	}
+	return g
+}
+
+func (g *Graph) visit(n *object.Node, visited map[*object.Node]*Node) *Node {
+	if node, done := visited[n]; done {
+		return node
+	}
+	node := &Node{ID: len(g.Nodes), Object: n}
+	visited[n] = node
+	g.Nodes = append(g.Nodes, node)
+
+	conns := n.GetConnections()
+	sort.SliceStable(conns, func(i, j int) bool {
+		return conns[i].Start.Name < conns[j].Start.Name
+	})
+	for _, conn := range conns {
+		end := g.visit(conn.End.Node, visited)
+		g.Edges = append(g.Edges, &Edge{
+			From:     node,
+			FromSlot: conn.Start.Name,
+			To:       end,
+			ToSlot:   conn.End.Name,
+		})
+	}
+	return node
+}
+
+// TypeName returns the name of the node's type, or an empty string for
+// untyped node literals.
+func (n *Node) TypeName() string {
+	if n.Object.NodeType == nil {
+		return ""
+	}
+	return n.Object.NodeType.Name
+}
+
+func (n *Node) Args() []Arg {
+	args := []Arg{}
+	if n.Object.NodeType == nil {
+		return args
+	}
+	for i, fp := range n.Object.NodeType.NodeArgs {
+		if i < len(n.Object.Arguments) {
+			args = append(args, Arg{Name: fp.Identifier.String(), Value: n.Object.Arguments[i]})
+		}
+	}
+	return args
+}
+
+// InputSlots returns the node's input slots in declaration order.
+func (n *Node) InputSlots() []string {
+	if n.Object.NodeType != nil {
+		return n.Object.NodeType.InputSlots
+	}
+	return sortedSlots(n.Object.InputSlots)
+}
+
+// OutputSlots returns the node's output slots in declaration order.
+func (n *Node) OutputSlots() []string {
+	if n.Object.NodeType != nil {
+		return n.Object.NodeType.OutputSlots
+	}
+	return sortedSlots(n.Object.OutputSlots)
+}
+
+func sortedSlots(slots map[string]struct{}) []string {
+	names := make([]string, 0, len(slots))
+	for n := range slots {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnvironmentRoots returns every node held by the environment, named nodes
+// first (sorted by name) followed by the unbound nodes in creation order.
+func EnvironmentRoots(env *object.Environment) []*object.Node {
+	roots := []*object.Node{}
+
+	names := env.GetNames()
+	sort.Strings(names)
+	unbound := env.GetUnboundNodes()
+	sort.Strings(unbound) // xids sort by creation time.
+
+	for _, ident := range append(names, unbound...) {
+		if obj, has := env.Get(ident); has {
+			if node, ok := obj.(*object.Node); ok {
+				roots = append(roots, node)
+			}
+		}
+	}
+	return roots
+}
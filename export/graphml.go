@@ -0,0 +1,81 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GraphMLExporter renders the graph as GraphML. Alongside the plain data keys
+// it emits yFiles graphics so yEd shows readable labels out of the box.
+type GraphMLExporter struct{}
+
+const graphMLHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns"
+    xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+    xmlns:y="http://www.yworks.com/xml/graphml"
+    xsi:schemaLocation="http://graphml.graphdrawing.org/xmlns http://www.yworks.com/xml/schema/graphml/1.1/ygraphml.xsd">
+  <key id="type" for="node" attr.name="type" attr.type="string"/>
+  <key id="args" for="node" attr.name="arguments" attr.type="string"/>
+  <key id="tag" for="node" attr.name="tag" attr.type="string"/>
+  <key id="field" for="node" attr.name="field" attr.type="string"/>
+  <key id="line" for="node" attr.name="line" attr.type="int"/>
+  <key id="column" for="node" attr.name="column" attr.type="int"/>
+  <key id="ngfx" for="node" yfiles.type="nodegraphics"/>
+  <key id="sslot" for="edge" attr.name="source_slot" attr.type="string"/>
+  <key id="tslot" for="edge" attr.name="target_slot" attr.type="string"/>
+  <key id="egfx" for="edge" yfiles.type="edgegraphics"/>
+  <graph id="G" edgedefault="directed">
+`
+
+func (x *GraphMLExporter) Export(w io.Writer, g *Graph) error {
+	out := bufio.NewWriter(w)
+
+	out.WriteString(graphMLHeader)
+	for _, n := range g.Nodes {
+		args := []string{}
+		for _, arg := range n.Args() {
+			args = append(args, arg.Name+"="+arg.Value.Inspect())
+		}
+		label := n.TypeName()
+		if len(args) > 0 {
+			label += "\n" + strings.Join(args, "\n")
+		}
+
+		fmt.Fprintf(out, "    <node id=\"n%d\">\n", n.ID)
+		writeGraphMLData(out, "type", n.TypeName())
+		writeGraphMLData(out, "args", strings.Join(args, ", "))
+		if n.Object.TagName != nil {
+			writeGraphMLData(out, "tag", *n.Object.TagName)
+		}
+		if n.Object.FieldName != nil {
+			writeGraphMLData(out, "field", *n.Object.FieldName)
+		}
+		writeGraphMLData(out, "line", fmt.Sprintf("%d", n.Object.Position.Line))
+		writeGraphMLData(out, "column", fmt.Sprintf("%d", n.Object.Position.Column))
+		fmt.Fprintf(out, "      <data key=\"ngfx\"><y:ShapeNode><y:Shape type=\"roundrectangle\"/><y:NodeLabel>%s</y:NodeLabel></y:ShapeNode></data>\n", xmlEscape(label))
+		out.WriteString("    </node>\n")
+	}
+	for i, e := range g.Edges {
+		fmt.Fprintf(out, "    <edge id=\"e%d\" source=\"n%d\" target=\"n%d\">\n", i, e.From.ID, e.To.ID)
+		writeGraphMLData(out, "sslot", e.FromSlot)
+		writeGraphMLData(out, "tslot", e.ToSlot)
+		fmt.Fprintf(out, "      <data key=\"egfx\"><y:PolyLineEdge><y:Arrows source=\"none\" target=\"standard\"/><y:EdgeLabel>%s</y:EdgeLabel></y:PolyLineEdge></data>\n", xmlEscape(e.FromSlot+" -> "+e.ToSlot))
+		out.WriteString("    </edge>\n")
+	}
+	out.WriteString("  </graph>\n</graphml>\n")
+
+	return out.Flush()
+}
+
+func writeGraphMLData(w io.Writer, key, value string) {
+	fmt.Fprintf(w, "      <data key=\"%s\">%s</data>\n", key, xmlEscape(value))
+}
+
+func xmlEscape(s string) string {
+	var buffer strings.Builder
+	xml.EscapeText(&buffer, []byte(s))
+	return buffer.String()
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/nirosys/stitch/object"
+)
+
+// IRVersion is bumped whenever the IR changes in a way that existing readers
+// would misinterpret. See doc/stitch-ir.md for the format.
+const IRVersion = 1
+
+type IRDocument struct {
+	Version int      `json:"version"`
+	Roots   []int    `json:"roots"`
+	Nodes   []IRNode `json:"nodes"`
+	Edges   []IREdge `json:"edges"`
+}
+
+type IRNode struct {
+	ID        int          `json:"id"`
+	Type      string       `json:"type"`
+	Arguments []IRArgument `json:"arguments"`
+	Inputs    []string     `json:"inputs"`
+	Outputs   []string     `json:"outputs"`
+	Tag       *string      `json:"tag,omitempty"`
+	Field     *string      `json:"field,omitempty"`
+	Position  IRPosition   `json:"position"`
+}
+
+type IRArgument struct {
+	Name  string      `json:"name"`
+	Kind  string      `json:"kind"`
+	Value interface{} `json:"value"`
+}
+
+type IRPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type IREdge struct {
+	From IRSlotRef `json:"from"`
+	To   IRSlotRef `json:"to"`
+}
+
+type IRSlotRef struct {
+	Node int    `json:"node"`
+	Slot string `json:"slot"`
+}
+
+// IRExporter renders the graph as the versioned stitch JSON IR.
+type IRExporter struct{}
+
+func (x *IRExporter) Export(w io.Writer, g *Graph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewIRDocument(g))
+}
+
+func NewIRDocument(g *Graph) *IRDocument {
+	doc := &IRDocument{
+		Version: IRVersion,
+		Roots:   []int{},
+		Nodes:   []IRNode{},
+		Edges:   []IREdge{},
+	}
+	for _, r := range g.Roots {
+		doc.Roots = append(doc.Roots, r.ID)
+	}
+	for _, n := range g.Nodes {
+		node := IRNode{
+			ID:        n.ID,
+			Type:      n.TypeName(),
+			Arguments: []IRArgument{},
+			Inputs:    n.InputSlots(),
+			Outputs:   n.OutputSlots(),
+			Tag:       n.Object.TagName,
+			Field:     n.Object.FieldName,
+			Position: IRPosition{
+				Line:   n.Object.Position.Line,
+				Column: n.Object.Position.Column,
+			},
+		}
+		for _, arg := range n.Args() {
+			node.Arguments = append(node.Arguments, IRArgument{
+				Name:  arg.Name,
+				Kind:  string(arg.Value.Type()),
+				Value: irValue(arg.Value),
+			})
+		}
+		doc.Nodes = append(doc.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		doc.Edges = append(doc.Edges, IREdge{
+			From: IRSlotRef{Node: e.From.ID, Slot: e.FromSlot},
+			To:   IRSlotRef{Node: e.To.ID, Slot: e.ToSlot},
+		})
+	}
+	return doc
+}
+
+// Primitives are written as JSON values, anything else is written using its
+// inspected form.
+func irValue(obj object.Object) interface{} {
+	switch t := obj.(type) {
+	case *object.Integer:
+		return t.Value
+	case *object.String:
+		return t.Value
+	case *object.BoolObject:
+		return bool(*t)
+	default:
+		return obj.Inspect()
+	}
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MermaidExporter renders the graph as a mermaid flowchart, suitable for
+// embedding in Markdown.
+type MermaidExporter struct{}
+
+func (m *MermaidExporter) Export(w io.Writer, g *Graph) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "flowchart LR\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(out, "    n%d[\"%s\"]\n", n.ID, mermaidLabel(n))
+	}
+	for _, e := range g.Edges {
+		if label := mermaidEdgeLabel(e); label != "" {
+			fmt.Fprintf(out, "    n%d -->|\"%s\"| n%d\n", e.From.ID, mermaidEscape(label), e.To.ID)
+		} else {
+			fmt.Fprintf(out, "    n%d --> n%d\n", e.From.ID, e.To.ID)
+		}
+	}
+
+	return out.Flush()
+}
+
+func mermaidLabel(n *Node) string {
+	lines := []string{mermaidEscape(n.TypeName())}
+	if n.Object.TagName != nil {
+		lines = append(lines, "@"+mermaidEscape(*n.Object.TagName))
+	} else if n.Object.FieldName != nil {
+		lines = append(lines, mermaidEscape(*n.Object.FieldName)+":")
+	}
+	for _, arg := range n.Args() {
+		lines = append(lines, mermaidEscape(arg.Name+"="+arg.Value.Inspect()))
+	}
+	return strings.Join(lines, "<br/>")
+}
+
+// Only connections that aren't the implicit Output -> Input get a label.
+func mermaidEdgeLabel(e *Edge) string {
+	switch {
+	case e.FromSlot == "Output" && e.ToSlot == "Input":
+		return ""
+	case e.ToSlot == "Input":
+		return e.FromSlot
+	default:
+		return e.FromSlot + " -> " + e.ToSlot
+	}
+}
+
+var mermaidReplacer = strings.NewReplacer(
+	`"`, "#quot;",
+	"<", "#lt;",
+	">", "#gt;",
+)
+
+func mermaidEscape(s string) string {
+	return mermaidReplacer.Replace(s)
+}
@@ -0,0 +1,593 @@
+// Package format prints stitch syntax trees as canonically formatted source.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/nirosys/stitch/ast"
+)
+
+const indentation = "    "
+
+// Lists that would print longer than this are broken up, one element per line.
+const maxListWidth = 80
+
+// Operator precedence, mirroring the parser. Used to decide where parentheses
+// are needed to keep the printed expression equivalent to the tree.
+const (
+	_ int = iota
+	precLowest
+	precOr
+	precDefault
+	precAnd
+	precEqual
+	precRange
+	precBitOr
+	precBitXor
+	precBitAnd
+	precShift
+	precSum
+	precProduct
+	precPrefix
+	precPower
+	precCall
+	precDereference
+)
+
+var precedences = map[string]int{
+	"or":  precOr,
+	"??":  precDefault,
+	"and": precAnd,
+	"==":  precEqual,
+	"!=":  precEqual,
+	"<":   precEqual,
+	"<=":  precEqual,
+	">":   precEqual,
+	">=":  precEqual,
+	"is":  precEqual,
+	"..":  precRange,
+	"..<": precRange,
+	"+":   precSum,
+	"-":   precSum,
+	"*":   precProduct,
+	"/":   precProduct,
+	"%":   precProduct,
+	"|":   precBitOr,
+	"^":   precBitXor,
+	"&":   precBitAnd,
+	"<<":  precShift,
+	">>":  precShift,
+	"**":  precPower,
+	".":   precDereference,
+}
+
+type printer struct {
+	buffer bytes.Buffer
+	indent int
+}
+
+// Fprint writes the formatted source for the tree to w.
+func Fprint(w io.Writer, tree *ast.ASTree) error {
+	p := &printer{}
+	p.statements(tree.Statements, true)
+	_, err := w.Write(p.buffer.Bytes())
+	return err
+}
+
+// Source returns the formatted source for the tree.
+func Source(tree *ast.ASTree) string {
+	p := &printer{}
+	p.statements(tree.Statements, true)
+	return p.buffer.String()
+}
+
+// Node returns the formatted source for a single node of the tree.
+func Node(n ast.Node) string {
+	p := &printer{}
+	p.node(n)
+	return p.buffer.String()
+}
+
+// Statements are grouped by kind; definitions always stand on their own, and
+// a blank line separates runs of statements of different kinds at top level.
+func (p *printer) statements(stmts []ast.Statement, topLevel bool) {
+	last := ""
+	for i, stmt := range stmts {
+		kind := statementKind(stmt)
+		if topLevel && i > 0 && (kind != last || kind == "definition") {
+			p.buffer.WriteByte('\n')
+		}
+		last = kind
+
+		p.writeIndent()
+		p.node(stmt)
+		p.buffer.WriteByte('\n')
+	}
+}
+
+func statementKind(stmt ast.Statement) string {
+	switch t := stmt.(type) {
+	case *ast.CommentStatement:
+		return "comment"
+	case *ast.ImportStatement:
+		return "import"
+	case *ast.LetStatement:
+		if _, ok := t.Value.(*ast.InternalExpression); ok {
+			return "internal"
+		}
+		return "let"
+	case *ast.NodeStatement, *ast.ModifierStatement:
+		return "definition"
+	case *ast.FunctionLiteral:
+		if t.Identifier != nil {
+			return "definition"
+		}
+	}
+	return "expression"
+}
+
+func (p *printer) writeIndent() {
+	p.buffer.WriteString(strings.Repeat(indentation, p.indent))
+}
+
+func (p *printer) node(n ast.Node) {
+	switch t := n.(type) {
+	case *ast.CommentStatement:
+		p.buffer.WriteByte('#')
+		p.buffer.WriteString(t.Text)
+	case *ast.ImportStatement:
+		p.buffer.WriteString("import ")
+		p.buffer.WriteString(quote(t.Path))
+	case *ast.LetStatement:
+		if t.Const {
+			p.buffer.WriteString("const ")
+		} else {
+			p.buffer.WriteString("let ")
+		}
+		p.buffer.WriteString(t.Name.Identifier)
+		p.buffer.WriteString(" = ")
+		p.expression(t.Value, precLowest)
+	case *ast.NodeStatement:
+		p.nodeStatement(t)
+	case *ast.ModifierStatement:
+		p.buffer.WriteString("mod ")
+		p.buffer.WriteString(t.Identifier.Identifier)
+		p.parameters("(", t.Parameters, ")")
+		p.buffer.WriteByte(' ')
+		p.block(t.Block)
+	case *ast.ExpressionStatement:
+		p.expression(t.Expression, precLowest)
+	case ast.Expression:
+		p.expression(t, precLowest)
+	default:
+		p.buffer.WriteString(n.String())
+	}
+}
+
+func (p *printer) nodeStatement(n *ast.NodeStatement) {
+	lit := n.Literal
+	p.buffer.WriteString("node")
+	p.parameters("[", lit.InputSlots, "]")
+	p.buffer.WriteByte(' ')
+	p.buffer.WriteString(n.Identifier.Identifier)
+	p.parameters("(", lit.Arguments, ")")
+	p.buffer.WriteString(" -> ")
+	p.parameters("[", lit.OutputSlots, "]")
+	p.buffer.WriteByte(' ')
+	p.block(lit.Block)
+}
+
+func (p *printer) parameters(open string, params []*ast.FunctionParameter, close string) {
+	p.buffer.WriteString(open)
+	for i, param := range params {
+		if i > 0 {
+			p.buffer.WriteString(", ")
+		}
+		p.buffer.WriteString(param.Identifier.Identifier)
+		if param.Type != nil {
+			p.buffer.WriteString(": ")
+			p.buffer.WriteString(param.Type.Identifier)
+		}
+	}
+	p.buffer.WriteString(close)
+}
+
+func (p *printer) block(b *ast.BlockExpression) {
+	if b == nil || len(b.Statements) == 0 {
+		p.buffer.WriteString("{}")
+		return
+	}
+	p.buffer.WriteString("{\n")
+	p.indent++
+	p.statements(b.Statements, false)
+	p.indent--
+	p.writeIndent()
+	p.buffer.WriteByte('}')
+}
+
+// Writes a match with one arm per line.
+func (p *printer) match(m *ast.MatchExpression) {
+	p.buffer.WriteString("match ")
+	p.expression(m.Subject, precLowest)
+	p.buffer.WriteString(" {\n")
+	p.indent++
+	for _, arm := range m.Arms {
+		p.writeIndent()
+		for i, pattern := range arm.Patterns {
+			if i > 0 {
+				p.buffer.WriteString(" | ")
+			}
+			p.expression(pattern, precBitOr+1)
+		}
+		p.buffer.WriteString(" => ")
+		p.expression(arm.Body, precLowest)
+		p.buffer.WriteString(",\n")
+	}
+	p.indent--
+	p.writeIndent()
+	p.buffer.WriteByte('}')
+}
+
+// Writes an expression, parenthesizing it when its own precedence is lower
+// than the precedence required by its parent.
+func (p *printer) expression(exp ast.Expression, prec int) {
+	if own := expressionPrecedence(exp); own < prec {
+		p.buffer.WriteByte('(')
+		p.expression(exp, precLowest)
+		p.buffer.WriteByte(')')
+		return
+	}
+
+	switch t := exp.(type) {
+	case *ast.Identifier:
+		p.buffer.WriteString(t.Identifier)
+	case *ast.StringLiteral:
+		p.buffer.WriteString(quote(t.Value))
+	case *ast.InterpolatedString:
+		p.interpolated(t)
+	case *ast.IntegerLiteral:
+		p.buffer.WriteString(fmt.Sprintf("%d", t.Value))
+	case *ast.BoolLiteral:
+		p.buffer.WriteString(t.String())
+	case *ast.TagName:
+		p.buffer.WriteByte('@')
+		p.buffer.WriteString(t.Identifier.Identifier)
+	case *ast.InternalExpression:
+		p.buffer.WriteString("internal ")
+		p.buffer.WriteString(quote(t.Name.Value))
+	case *ast.NotExpression:
+		p.buffer.WriteByte('!')
+		p.expression(t.Expression, precPrefix)
+	case *ast.PrefixExpression:
+		p.buffer.WriteString(t.Operator)
+		p.expression(t.Right, precPrefix)
+	case *ast.IndexExpression:
+		p.expression(t.Left, precCall)
+		p.buffer.WriteByte('[')
+		p.expression(t.Index, precLowest)
+		p.buffer.WriteByte(']')
+	case *ast.SliceExpression:
+		p.expression(t.Left, precCall)
+		p.buffer.WriteByte('[')
+		if t.Low != nil {
+			p.expression(t.Low, precLowest)
+		}
+		p.buffer.WriteByte(':')
+		if t.High != nil {
+			p.expression(t.High, precLowest)
+		}
+		p.buffer.WriteByte(']')
+	case *ast.IndexAssignment:
+		p.expression(t.Target, precLowest)
+		p.buffer.WriteString(" = ")
+		p.expression(t.Value, precOr)
+	case *ast.InfixExpression:
+		p.infix(t)
+	case *ast.ArrowExpression:
+		// Arrows lean right, so only the left side needs to bind tighter.
+		p.expression(t.Left, precOr+1)
+		p.buffer.WriteString(" -> ")
+		p.expression(t.Right, precOr)
+	case *ast.AssignmentExpression:
+		p.buffer.WriteString(t.Identifier.Identifier)
+		p.buffer.WriteString(" = ")
+		p.expression(t.Value, precOr)
+	case *ast.NamedNodeExpression:
+		if t.TagName != nil {
+			p.buffer.WriteByte('@')
+			p.buffer.WriteString(t.TagName.Identifier)
+		} else if t.FieldName != nil {
+			p.buffer.WriteString(t.FieldName.Identifier)
+		}
+		p.buffer.WriteByte(':')
+		p.expression(t.Expression, precLowest)
+	case *ast.CallExpression:
+		p.expression(t.Function, precCall)
+		p.buffer.WriteByte('(')
+		for i, arg := range t.Arguments {
+			if i > 0 {
+				p.buffer.WriteString(", ")
+			}
+			p.expression(arg, precLowest)
+		}
+		p.buffer.WriteByte(')')
+	case *ast.ListLiteral:
+		p.list(t)
+	case *ast.MapLiteral:
+		p.mapLiteral(t)
+	case *ast.HashLiteral:
+		p.hash(t)
+	case *ast.BlockExpression:
+		p.block(t)
+	case *ast.ConditionalExpression:
+		p.buffer.WriteString("if ")
+		p.expression(t.Condition, precLowest)
+		p.buffer.WriteByte(' ')
+		p.block(t.Block)
+		if t.Else != nil {
+			p.buffer.WriteString(" else ")
+			p.expression(t.Else, precLowest)
+		}
+	case *ast.FunctionLiteral:
+		p.function(t)
+	case *ast.NodeLiteral:
+		p.buffer.WriteString("node ")
+		p.block(t.Block)
+	case *ast.MatchExpression:
+		p.match(t)
+	case *ast.TryExpression:
+		p.buffer.WriteString("try ")
+		p.block(t.Block)
+		p.buffer.WriteString(" catch ")
+		if t.ErrVar != nil {
+			p.buffer.WriteString(t.ErrVar.Identifier)
+			p.buffer.WriteByte(' ')
+		}
+		p.block(t.Catch)
+	case *ast.ForeachStatement:
+		p.buffer.WriteString("foreach ")
+		if t.KeyVar != nil {
+			p.buffer.WriteString(t.KeyVar.Identifier)
+			p.buffer.WriteString(", ")
+		}
+		p.buffer.WriteString(t.LoopVar.Identifier)
+		p.buffer.WriteString(" in ")
+		p.expression(t.List, precLowest)
+		p.buffer.WriteByte(' ')
+		p.block(t.Block)
+	default:
+		p.buffer.WriteString(exp.String())
+	}
+}
+
+func (p *printer) infix(in *ast.InfixExpression) {
+	prec := expressionPrecedence(in)
+	if in.Operator == "**" {
+		// Exponentiation leans right, like arrows.
+		p.expression(in.Left, prec+1)
+		p.buffer.WriteString(" ** ")
+		p.expression(in.Right, prec)
+		return
+	}
+	p.expression(in.Left, prec)
+	switch in.Operator {
+	case ".", "..", "..<":
+		p.buffer.WriteString(in.Operator)
+	default:
+		p.buffer.WriteByte(' ')
+		p.buffer.WriteString(in.Operator)
+		p.buffer.WriteByte(' ')
+	}
+	// Infix operators are left associative.
+	p.expression(in.Right, prec+1)
+}
+
+func (p *printer) function(fn *ast.FunctionLiteral) {
+	p.buffer.WriteString("fn")
+	if fn.Identifier != nil {
+		p.buffer.WriteByte(' ')
+		p.buffer.WriteString(fn.Identifier.Identifier)
+		p.parameters("(", fn.Parameters, ")")
+		p.buffer.WriteByte(' ')
+		p.block(fn.Body)
+		return
+	}
+	// Lambdas have a single expression as their body.
+	p.parameters("(", fn.Parameters, ")")
+	p.buffer.WriteString(": ")
+	if fn.Body != nil && len(fn.Body.Statements) == 1 {
+		p.node(fn.Body.Statements[0])
+	} else {
+		p.block(fn.Body)
+	}
+}
+
+func (p *printer) list(l *ast.ListLiteral) {
+	items := make([]string, 0, len(l.Contents))
+	width := 0
+	multiline := false
+	for _, exp := range l.Contents {
+		item := &printer{indent: p.indent + 1}
+		item.expression(exp, precLowest)
+		s := item.buffer.String()
+		width += len(s) + 2
+		multiline = multiline || strings.Contains(s, "\n")
+		items = append(items, s)
+	}
+
+	if !multiline && width+p.indent*len(indentation) <= maxListWidth {
+		p.buffer.WriteByte('[')
+		p.buffer.WriteString(strings.Join(items, ", "))
+		p.buffer.WriteByte(']')
+		return
+	}
+
+	p.buffer.WriteString("[\n")
+	p.indent++
+	for i, item := range items {
+		p.writeIndent()
+		p.buffer.WriteString(item)
+		if i < len(items)-1 {
+			p.buffer.WriteByte(',')
+		}
+		p.buffer.WriteByte('\n')
+	}
+	p.indent--
+	p.writeIndent()
+	p.buffer.WriteByte(']')
+}
+
+func (p *printer) mapLiteral(m *ast.MapLiteral) {
+	if len(m.Assignments) == 0 {
+		p.buffer.WriteString("{}")
+		return
+	}
+	p.buffer.WriteString("{\n")
+	p.indent++
+	for _, assign := range m.Assignments {
+		p.writeIndent()
+		p.expression(assign, precLowest)
+		p.buffer.WriteByte('\n')
+	}
+	p.indent--
+	p.writeIndent()
+	p.buffer.WriteByte('}')
+}
+
+// Hashes print on one line when they're short, like lists.
+func (p *printer) hash(h *ast.HashLiteral) {
+	if len(h.Keys) == 0 {
+		p.buffer.WriteString("#{}")
+		return
+	}
+	items := make([]string, 0, len(h.Keys))
+	width := 0
+	multiline := false
+	for i := range h.Keys {
+		item := &printer{indent: p.indent + 1}
+		item.expression(h.Keys[i], precLowest)
+		item.buffer.WriteString(": ")
+		item.expression(h.Values[i], precLowest)
+		s := item.buffer.String()
+		width += len(s) + 2
+		multiline = multiline || strings.Contains(s, "\n")
+		items = append(items, s)
+	}
+
+	if !multiline && width+p.indent*len(indentation) <= maxListWidth {
+		p.buffer.WriteString("#{")
+		p.buffer.WriteString(strings.Join(items, ", "))
+		p.buffer.WriteByte('}')
+		return
+	}
+
+	p.buffer.WriteString("#{\n")
+	p.indent++
+	for _, item := range items {
+		p.writeIndent()
+		p.buffer.WriteString(item)
+		p.buffer.WriteString(",\n")
+	}
+	p.indent--
+	p.writeIndent()
+	p.buffer.WriteByte('}')
+}
+
+func expressionPrecedence(exp ast.Expression) int {
+	switch t := exp.(type) {
+	case *ast.InfixExpression:
+		if prec, ok := precedences[t.Operator]; ok {
+			return prec
+		}
+		return precLowest
+	case *ast.ArrowExpression, *ast.AssignmentExpression, *ast.IndexAssignment:
+		return precOr
+	case *ast.NamedNodeExpression:
+		// The named expression extends as far right as it can.
+		return precLowest
+	case *ast.NotExpression, *ast.PrefixExpression:
+		return precPrefix
+	case *ast.FunctionLiteral:
+		if t.Identifier == nil {
+			return precLowest
+		}
+	case *ast.ConditionalExpression, *ast.ForeachStatement, *ast.MatchExpression, *ast.TryExpression:
+		return precLowest
+	}
+	return precDereference + 1
+}
+
+// Interpolated strings are triple-quoted when their text spans lines, like
+// other strings, but never raw.
+func (p *printer) interpolated(s *ast.InterpolatedString) {
+	multiline := false
+	for _, part := range s.Parts {
+		if text, ok := part.(*ast.StringLiteral); ok && strings.Contains(text.Value, "\n") {
+			multiline = true
+		}
+	}
+	if multiline {
+		p.buffer.WriteString(`"""` + "\n")
+	} else {
+		p.buffer.WriteByte('"')
+	}
+	for _, part := range s.Parts {
+		if text, ok := part.(*ast.StringLiteral); ok {
+			p.buffer.WriteString(escape(text.Value, multiline))
+		} else {
+			p.buffer.WriteString("${")
+			p.expression(part, precLowest)
+			p.buffer.WriteByte('}')
+		}
+	}
+	if multiline {
+		p.buffer.WriteString(`"""`)
+	} else {
+		p.buffer.WriteByte('"')
+	}
+}
+
+// Strings print with as few escapes as they can: raw when they hold
+// backslashes, like regexes, and triple-quoted when they span lines.
+func quote(s string) string {
+	printable := strings.IndexFunc(s, func(r rune) bool {
+		return r != '\n' && r != '\t' && !unicode.IsPrint(r)
+	}) < 0
+	switch {
+	case printable && strings.Contains(s, `\`) && !strings.Contains(s, "`") && !strings.Contains(s, "${"):
+		return "`" + s + "`"
+	case strings.Contains(s, "\n"):
+		return `"""` + "\n" + escape(s, true) + `"""`
+	}
+	return `"` + escape(s, false) + `"`
+}
+
+func escape(s string, newlines bool) string {
+	var buffer strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\' || r == '"' || (r == '$' && strings.HasPrefix(s[i+1:], "{")):
+			buffer.WriteByte('\\')
+			buffer.WriteRune(r)
+		case r == '\n' && newlines:
+			buffer.WriteRune(r)
+		case r == '\n':
+			buffer.WriteString(`\n`)
+		case r == '\t':
+			buffer.WriteString(`\t`)
+		case r == '\r':
+			buffer.WriteString(`\r`)
+		case !unicode.IsPrint(r) && r > 0xffff:
+			buffer.WriteString(fmt.Sprintf(`\U%08x`, r))
+		case !unicode.IsPrint(r):
+			buffer.WriteString(fmt.Sprintf(`\u%04x`, r))
+		default:
+			buffer.WriteRune(r)
+		}
+	}
+	return buffer.String()
+}
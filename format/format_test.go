@@ -0,0 +1,75 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/parsing"
+)
+
+func parse(t *testing.T, src string) *ast.ASTree {
+	parser := parsing.NewParser(strings.NewReader(src))
+	tree := parser.Parse()
+	if tree == nil {
+		t.Fatalf("unable to parse %q: %v", src, parser.Errors())
+	}
+	return tree
+}
+
+// The tree's statements as the parser saw them, to check the formatted source
+// means the same thing.
+func treeString(tree *ast.ASTree) string {
+	parts := []string{}
+	for _, stmt := range tree.Statements {
+		parts = append(parts, stmt.String())
+	}
+	return strings.Join(parts, "\n")
+}
+
+func Test_RoundTrip(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"let x=1+2*3", "let x = 1 + 2 * 3\n"},
+		{"let y = (1 + 2) * 3", "let y = (1 + 2) * 3\n"},
+		{"const port = 161", "const port = 161\n"},
+		{"fn f(a, b) { a + b }", "fn f(a, b) {\n    a + b\n}\n"},
+		{"let f = fn(x): x * 2", "let f = fn(x): x * 2\n"},
+		{"let m = {a = 1; b = \"x\"}", "let m = {\n    a = 1\n    b = \"x\"\n}\n"},
+		{`let h = #{"a": 1, 2: "two"}`, "let h = #{\"a\": 1, 2: \"two\"}\n"},
+		{"foreach i, v in [1, 2] { if v > 1 { break } else { continue } }",
+			"foreach i, v in [1, 2] {\n    if v > 1 {\n        break\n    } else {\n        continue\n    }\n}\n"},
+		{`let r = match x { 1 | 2 => "low", _ => "high" }`, "let r = match x {\n    1 | 2 => \"low\",\n    _ => \"high\",\n}\n"},
+		{"let t = try { f() } catch e { e.message }", "let t = try {\n    f()\n} catch e {\n    e.message\n}\n"},
+		{`let s = "ifInOctets.${n + 1}"`, "let s = \"ifInOctets.${n + 1}\"\n"},
+		{`let s = "{{ .Input.Key.Token -${ n } }}"`, "let s = \"{{ .Input.Key.Token -${n} }}\"\n"},
+		{"let s = `raw\\d`", "let s = `raw\\d`\n"},
+		{`let s = "tab\there"`, "let s = \"tab\\there\"\n"},
+		{"let d = x ?? 1", "let d = x ?? 1\n"},
+		{"x is none", "x is none\n"},
+		{"let a = 1..4", "let a = 1..4\n"},
+		{"let b = 0..<n", "let b = 0..<n\n"},
+		{"let c = -2 ** 2", "let c = -2 ** 2\n"},
+		{"let e = (1 | 2) ^ 3 & 4 << 1", "let e = (1 | 2) ^ 3 & 4 << 1\n"},
+		{"l[1:3]\nl[-1]", "l[1:3]\nl[-1]\n"},
+		{"w.Error -> get(\"a\")", "w.Error -> get(\"a\")\n"},
+		{"# comment\nlet x = 1", "# comment\n\nlet x = 1\n"},
+	}
+	for i, test := range tests {
+		tree := parse(t, test.src)
+		formatted := Source(tree)
+		if formatted != test.expected {
+			t.Errorf("[%d] expected %q, found %q", i, test.expected, formatted)
+			continue
+		}
+		reparsed := parse(t, formatted)
+		if again := Source(reparsed); again != formatted {
+			t.Errorf("[%d] formatting isn't stable, %q became %q", i, formatted, again)
+		}
+		if treeString(reparsed) != treeString(tree) {
+			t.Errorf("[%d] formatted source parses as %q, expected %q", i, treeString(reparsed), treeString(tree))
+		}
+	}
+}
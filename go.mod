@@ -1,6 +1,6 @@
 module github.com/nirosys/stitch
 
-go 1.13
+go 1.16
 
 require (
 	github.com/emicklei/dot v0.10.1
@@ -9,6 +9,7 @@ require (
 	github.com/peterh/liner v0.0.0-00010101000000-000000000000
 	github.com/rs/xid v1.2.1
 	github.com/spf13/cobra v0.0.7
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 replace github.com/peterh/liner => ./cmd/stitch/subcmd/internal/liner
@@ -0,0 +1,194 @@
+// Package host exposes Go functions to stitch programs as internals.
+//
+//	lib := host.NewLibrary()
+//	lib.RegisterFunc("std:strlen", func(s string) int { return len(s) })
+//
+// makes `internal "std:strlen"` a function taking a string, and returning an
+// integer. Arguments and results are converted with object.ToValue and
+// object.FromValue.
+package host
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/eval"
+	"github.com/nirosys/stitch/object"
+)
+
+var (
+	errorInterface  = reflect.TypeOf((*error)(nil)).Elem()
+	environmentType = reflect.TypeOf((*object.Environment)(nil))
+)
+
+// Library ////////////////////////////////////////////////////////////////////
+
+type Library struct {
+	funcs map[string]*object.NativeFunction
+}
+
+func NewLibrary() *Library {
+	return &Library{funcs: map[string]*object.NativeFunction{}}
+}
+
+// RegisterFunc makes a Go function available as the named internal.
+//
+// The function can take any arguments FromValue/ToValue convert, and can be
+// variadic. A leading *object.Environment argument receives the caller's
+// environment, rather than a stitch argument. It can return nothing, a value,
+// an error, or a value and an error. Parameters are named after params, or
+// numbered when there are too few names.
+func (l *Library) RegisterFunc(name string, fn interface{}, params ...string) error {
+	if _, have := l.funcs[name]; have {
+		return fmt.Errorf("function '%s' already registered", name)
+	}
+	native, err := NewNativeFunction(name, fn, params...)
+	if err != nil {
+		return err
+	}
+	l.funcs[name] = native
+	return nil
+}
+
+// MustRegisterFunc is RegisterFunc for functions that are known to be valid,
+// panicking otherwise.
+func (l *Library) MustRegisterFunc(name string, fn interface{}, params ...string) {
+	if err := l.RegisterFunc(name, fn, params...); err != nil {
+		panic(err)
+	}
+}
+
+func (l *Library) Lookup(name string) (*object.NativeFunction, bool) {
+	fn, ok := l.funcs[name]
+	return fn, ok
+}
+
+// Resolve returns the function for an internal, which makes a library usable
+// as an evaluator's resolver.
+func (l *Library) Resolve(name string) (object.Object, error) {
+	if fn, ok := l.funcs[name]; ok {
+		return &object.InternalFunction{Fn: fn, Env: nil}, nil
+	}
+	return nil, eval.UnknownInternal(name)
+}
+
+// Names returns the name of every registered function, sorted.
+func (l *Library) Names() []string {
+	names := make([]string, 0, len(l.funcs))
+	for n := range l.funcs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NativeFunction /////////////////////////////////////////////////////////////
+
+// NewNativeFunction wraps a Go function, deriving its parameters with
+// reflection. See RegisterFunc.
+func NewNativeFunction(name string, fn interface{}, params ...string) (*object.NativeFunction, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("%s: expected a function, found %T", name, fn)
+	}
+	t := v.Type()
+
+	takesEnv := t.NumIn() > 0 && t.In(0) == environmentType
+	first := 0
+	if takesEnv {
+		first = 1
+	}
+
+	switch {
+	case t.NumOut() > 2:
+		return nil, fmt.Errorf("%s: functions can return at most a value and an error", name)
+	case t.NumOut() == 2 && t.Out(1) != errorInterface:
+		return nil, fmt.Errorf("%s: second result must be an error", name)
+	}
+
+	native := &object.NativeFunction{
+		Params:   []*ast.FunctionParameter{},
+		Variadic: t.IsVariadic(),
+	}
+	for i := first; i < t.NumIn(); i++ {
+		paramName := fmt.Sprintf("arg%d", i-first+1)
+		if i-first < len(params) {
+			paramName = params[i-first]
+		}
+		paramType := t.In(i)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			paramType = paramType.Elem()
+		}
+		native.Params = append(native.Params, &ast.FunctionParameter{
+			Identifier: &ast.Identifier{Identifier: paramName},
+			Type:       &ast.Identifier{Identifier: object.TypeName(paramType)},
+		})
+	}
+
+	native.Fn = func(env *object.Environment, args []object.Object) (object.Object, error) {
+		in, err := arguments(name, t, first, args)
+		if err != nil {
+			return nil, err
+		}
+		if takesEnv {
+			in = append([]reflect.Value{reflect.ValueOf(env)}, in...)
+		}
+		return results(name, v.Call(in))
+	}
+	return native, nil
+}
+
+// Converts the stitch arguments to the function's parameter types.
+func arguments(name string, t reflect.Type, first int, args []object.Object) ([]reflect.Value, error) {
+	fixed := t.NumIn() - first
+	if t.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return nil, fmt.Errorf("%s: expected at least %d arguments but found %d", name, fixed, len(args))
+		}
+	} else if len(args) != fixed {
+		return nil, fmt.Errorf("%s: expected %d arguments but found %d", name, fixed, len(args))
+	}
+
+	in := make([]reflect.Value, 0, len(args))
+	for i, arg := range args {
+		var paramType reflect.Type
+		if i < fixed {
+			paramType = t.In(first + i)
+		} else {
+			paramType = t.In(t.NumIn() - 1).Elem()
+		}
+		v, err := object.ToValue(arg, paramType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %d: %w", name, i+1, err)
+		}
+		in = append(in, v)
+	}
+	return in, nil
+}
+
+// Converts the function's results to an object, and an error.
+func results(name string, out []reflect.Value) (object.Object, error) {
+	if len(out) > 0 && out[len(out)-1].Type() == errorInterface {
+		if err := out[len(out)-1]; !err.IsNil() {
+			return nil, fmt.Errorf("%s: %w", name, err.Interface().(error))
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return object.None, nil
+	}
+
+	// Nil pointers are none, unlike nil slices and maps which are empty.
+	result := out[0]
+	if (result.Kind() == reflect.Ptr || result.Kind() == reflect.Interface) && result.IsNil() {
+		return object.None, nil
+	}
+	obj, err := object.FromValue(result)
+	if err != nil {
+		return nil, fmt.Errorf("%s: result: %w", name, err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,116 @@
+package host
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nirosys/stitch/object"
+)
+
+type agent struct {
+	Address string
+	Port    int
+}
+
+func Test_RegisterFunc(t *testing.T) {
+	lib := NewLibrary()
+	lib.MustRegisterFunc("strlen", func(s string) int { return len(s) }, "s")
+	lib.MustRegisterFunc("sum", func(base int64, xs ...int64) int64 {
+		for _, x := range xs {
+			base += x
+		}
+		return base
+	})
+	lib.MustRegisterFunc("fail", func(fail bool) (string, error) {
+		if fail {
+			return "", errors.New("failed")
+		}
+		return "ok", nil
+	})
+	lib.MustRegisterFunc("keys", func(m map[string]interface{}) []string {
+		keys := []string{}
+		for k := range m {
+			keys = append(keys, k)
+		}
+		return keys
+	})
+	lib.MustRegisterFunc("agent", func(address string, port int) agent { return agent{address, port} })
+	lib.MustRegisterFunc("env", func(env *object.Environment, s string) bool { return env != nil })
+	lib.MustRegisterFunc("nothing", func(a *agent) *agent { return a }, "a")
+
+	str := func(s string) object.Object { return &object.String{Value: s} }
+	num := func(i int64) object.Object { return &object.Integer{Value: i} }
+
+	tests := []struct {
+		name     string
+		args     []object.Object
+		expected string
+		err      string
+	}{
+		{"strlen", []object.Object{str("hello")}, "5", ""},
+		{"strlen", []object.Object{num(5)}, "", "strlen: argument 1: type mismatch: expected STRING, received INTEGER"},
+		{"strlen", []object.Object{}, "", "strlen: expected 1 arguments but found 0"},
+		{"sum", []object.Object{num(1)}, "1", ""},
+		{"sum", []object.Object{num(1), num(2), num(3)}, "6", ""},
+		{"sum", []object.Object{num(1), str("2")}, "", "sum: argument 2: type mismatch: expected INTEGER, received STRING"},
+		{"fail", []object.Object{object.NewBoolObject(false)}, `"ok"`, ""},
+		{"fail", []object.Object{object.NewBoolObject(true)}, "", "fail: failed"},
+		{"keys", []object.Object{&object.MapObject{Fields: map[string]object.Object{"a": num(1)}}}, `["a"]`, ""},
+		{"agent", []object.Object{str("10.0.0.1"), num(161)}, "", ""},
+		{"env", []object.Object{str("x")}, "true", ""},
+		{"nothing", []object.Object{object.None}, "none", ""},
+	}
+
+	env := object.NewEnvironment()
+	for i, test := range tests {
+		fn, ok := lib.Lookup(test.name)
+		if !ok {
+			t.Fatalf("[%d] '%s' not registered", i, test.name)
+		}
+		obj, err := fn.Fn(env, test.args)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("[%d] expected error %q, found %v", i, test.err, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+			continue
+		}
+		if test.expected != "" && obj.Inspect() != test.expected {
+			t.Errorf("[%d] expected %s, found %s", i, test.expected, obj.Inspect())
+		}
+	}
+
+	fn, _ := lib.Lookup("agent")
+	obj, _ := fn.Fn(env, []object.Object{str("10.0.0.1"), num(161)})
+	if m, ok := obj.(*object.MapObject); !ok {
+		t.Errorf("expected struct to become a map, found %T", obj)
+	} else if m.Fields["Address"].Inspect() != `"10.0.0.1"` || m.Fields["Port"].Inspect() != "161" {
+		t.Errorf("unexpected struct conversion: %s", m.Inspect())
+	}
+
+	if sum, _ := lib.Lookup("sum"); !sum.Variadic || len(sum.Params) != 2 || sum.Params[1].String() != "arg2:INTEGER" {
+		t.Errorf("unexpected parameters for variadic function: %v", sum.Params)
+	}
+}
+
+func Test_RegisterFuncInvalid(t *testing.T) {
+	lib := NewLibrary()
+	invalid := []interface{}{
+		"not a function",
+		func() (int, int) { return 0, 0 },
+		func() (int, error, bool) { return 0, nil, false },
+	}
+	for i, fn := range invalid {
+		if err := lib.RegisterFunc("fn", fn); err == nil {
+			t.Errorf("[%d] expected error registering %T", i, fn)
+		}
+	}
+
+	lib.MustRegisterFunc("fn", func() {})
+	if err := lib.RegisterFunc("fn", func() {}); err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("expected duplicate registration to fail, found %v", err)
+	}
+}
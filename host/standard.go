@@ -0,0 +1,16 @@
+package host
+
+import (
+	"github.com/nirosys/stitch/object"
+)
+
+// Standard returns a library of the functions every host provides, which make
+// the values with types of their own: ranges, OIDs, IP addresses and CIDRs.
+func Standard() *Library {
+	lib := NewLibrary()
+	lib.MustRegisterFunc("std:range", object.NewRange, "start", "end", "step")
+	lib.MustRegisterFunc("std:oid", object.NewOID, "oid")
+	lib.MustRegisterFunc("std:ip", object.NewIP, "ip")
+	lib.MustRegisterFunc("std:cidr", object.NewCIDR, "cidr")
+	return lib
+}
@@ -0,0 +1,263 @@
+// Package importing reconstructs stitch source from graphs that were built
+// outside of stitch: Gaufre JSON graphs, and the legacy YAML configurations.
+package importing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nirosys/gaufre/graph"
+	"gopkg.in/yaml.v2"
+)
+
+var ErrUnknownFormat = errors.New("unknown graph format")
+
+// Graph is the format-neutral description of an imported graph.
+type Graph struct {
+	Name  string
+	Nodes []*Node
+}
+
+type Node struct {
+	Name        string
+	Type        string
+	Args        map[string]interface{}
+	Field       string // Field name for the data produced.
+	Tag         string // Metadata name for the data produced.
+	Connections []*Connection
+}
+
+// Connection links one of the node's output slots to another node's input
+// slot.
+type Connection struct {
+	FromSlot string
+	To       *Node
+	ToSlot   string
+}
+
+func (g *Graph) nodeByName(name string) *Node {
+	for _, n := range g.Nodes {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// Load reads a graph, choosing the decoder based on the file extension. Files
+// without a known extension are sniffed: JSON objects start with a '{'.
+func Load(r io.Reader, filename string) (*Graph, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return LoadGaufre(bytes.NewReader(data))
+	case ".yaml", ".yml":
+		return LoadYAML(bytes.NewReader(data))
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return LoadGaufre(bytes.NewReader(data))
+	}
+	return LoadYAML(bytes.NewReader(data))
+}
+
+// LoadGaufre reads a Gaufre JSON graph. Both the bare graph written by
+// `stitch compile`, and the `{"graph": ...}` document Gaufre loads are
+// accepted.
+func LoadGaufre(r io.Reader) (*Graph, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := struct {
+		Graph *graph.Graph `json:"graph"`
+	}{}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, err
+	}
+	g := wrapped.Graph
+	if g == nil {
+		g = &graph.Graph{}
+		if err := json.Unmarshal(data, g); err != nil {
+			return nil, err
+		}
+	}
+	if len(g.Nodes) == 0 {
+		return nil, fmt.Errorf("%w: no nodes found", ErrUnknownFormat)
+	}
+
+	imported := &Graph{Name: g.Name}
+	byID := map[uint]*Node{}
+	for i := range g.Nodes {
+		gn := &g.Nodes[i]
+		node := &Node{
+			Name:  gn.Name,
+			Type:  gn.Type,
+			Args:  gn.Configuration.GetStringMap("args"),
+			Field: gn.Configuration.GetString("tag"), // `stitch compile` writes field names as tags.
+		}
+		if node.Args == nil {
+			node.Args = map[string]interface{}{}
+		}
+		byID[gn.ID] = node
+		imported.Nodes = append(imported.Nodes, node)
+	}
+
+	for _, c := range g.Connections {
+		startID, err := c.Start.NodeId()
+		if err != nil {
+			return nil, err
+		}
+		endID, err := c.End.NodeId()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := byID[startID]
+		if !ok {
+			return nil, fmt.Errorf("connection from unknown node %d", startID)
+		}
+		end, ok := byID[endID]
+		if !ok {
+			return nil, fmt.Errorf("connection to unknown node %d", endID)
+		}
+
+		startNode, _ := g.NodeById(startID)
+		endNode, _ := g.NodeById(endID)
+		fromSlot, err := outputName(startNode, c.Start)
+		if err != nil {
+			return nil, err
+		}
+		toSlot := endNode.Inputs.Name
+		if toSlot == "" {
+			toSlot = "Input"
+		}
+		start.Connections = append(start.Connections, &Connection{
+			FromSlot: fromSlot,
+			To:       end,
+			ToSlot:   toSlot,
+		})
+	}
+
+	return imported, nil
+}
+
+// Gaufre references look like "$ref:/node/<id>/output/<socket>".
+func outputName(n *graph.Node, ref graph.GraphRef) (string, error) {
+	toks := strings.Split(string(ref), "/")
+	if len(toks) != 5 {
+		return "", fmt.Errorf("invalid output reference '%s'", ref)
+	}
+	for _, o := range n.Outputs {
+		if fmt.Sprintf("%d", o.ID) == toks[4] {
+			return o.Name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output in reference '%s'", ref)
+}
+
+// Legacy YAML configurations describe each node by name, with connections
+// listed per output slot:
+//
+//	name: interfaces
+//	nodes:
+//	  - name: types
+//	    type: snmp:walk
+//	    args: {oid: ifType}
+//	    connections:
+//	      Output: [inOctets, outOctets.Input]
+//	  - name: inOctets
+//	    type: snmp:get
+//	    field: in
+//	    args: {oid: "ifInOctets.{{ .Input.Key }}"}
+type yamlGraph struct {
+	Name  string     `yaml:"name"`
+	Nodes []yamlNode `yaml:"nodes"`
+}
+
+type yamlNode struct {
+	Name        string                 `yaml:"name"`
+	Type        string                 `yaml:"type"`
+	Args        map[string]interface{} `yaml:"args"`
+	Field       string                 `yaml:"field"`
+	Metadata    string                 `yaml:"metadata"`
+	Connections map[string][]string    `yaml:"connections"`
+}
+
+// LoadYAML reads a legacy YAML configuration.
+func LoadYAML(r io.Reader) (*Graph, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	config := &yamlGraph{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+	if len(config.Nodes) == 0 {
+		return nil, fmt.Errorf("%w: no nodes found", ErrUnknownFormat)
+	}
+
+	imported := &Graph{Name: config.Name}
+	for i, yn := range config.Nodes {
+		if yn.Name == "" {
+			return nil, fmt.Errorf("node %d has no name", i)
+		} else if yn.Type == "" {
+			return nil, fmt.Errorf("node '%s' has no type", yn.Name)
+		} else if imported.nodeByName(yn.Name) != nil {
+			return nil, fmt.Errorf("duplicate node name '%s'", yn.Name)
+		}
+		node := &Node{
+			Name:  yn.Name,
+			Type:  yn.Type,
+			Args:  yn.Args,
+			Field: yn.Field,
+			Tag:   yn.Metadata,
+		}
+		if node.Args == nil {
+			node.Args = map[string]interface{}{}
+		}
+		imported.Nodes = append(imported.Nodes, node)
+	}
+
+	for i, yn := range config.Nodes {
+		node := imported.Nodes[i]
+		for _, slot := range sortedKeys(yn.Connections) {
+			for _, target := range yn.Connections[slot] {
+				name, toSlot := target, "Input"
+				if idx := strings.LastIndex(target, "."); idx >= 0 {
+					name, toSlot = target[:idx], target[idx+1:]
+				}
+				to := imported.nodeByName(name)
+				if to == nil {
+					return nil, fmt.Errorf("node '%s' connects to unknown node '%s'", yn.Name, name)
+				}
+				node.Connections = append(node.Connections, &Connection{
+					FromSlot: slot,
+					To:       to,
+					ToSlot:   toSlot,
+				})
+			}
+		}
+	}
+
+	return imported, nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
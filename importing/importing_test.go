@@ -0,0 +1,168 @@
+package importing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nirosys/stitch/parsing"
+)
+
+func Test_ImportGaufre(t *testing.T) {
+	src := `{"name":"test","nodes":[
+	{"id":0,"name":"","type":"snmp:walk","config":{"args":{"oid":"ifType"}},"input":{"id":0,"name":"Input"},"outputs":[{"id":0,"name":"Output"},{"id":1,"name":"Error"}]},
+	{"id":1,"name":"","type":"snmp:get","config":{"args":{"oid":"ifInOctets"},"tag":"inoct"},"input":{"id":0,"name":"Input"},"outputs":[{"id":0,"name":"Output"}]},
+	{"id":2,"name":"","type":"snmp:get","config":{"args":{"oid":"ifOutOctets"}},"input":{"id":0,"name":"Input"},"outputs":[{"id":0,"name":"Output"}]},
+	{"id":3,"name":"","type":"std:passthru","config":{"args":{}},"input":{"id":0,"name":"Input"},"outputs":[{"id":0,"name":"Output"}]}],
+	"connections":[
+	{"start":"$ref:/node/0/output/0","end":"$ref:/node/1/input/0"},
+	{"start":"$ref:/node/0/output/0","end":"$ref:/node/2/input/0"},
+	{"start":"$ref:/node/0/output/1","end":"$ref:/node/3/input/0"}]}`
+
+	expected := `# Imported from graph 'test'
+
+let snmp_get = internal "snmp:get"
+let snmp_walk = internal "snmp:walk"
+let std_passthru = internal "std:passthru"
+
+let walk = snmp_walk("ifType")
+
+walk -> [inoct:snmp_get("ifInOctets"), snmp_get("ifOutOctets")]
+walk.Error -> std_passthru()
+walk
+`
+
+	g, err := Load(strings.NewReader(src), "graph.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := g.Source(Options{}); err != nil {
+		t.Fatal(err)
+	} else if s != expected {
+		t.Errorf("unexpected source:\n%s\nexpected:\n%s", s, expected)
+	}
+}
+
+func Test_ImportYAML(t *testing.T) {
+	src := `
+name: interfaces
+nodes:
+  - name: types
+    type: snmp:walk
+    args: {oid: ifType}
+    connections:
+      Output: [inOctets, errors]
+      Error: [errors.Input]
+  - name: inOctets
+    type: snmp:get
+    metadata: in_octets
+    args: {oid: "ifInOctets.{{ .Input.Key }}"}
+  - name: errors
+    type: std:passthru
+`
+	expected := `# Imported from graph 'interfaces'
+
+let snmp_get = internal "snmp:get"
+let snmp_walk = internal "snmp:walk"
+let std_passthru = internal "std:passthru"
+
+let types = snmp_walk("ifType")
+let errors = std_passthru()
+
+types -> [@in_octets:snmp_get("ifInOctets.{{ .Input.Key }}"), errors]
+types.Error -> errors
+types
+`
+
+	g, err := Load(strings.NewReader(src), "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := g.Source(Options{}); err != nil {
+		t.Fatal(err)
+	} else if s != expected {
+		t.Errorf("unexpected source:\n%s\nexpected:\n%s", s, expected)
+	}
+}
+
+// Field and metadata names are kept as they are when they're identifiers, even
+// with leading or trailing underscores.
+func Test_ImportUnderscoreNames(t *testing.T) {
+	src := `
+name: underscores
+nodes:
+  - name: walk
+    type: snmp:walk
+    field: _walk
+    connections:
+      Output: [get]
+  - name: get
+    type: snmp:get
+    metadata: octets_
+`
+	g, err := Load(strings.NewReader(src), "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := g.Source(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"_walk:snmp_walk()", "@octets_:snmp_get()"} {
+		if !strings.Contains(s, name) {
+			t.Errorf("expected %s in the imported source:\n%s", name, s)
+		}
+	}
+	p := parsing.NewParser(strings.NewReader(s))
+	if p.Parse() == nil {
+		t.Errorf("unable to parse the imported source: %v\n%s", p.Errors(), s)
+	}
+}
+
+// Nodes named after keywords are renamed, so the source still parses.
+func Test_ImportKeywordNames(t *testing.T) {
+	src := `
+name: keywords
+nodes:
+  - name: match
+    type: snmp:walk
+    connections:
+      Output: [none]
+  - name: none
+    type: snmp:get
+    connections:
+      Output: [try]
+  - name: try
+    type: std:passthru
+`
+	g, err := Load(strings.NewReader(src), "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := g.Source(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parsing.NewParser(strings.NewReader(s))
+	if p.Parse() == nil {
+		t.Errorf("unable to parse the imported source: %v\n%s", p.Errors(), s)
+	}
+}
+
+func Test_ImportInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"empty.yaml", "name: empty\n"},
+		{"unknown.yaml", "nodes:\n  - name: a\n    type: std:passthru\n    connections: {Output: [b]}\n"},
+		{"field.yaml", "nodes:\n  - name: a\n    type: std:passthru\n    field: in\n"},
+		{"digit.yaml", "nodes:\n  - name: a\n    type: std:passthru\n    metadata: 1a\n"},
+	}
+	for i, test := range tests {
+		if g, err := Load(strings.NewReader(test.src), test.name); err == nil {
+			if _, err := g.Source(Options{}); err == nil {
+				t.Errorf("[%d] expected error importing %s", i, test.name)
+			}
+		}
+	}
+}
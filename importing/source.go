@@ -0,0 +1,344 @@
+package importing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/format"
+	"github.com/nirosys/stitch/lexing"
+)
+
+type Options struct {
+	// ArgOrder returns the positional order of a node type's arguments. When
+	// it is nil, or doesn't know the type, arguments are ordered by name.
+	ArgOrder func(nodeType string) []string
+}
+
+// Source returns the stitch source that rebuilds the graph.
+func (g *Graph) Source(opts Options) (string, error) {
+	tree, err := g.Tree(opts)
+	if err != nil {
+		return "", err
+	}
+	return format.Source(tree), nil
+}
+
+// Tree builds the syntax tree for the graph.
+//
+// Every node type becomes an internal, nodes that fan out (or are shared) are
+// bound with `let`, and nodes that are only fed by one other node are created
+// inline in that node's connection. Connections from the same slot are
+// batched into a list: `a -> [b, c]`.
+func (g *Graph) Tree(opts Options) (*ast.ASTree, error) {
+	b := &builder{
+		graph:     g,
+		opts:      opts,
+		names:     map[*Node]string{},
+		internals: map[string]string{},
+		used:      map[string]bool{},
+	}
+	return b.build()
+}
+
+type builder struct {
+	graph     *Graph
+	opts      Options
+	names     map[*Node]string  // Identifier for bound nodes.
+	internals map[string]string // Identifier for each node type.
+	used      map[string]bool
+}
+
+func (b *builder) build() (*ast.ASTree, error) {
+	tree := &ast.ASTree{Statements: []ast.Statement{}}
+
+	if b.graph.Name != "" {
+		tree.Statements = append(tree.Statements, &ast.CommentStatement{
+			Text: fmt.Sprintf(" Imported from graph '%s'", b.graph.Name),
+		})
+	}
+
+	types := []string{}
+	for _, n := range b.graph.Nodes {
+		if _, have := b.internals[n.Type]; !have {
+			b.internals[n.Type] = b.uniqueName(typeIdentifier(n.Type))
+			types = append(types, n.Type)
+		}
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		tree.Statements = append(tree.Statements, &ast.LetStatement{
+			Name:  ident(b.internals[t]),
+			Value: &ast.InternalExpression{Name: &ast.StringLiteral{Value: t}},
+		})
+	}
+
+	incoming := map[*Node]int{}
+	for _, n := range b.graph.Nodes {
+		for _, c := range n.Connections {
+			incoming[c.To]++
+		}
+	}
+
+	inline := map[*Node]bool{}
+	for _, n := range b.graph.Nodes {
+		inline[n] = incoming[n] == 1 && len(n.Connections) == 0
+	}
+	for _, n := range b.graph.Nodes {
+		for _, c := range n.Connections {
+			if c.ToSlot != "Input" {
+				inline[c.To] = false
+			}
+		}
+	}
+
+	for _, n := range b.graph.Nodes {
+		if inline[n] {
+			continue
+		}
+		b.names[n] = b.uniqueName(nodeIdentifier(n))
+		value, err := b.construct(n)
+		if err != nil {
+			return nil, err
+		}
+		tree.Statements = append(tree.Statements, &ast.LetStatement{
+			Name:  ident(b.names[n]),
+			Value: value,
+		})
+	}
+
+	for _, n := range b.graph.Nodes {
+		if inline[n] {
+			continue
+		}
+		stmts, err := b.connections(n, inline)
+		if err != nil {
+			return nil, err
+		}
+		tree.Statements = append(tree.Statements, stmts...)
+	}
+
+	// The first node is Gaufre's root, leaving it as the result of the program
+	// makes it the root for `stitch compile` as well.
+	if len(b.graph.Nodes) > 0 {
+		tree.Statements = append(tree.Statements, ident(b.names[b.graph.Nodes[0]]))
+	}
+
+	return tree, nil
+}
+
+// Builds the connection statements for a node, one statement per output slot.
+// Connections into other slots than Input can't share a list with whole
+// nodes, so they are written one at a time.
+func (b *builder) connections(n *Node, inline map[*Node]bool) ([]ast.Statement, error) {
+	stmts := []ast.Statement{}
+
+	slots := []string{}
+	bySlot := map[string][]*Connection{}
+	for _, c := range n.Connections {
+		if _, have := bySlot[c.FromSlot]; !have {
+			slots = append(slots, c.FromSlot)
+		}
+		bySlot[c.FromSlot] = append(bySlot[c.FromSlot], c)
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		return slots[i] == "Output" && slots[j] != "Output"
+	})
+
+	for _, slot := range slots {
+		var source ast.Expression = ident(b.names[n])
+		if slot != "Output" {
+			source = dereference(source, slot)
+		}
+
+		batch := []ast.Expression{}
+		for _, c := range bySlot[slot] {
+			var target ast.Expression
+			if inline[c.To] {
+				exp, err := b.construct(c.To)
+				if err != nil {
+					return nil, err
+				}
+				target = exp
+			} else {
+				target = ident(b.names[c.To])
+			}
+
+			if c.ToSlot != "Input" {
+				stmts = append(stmts, &ast.ArrowExpression{
+					Left:  source,
+					Right: dereference(target, c.ToSlot),
+				})
+			} else {
+				batch = append(batch, target)
+			}
+		}
+
+		switch len(batch) {
+		case 0:
+		case 1:
+			stmts = append(stmts, &ast.ArrowExpression{Left: source, Right: batch[0]})
+		default:
+			stmts = append(stmts, &ast.ArrowExpression{
+				Left:  source,
+				Right: &ast.ListLiteral{Contents: batch},
+			})
+		}
+	}
+	return stmts, nil
+}
+
+// Builds the call that constructs the node, including its field or
+// metadata name.
+func (b *builder) construct(n *Node) (ast.Expression, error) {
+	call := &ast.CallExpression{Function: ident(b.internals[n.Type])}
+
+	for _, name := range b.argOrder(n) {
+		value, have := n.Args[name]
+		if !have {
+			return nil, fmt.Errorf("node '%s' is missing argument '%s'", n.Name, name)
+		}
+		lit, err := literal(value)
+		if err != nil {
+			return nil, fmt.Errorf("node '%s' argument '%s': %w", n.Name, name, err)
+		}
+		call.Arguments = append(call.Arguments, lit)
+	}
+
+	// Field and metadata names are written as identifiers, and unlike node
+	// names they can't be renamed without changing the data produced.
+	switch {
+	case n.Tag != "":
+		if !lexing.IsIdentifier(n.Tag) {
+			return nil, fmt.Errorf("node '%s' metadata name '%s' is not a valid identifier", n.Name, n.Tag)
+		}
+		return &ast.NamedNodeExpression{TagName: ident(n.Tag), Expression: call}, nil
+	case n.Field != "":
+		if !lexing.IsIdentifier(n.Field) {
+			return nil, fmt.Errorf("node '%s' field name '%s' is not a valid identifier", n.Name, n.Field)
+		}
+		return &ast.NamedNodeExpression{FieldName: ident(n.Field), Expression: call}, nil
+	}
+	return call, nil
+}
+
+func (b *builder) argOrder(n *Node) []string {
+	if b.opts.ArgOrder != nil {
+		if order := b.opts.ArgOrder(n.Type); order != nil {
+			// Arguments the type doesn't know about would be silently dropped
+			// otherwise, so they're kept at the end.
+			extra := []string{}
+			for name := range n.Args {
+				if !contains(order, name) {
+					extra = append(extra, name)
+				}
+			}
+			sort.Strings(extra)
+			return append(append([]string{}, order...), extra...)
+		}
+	}
+	names := make([]string, 0, len(n.Args))
+	for name := range n.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (b *builder) uniqueName(base string) string {
+	name := base
+	for i := 2; b.used[name] || isKeyword(name); i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	b.used[name] = true
+	return name
+}
+
+func literal(value interface{}) (ast.Expression, error) {
+	switch t := value.(type) {
+	case string:
+		return &ast.StringLiteral{Value: t}, nil
+	case bool:
+		return &ast.BoolLiteral{Value: t}, nil
+	case int:
+		return &ast.IntegerLiteral{Value: int64(t)}, nil
+	case int64:
+		return &ast.IntegerLiteral{Value: t}, nil
+	case float64:
+		if t != math.Trunc(t) {
+			return nil, fmt.Errorf("non-integer number %v not supported", t)
+		}
+		return &ast.IntegerLiteral{Value: int64(t)}, nil
+	default:
+		return nil, fmt.Errorf("values of type %T not supported", value)
+	}
+}
+
+func ident(name string) *ast.Identifier {
+	return &ast.Identifier{Identifier: name}
+}
+
+func dereference(exp ast.Expression, name string) ast.Expression {
+	return &ast.InfixExpression{Left: exp, Operator: ".", Right: ident(name)}
+}
+
+// "snmp:get" becomes "snmp_get".
+func typeIdentifier(nodeType string) string {
+	return sanitize(nodeType, "node_type")
+}
+
+// Nodes keep their own name when they have one, otherwise they are named
+// after their field name, or their type.
+func nodeIdentifier(n *Node) string {
+	switch {
+	case n.Name != "":
+		return sanitize(n.Name, "node")
+	case n.Field != "":
+		return sanitize(n.Field, "node")
+	case n.Tag != "":
+		return sanitize(n.Tag, "node")
+	}
+	base := n.Type
+	if idx := strings.LastIndex(base, ":"); idx >= 0 {
+		base = base[idx+1:]
+	}
+	return sanitize(base, "node")
+}
+
+func sanitize(name, fallback string) string {
+	var buffer strings.Builder
+	for i, r := range name {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || r == '_'):
+			buffer.WriteRune(r)
+		case r < unicode.MaxASCII && unicode.IsDigit(r):
+			if i == 0 {
+				buffer.WriteByte('_')
+			}
+			buffer.WriteRune(r)
+		default:
+			buffer.WriteByte('_')
+		}
+	}
+	if s := strings.Trim(buffer.String(), "_"); s != "" {
+		return s
+	}
+	return fallback
+}
+
+func isKeyword(name string) bool {
+	_, ok := lexing.Keyword(name)
+	return ok
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"unicode/utf8"
 )
 
 var ErrUnexepctedChar = errors.New("unexpected character")
+var ErrUnterminatedString = errors.New("unterminated string")
+var ErrInvalidEscape = errors.New("invalid escape sequence")
 
 type TokenType uint8
 
@@ -26,15 +29,25 @@ const (
 	K_OR                  /* or - for logic */
 	K_FOREACH             /* foreach - for looping */
 	K_IN                  /* in - for foreach loops */
+	K_BREAK               /* break - leave a foreach loop */
+	K_CONTINUE            /* continue - next foreach iteration */
+	K_MATCH               /* match - start of a match expression */
+	K_TRY                 /* try - block whose errors can be caught */
+	K_CATCH               /* catch - handles the errors of a try block */
+	K_NONE                /* none - the absence of a value */
+	K_IS                  /* is - for 'is none' checks */
+	K_CONST               /* const - statement start, for constants */
 	L_INTEGER             //
 	L_FLOAT               //
 	L_STRING              //
+	L_INTERP              /* "...${x}..." - string with expressions in it */
 	D_RPARENTH            //
 	D_LPARENTH            //
 	D_RBRACE              //
 	D_LBRACE              //
 	D_LBRACKET            //
 	D_RBRACKET            //
+	D_MAPBRACE            /* #{ - start of a map literal */
 	D_SEMICOLON           //
 	O_MINUS               //
 	O_PLUS                //
@@ -55,6 +68,17 @@ const (
 	O_BANG                // !
 	O_ARROW               //
 	O_ASSIGN              //
+	O_FATARROW            // =>
+	O_PIPE                // |
+	O_AMP                 // &
+	O_CARET               // ^
+	O_TILDE               // ~
+	O_SHL                 // <<
+	O_SHR                 // >>
+	O_POWER               // **
+	O_RANGE               // ..
+	O_RANGEX              // ..<
+	O_DEFAULT             // ??
 	IDENT                 //
 	COMMENT               //
 	EOF                   //
@@ -75,15 +99,25 @@ var TokenStrings = map[TokenType]string{
 	K_OR:        "keyword 'or'",
 	K_FOREACH:   "keyword 'foreach'",
 	K_IN:        "keyword 'in'",
+	K_BREAK:     "keyword 'break'",
+	K_CONTINUE:  "keyword 'continue'",
+	K_MATCH:     "keyword 'match'",
+	K_TRY:       "keyword 'try'",
+	K_CATCH:     "keyword 'catch'",
+	K_NONE:      "keyword 'none'",
+	K_IS:        "keyword 'is'",
+	K_CONST:     "keyword 'const'",
 	L_INTEGER:   "INTEGER literal",
 	L_FLOAT:     "FLOAT literal",
 	L_STRING:    "STRING literal",
+	L_INTERP:    "interpolated STRING literal",
 	D_RPARENTH:  "')'",
 	D_LPARENTH:  "'('",
 	D_RBRACE:    "'}'",
 	D_LBRACE:    "'{'",
 	D_LBRACKET:  "'['",
 	D_RBRACKET:  "']'",
+	D_MAPBRACE:  "'#{'",
 	D_SEMICOLON: "';'",
 	O_MINUS:     "'-'",
 	O_PLUS:      "'+'",
@@ -104,11 +138,82 @@ var TokenStrings = map[TokenType]string{
 	O_BANG:      "'!'",
 	O_ARROW:     "'->'",
 	O_ASSIGN:    "'='",
+	O_FATARROW:  "'=>'",
+	O_PIPE:      "'|'",
+	O_AMP:       "'&'",
+	O_CARET:     "'^'",
+	O_TILDE:     "'~'",
+	O_SHL:       "'<<'",
+	O_SHR:       "'>>'",
+	O_POWER:     "'**'",
+	O_RANGE:     "'..'",
+	O_RANGEX:    "'..<'",
+	O_DEFAULT:   "'??'",
 	IDENT:       "IDENTIFIER",
 	COMMENT:     "COMMENT",
 	EOF:         "EOF",
 }
 
+// IsKeyword reports whether the token is one of the language's keywords.
+func (t TokenType) IsKeyword() bool {
+	return t >= K_LET && t <= K_CONST
+}
+
+var keywords = map[string]TokenType{
+	"let":      K_LET,
+	"import":   K_IMPORT,
+	"node":     K_NODE,
+	"fn":       K_FUNCTION,
+	"mod":      K_MODIFIER,
+	"internal": K_INTERNAL,
+	"if":       K_IF,
+	"else":     K_ELSE,
+	"true":     K_TRUE,
+	"false":    K_FALSE,
+	"and":      K_AND,
+	"or":       K_OR,
+	"foreach":  K_FOREACH,
+	"in":       K_IN,
+	"break":    K_BREAK,
+	"continue": K_CONTINUE,
+	"match":    K_MATCH,
+	"try":      K_TRY,
+	"catch":    K_CATCH,
+	"none":     K_NONE,
+	"is":       K_IS,
+	"const":    K_CONST,
+}
+
+// Keyword returns the keyword token for name, if name is a keyword and so
+// can't be used as an identifier.
+func Keyword(name string) (TokenType, bool) {
+	t, ok := keywords[name]
+	return t, ok
+}
+
+// IsIdentifier reports whether name lexes as a single identifier: a letter or
+// underscore, then letters, digits and underscores, and not a keyword.
+func IsIdentifier(name string) bool {
+	if name == "" || !isIdentifierStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isIdentifierChar(name[i]) {
+			return false
+		}
+	}
+	_, keyword := keywords[name]
+	return !keyword
+}
+
+func isIdentifierStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isIdentifierChar(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
 type Position struct {
 	Line   int
 	Column int
@@ -127,6 +232,25 @@ type Token struct {
 	Text     string
 	Position Position
 	Type     TokenType
+	Parts    []StringPart // Of strings with interpolations or templates.
+}
+
+type PartKind uint8
+
+const (
+	PartText          PartKind = iota
+	PartInterpolation          // ${expression}, evaluated by stitch
+	PartTemplate               // {{ template }}, left for run time
+)
+
+// StringPart is a piece of a string literal. Text is the string's own text,
+// or the source of an interpolated expression, and the positions are where
+// the part starts and ends in the source, delimiters included.
+type StringPart struct {
+	Kind     PartKind
+	Text     string
+	Position Position
+	End      Position
 }
 
 type Lexer struct {
@@ -148,6 +272,14 @@ func NewLexer(r io.Reader) *Lexer {
 	}
 }
 
+// NewLexerAt reads source that starts at pos in a larger file, such as the
+// expressions in interpolated strings, so its tokens keep their real positions.
+func NewLexerAt(r io.Reader, pos Position) *Lexer {
+	l := NewLexer(r)
+	l.position = pos
+	return l
+}
+
 func (l *Lexer) readMore() error {
 	l.buffer = l.buffer[:cap(l.buffer)] // Reset our buffer to max size, jic
 
@@ -185,42 +317,15 @@ func (l *Lexer) NextToken() (Token, error) {
 				numType = L_FLOAT
 			}
 			return Token{Text: string(b), Position: pos, Type: numType}, nil
-		} else if (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || char == '_' {
+		} else if isIdentifierStart(char) {
 			b, err := l.slurpIdentifier()
 			if err != nil {
 				return Token{}, err
 			}
 			t := Token{Text: string(b), Position: pos}
-			switch t.Text {
-			case "let":
-				t.Type = K_LET
-			case "import":
-				t.Type = K_IMPORT
-			case "node":
-				t.Type = K_NODE
-			case "fn":
-				t.Type = K_FUNCTION
-			case "internal":
-				t.Type = K_INTERNAL
-			case "if":
-				t.Type = K_IF
-			case "else":
-				t.Type = K_ELSE
-			case "true":
-				t.Type = K_TRUE
-			case "false":
-				t.Type = K_FALSE
-			case "and":
-				t.Type = K_AND
-			case "or":
-				t.Type = K_OR
-			case "foreach":
-				t.Type = K_FOREACH
-			case "in":
-				t.Type = K_IN
-			case "mod":
-				t.Type = K_MODIFIER
-			default:
+			if kw, ok := keywords[t.Text]; ok {
+				t.Type = kw
+			} else {
 				t.Type = IDENT
 			}
 			return t, nil
@@ -240,6 +345,10 @@ func (l *Lexer) NextToken() (Token, error) {
 				}
 			case '*':
 				_, _ = l.takeChar()
+				if l.peekIs('*') {
+					_, _ = l.takeChar()
+					return Token{Text: "**", Position: pos, Type: O_POWER}, nil
+				}
 				return Token{Text: "*", Position: pos, Type: O_STAR}, nil
 			case '/':
 				_, _ = l.takeChar()
@@ -276,13 +385,24 @@ func (l *Lexer) NextToken() (Token, error) {
 				return Token{Text: ":", Position: pos, Type: O_COLON}, nil
 			case '.':
 				_, _ = l.takeChar()
-				return Token{Text: ".", Position: pos, Type: O_DOT}, nil
+				if !l.peekIs('.') {
+					return Token{Text: ".", Position: pos, Type: O_DOT}, nil
+				}
+				_, _ = l.takeChar()
+				if l.peekIs('<') {
+					_, _ = l.takeChar()
+					return Token{Text: "..<", Position: pos, Type: O_RANGEX}, nil
+				}
+				return Token{Text: "..", Position: pos, Type: O_RANGE}, nil
 			case '<':
 				_, _ = l.takeChar()
 
 				next, err := l.peekChar()
 				if err != nil {
 					return Token{}, err
+				} else if next == '<' {
+					_, _ = l.takeChar()
+					return Token{Text: "<<", Position: pos, Type: O_SHL}, nil
 				} else if next != '=' {
 					return Token{Text: "<", Position: pos, Type: O_LT}, nil
 				} else {
@@ -295,12 +415,22 @@ func (l *Lexer) NextToken() (Token, error) {
 				next, err := l.peekChar()
 				if err != nil {
 					return Token{}, err
+				} else if next == '>' {
+					_, _ = l.takeChar()
+					return Token{Text: ">>", Position: pos, Type: O_SHR}, nil
 				} else if next != '=' {
 					return Token{Text: ">", Position: pos, Type: O_GT}, nil
 				} else {
 					_, _ = l.takeChar()
 					return Token{Text: ">=", Position: pos, Type: O_GTEQ}, nil
 				}
+			case '?':
+				_, _ = l.takeChar()
+				if l.peekIs('?') {
+					_, _ = l.takeChar()
+					return Token{Text: "??", Position: pos, Type: O_DEFAULT}, nil
+				}
+				return Token{Position: pos}, fmt.Errorf("%w: ?", ErrUnexepctedChar)
 			case '!':
 				_, _ = l.takeChar()
 
@@ -320,6 +450,9 @@ func (l *Lexer) NextToken() (Token, error) {
 
 				if err != nil {
 					return Token{}, err
+				} else if next == '>' {
+					_, _ = l.takeChar()
+					return Token{Text: "=>", Position: pos, Type: O_FATARROW}, nil
 				} else if next != '=' {
 					return Token{Text: "=", Position: pos, Type: O_ASSIGN}, nil
 				} else if next == '=' {
@@ -327,12 +460,23 @@ func (l *Lexer) NextToken() (Token, error) {
 					return Token{Text: "==", Position: pos, Type: O_EQ}, nil
 				}
 			case '"':
-				b, err := l.slurpString()
+				parts, err := l.slurpString()
 				if err != nil {
-					return Token{}, err
+					return Token{Position: pos}, err
 				}
-				return Token{Text: string(b), Position: pos, Type: L_STRING}, nil
+				return stringToken(pos, parts), nil
+			case '`':
+				parts, err := l.slurpRawString()
+				if err != nil {
+					return Token{Position: pos}, err
+				}
+				return stringToken(pos, parts), nil
 			case '#':
+				_, _ = l.takeChar()
+				if next, err := l.peekChar(); err == nil && next == '{' {
+					_, _ = l.takeChar()
+					return Token{Text: "#{", Position: pos, Type: D_MAPBRACE}, nil
+				}
 				b, err := l.slurpComment()
 				if err != nil {
 					return Token{}, err
@@ -341,6 +485,18 @@ func (l *Lexer) NextToken() (Token, error) {
 			case '@':
 				_, _ = l.takeChar()
 				return Token{Text: "@", Position: pos, Type: O_TAGMARKER}, nil
+			case '|':
+				_, _ = l.takeChar()
+				return Token{Text: "|", Position: pos, Type: O_PIPE}, nil
+			case '&':
+				_, _ = l.takeChar()
+				return Token{Text: "&", Position: pos, Type: O_AMP}, nil
+			case '^':
+				_, _ = l.takeChar()
+				return Token{Text: "^", Position: pos, Type: O_CARET}, nil
+			case '~':
+				_, _ = l.takeChar()
+				return Token{Text: "~", Position: pos, Type: O_TILDE}, nil
 			case ' ', '\t':
 				_, _ = l.takeChar()
 			case '\n':
@@ -348,7 +504,7 @@ func (l *Lexer) NextToken() (Token, error) {
 				l.position.AdvanceLine()
 			default:
 				ch, _ := l.takeChar()
-				return Token{}, fmt.Errorf("%w: %c", ErrUnexepctedChar, ch)
+				return Token{Position: pos}, fmt.Errorf("%w: %c", ErrUnexepctedChar, ch)
 			}
 		}
 	}
@@ -365,6 +521,26 @@ func (l *Lexer) peekChar() (byte, error) {
 	return l.buffer[l.readPos], nil
 }
 
+// Looks n characters past the next one, keeping what's unread when it has to
+// read more.
+func (l *Lexer) peekAhead(n int) (byte, error) {
+	for l.readPos+n >= len(l.buffer) {
+		more := make([]byte, cap(l.buffer))
+		read, err := l.input.Read(more)
+		if read == 0 && err != nil {
+			return 0, err
+		}
+		l.buffer = append(append([]byte{}, l.buffer[l.readPos:]...), more[:read]...)
+		l.readPos = 0
+	}
+	return l.buffer[l.readPos+n], nil
+}
+
+func (l *Lexer) digitAhead() bool {
+	next, err := l.peekAhead(1)
+	return err == nil && next >= '0' && next <= '9'
+}
+
 func (l *Lexer) takeChar() (byte, error) {
 	if l.readPos >= len(l.buffer) {
 		err := l.readMore()
@@ -383,7 +559,8 @@ func (l *Lexer) slurpNumeric() ([]byte, bool, error) {
 	var bytes []byte
 
 	cur, err := l.peekChar()
-	for ((cur <= '9' && cur >= '0') || (cur == '.' && !haveDecimal)) && err == nil {
+	// A point only starts a fraction with a digit after it, so 1..48 is a range.
+	for ((cur <= '9' && cur >= '0') || (cur == '.' && !haveDecimal && l.digitAhead())) && err == nil {
 		cur, err = l.takeChar()
 		if !haveDecimal {
 			haveDecimal = cur == '.'
@@ -400,7 +577,7 @@ func (l *Lexer) slurpNumeric() ([]byte, bool, error) {
 func (l *Lexer) slurpIdentifier() ([]byte, error) {
 	var bytes []byte
 	cur, err := l.peekChar()
-	for ((cur <= 'z' && cur >= 'a') || (cur <= 'Z' && cur >= 'A') || (cur <= '9' && cur >= '0') || cur == '_') && err == nil {
+	for isIdentifierChar(cur) && err == nil {
 		cur, err = l.takeChar()
 		bytes = append(bytes, cur)
 		cur, err = l.peekChar()
@@ -411,50 +588,351 @@ func (l *Lexer) slurpIdentifier() ([]byte, error) {
 	return bytes, err
 }
 
-func (l *Lexer) slurpString() ([]byte, error) {
-	var escaped bool = false
-	var done bool = false
-	var bytes []byte
-	_, err := l.takeChar() // take leading double-quote
-	if err != nil {
-		return []byte{}, err
+// Position is where the next token will start.
+func (l *Lexer) Position() Position {
+	return l.position
+}
+
+// Strings ////////////////////////////////////////////////////////////////////
+
+// Strings are plain strings unless they have an interpolation, and only keep
+// their parts when they have interpolations or templates.
+func stringToken(pos Position, parts []StringPart) Token {
+	tok := Token{Position: pos, Type: L_STRING}
+	var text []byte
+	for _, part := range parts {
+		switch part.Kind {
+		case PartInterpolation:
+			tok.Type = L_INTERP
+			text = append(text, "${"+part.Text+"}"...)
+			tok.Parts = parts
+		case PartTemplate:
+			text = append(text, part.Text...)
+			tok.Parts = parts
+		default:
+			text = append(text, part.Text...)
+		}
 	}
+	tok.Text = string(text)
+	return tok
+}
 
-	cur, err := l.peekChar()
-	for !done && err == nil {
-		if cur == '"' && !escaped {
-			done = true
-			_, _ = l.takeChar()
-		} else if cur == '"' && escaped {
-			escaped = false
-			bytes = append(bytes, '"')
-			_, _ = l.takeChar()
-		} else if cur == '\\' && !escaped {
-			escaped = true
-			_, _ = l.takeChar()
-		} else if escaped {
-			_, _ = l.takeChar()
-			escaped = false
-			bytes = append(bytes, '\\')
-			bytes = append(bytes, cur)
-		} else {
-			cur, _ = l.takeChar()
+// Collects the parts of a string as it's taken.
+type partBuilder struct {
+	parts    []StringPart
+	text     []byte
+	kind     PartKind
+	start    Position
+	template int // The first part of the open template.
+}
+
+func (b *partBuilder) flush(end Position) {
+	if len(b.text) > 0 || b.kind == PartInterpolation {
+		b.parts = append(b.parts, StringPart{Kind: b.kind, Text: string(b.text), Position: b.start, End: end})
+	}
+	b.text = nil
+	b.kind = PartText
+	b.start = end
+}
+
+// A template that never closes is just text.
+func (b *partBuilder) finish(end Position) []StringPart {
+	if b.kind == PartTemplate {
+		for i := b.template; i < len(b.parts); i++ {
+			if b.parts[i].Kind == PartTemplate {
+				b.parts[i].Kind = PartText
+			}
+		}
+		if n := len(b.parts); n > 0 && b.parts[n-1].Kind == PartText {
+			b.text = append([]byte(b.parts[n-1].Text), b.text...)
+			b.start = b.parts[n-1].Position
+			b.parts = b.parts[:n-1]
+		}
+	}
+	b.kind = PartText
+	b.flush(end)
+	return b.parts
+}
+
+// Takes a double-quoted string, or a triple-quoted one. Triple-quoted strings
+// are meant to span lines, and drop a newline straight after the opening
+// quotes. Both have their escape sequences replaced.
+func (l *Lexer) slurpString() ([]StringPart, error) {
+	_, _ = l.takeChar() // take leading double-quote
+	quotes := 1
+	for quotes < 3 {
+		if !l.peekIs('"') {
+			break
+		}
+		_, _ = l.takeChar()
+		quotes++
+	}
+	switch quotes {
+	case 2:
+		return nil, nil
+	case 3:
+		return l.slurpTripleString()
+	}
+
+	b := &partBuilder{start: l.position}
+	for {
+		pos := l.position
+		cur, err := l.takeStringChar()
+		if err != nil {
+			return nil, err
+		}
+		switch cur {
+		case '"':
+			return b.finish(pos), nil
+		case '\\':
+			if b.text, err = l.slurpEscape(b.text); err != nil {
+				return nil, err
+			}
+		default:
+			if err := l.addStringChar(b, cur, pos, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// Quotes inside a triple-quoted string only end it when there are three in a
+// row, and any more than three belong to the string.
+func (l *Lexer) slurpTripleString() ([]StringPart, error) {
+	if l.peekIs('\n') {
+		_, _ = l.takeStringChar()
+	}
+
+	b := &partBuilder{start: l.position}
+	quotes := 0
+	for {
+		pos := l.position
+		cur, err := l.takeStringChar()
+		if err != nil {
+			return nil, err
+		}
+		if cur == '"' {
+			quotes++
+			if quotes < 3 {
+				continue
+			}
+			if l.peekIs('"') {
+				b.text = append(b.text, '"')
+				quotes--
+				continue
+			}
+			return b.finish(Position{Line: pos.Line, Column: pos.Column - 2}), nil
+		}
+
+		b.text = append(b.text, `""`[:quotes]...)
+		quotes = 0
+		if cur == '\\' {
+			if b.text, err = l.slurpEscape(b.text); err != nil {
+				return nil, err
+			}
+		} else if err := l.addStringChar(b, cur, pos, true); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Raw strings are everything up to the closing backtick, as written, so they
+// can't interpolate.
+func (l *Lexer) slurpRawString() ([]StringPart, error) {
+	_, _ = l.takeChar() // take leading backtick
+	b := &partBuilder{start: l.position}
+	for {
+		pos := l.position
+		cur, err := l.takeStringChar()
+		if err != nil {
+			return nil, err
+		} else if cur == '`' {
+			return b.finish(pos), nil
+		}
+		_ = l.addStringChar(b, cur, pos, false)
+	}
+}
+
+// Adds a character, taken from pos, to the string being built, starting and
+// ending the parts interpolations and templates make. Interpolations inside a
+// template split it, so "{{ .Key - ${n} }}" has n put in before run time.
+func (l *Lexer) addStringChar(b *partBuilder, cur byte, pos Position, interpolate bool) error {
+	switch {
+	case b.kind == PartTemplate && cur == '}' && l.peekIs('}'):
+		_, _ = l.takeStringChar()
+		b.text = append(b.text, "}}"...)
+		b.flush(l.position)
+	case b.kind == PartText && cur == '{' && l.peekIs('{'):
+		_, _ = l.takeStringChar()
+		b.flush(pos)
+		b.kind = PartTemplate
+		b.template = len(b.parts)
+		b.text = append(b.text, "{{"...)
+	case b.kind != PartInterpolation && interpolate && cur == '$' && l.peekIs('{'):
+		_, _ = l.takeStringChar()
+		kind := b.kind
+		b.flush(pos)
+		src, err := l.slurpInterpolation()
+		if err != nil {
+			return err
+		}
+		b.kind = PartInterpolation
+		b.text = src
+		b.flush(l.position)
+		b.kind = kind
+	default:
+		b.text = append(b.text, cur)
+	}
+	return nil
+}
+
+// Takes the source of an interpolated expression, up to its closing brace.
+// Strings inside it are taken whole, so their braces don't count.
+func (l *Lexer) slurpInterpolation() ([]byte, error) {
+	var bytes []byte
+	depth := 0
+	for {
+		cur, err := l.takeStringChar()
+		if err != nil {
+			return nil, err
+		}
+		switch cur {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return bytes, nil
+			}
+			depth--
+		case '"', '`':
+			nested, err := l.slurpNestedString(cur)
+			if err != nil {
+				return nil, err
+			}
 			bytes = append(bytes, cur)
+			bytes = append(bytes, nested...)
+			continue
 		}
-		cur, err = l.peekChar()
+		bytes = append(bytes, cur)
 	}
-	if err == io.EOF && done {
-		err = nil
+}
+
+// Takes the source of a string inside an interpolation, as written.
+func (l *Lexer) slurpNestedString(quote byte) ([]byte, error) {
+	var bytes []byte
+	for {
+		cur, err := l.takeStringChar()
+		if err != nil {
+			return nil, err
+		}
+		bytes = append(bytes, cur)
+		switch {
+		case cur == quote:
+			return bytes, nil
+		case quote == '"' && cur == '\\':
+			if next, err := l.takeStringChar(); err != nil {
+				return nil, err
+			} else {
+				bytes = append(bytes, next)
+			}
+		case quote == '"' && cur == '$' && l.peekIs('{'):
+			_, _ = l.takeStringChar()
+			inner, err := l.slurpInterpolation()
+			if err != nil {
+				return nil, err
+			}
+			bytes = append(bytes, '{')
+			bytes = append(bytes, inner...)
+			bytes = append(bytes, '}')
+		}
 	}
+}
+
+// Appends the character an escape sequence stands for, once its backslash has
+// been taken.
+func (l *Lexer) slurpEscape(bytes []byte) ([]byte, error) {
+	cur, err := l.takeStringChar()
 	if err != nil {
-		return []byte{}, err
+		return bytes, err
+	}
+	switch cur {
+	case 'n':
+		return append(bytes, '\n'), nil
+	case 't':
+		return append(bytes, '\t'), nil
+	case 'r':
+		return append(bytes, '\r'), nil
+	case '0':
+		return append(bytes, 0), nil
+	case 'a':
+		return append(bytes, '\a'), nil
+	case 'b':
+		return append(bytes, '\b'), nil
+	case 'f':
+		return append(bytes, '\f'), nil
+	case 'v':
+		return append(bytes, '\v'), nil
+	case '\\', '"', '\'', '$':
+		return append(bytes, cur), nil
+	case 'u', 'U':
+		digits := 4
+		if cur == 'U' {
+			digits = 8
+		}
+		var r rune
+		for i := 0; i < digits; i++ {
+			h, err := l.takeStringChar()
+			if err != nil {
+				return bytes, err
+			}
+			v := hexValue(h)
+			if v < 0 {
+				return bytes, fmt.Errorf("%w: \\%c needs %d hex digits", ErrInvalidEscape, cur, digits)
+			}
+			r = r<<4 | rune(v)
+		}
+		if !utf8.ValidRune(r) {
+			return bytes, fmt.Errorf("%w: %U is not a valid character", ErrInvalidEscape, r)
+		}
+		return append(bytes, string(r)...), nil
+	}
+	return bytes, fmt.Errorf("%w: \\%c", ErrInvalidEscape, cur)
+}
+
+// Strings can span lines, so newlines inside them have to move the position
+// along, and running out of input before the closing quote is an error.
+func (l *Lexer) takeStringChar() (byte, error) {
+	if _, err := l.peekChar(); err == io.EOF || len(l.buffer) == 0 {
+		return 0, ErrUnterminatedString
+	} else if err != nil {
+		return 0, err
+	}
+	cur, _ := l.takeChar()
+	if cur == '\n' {
+		l.position.AdvanceLine()
+	}
+	return cur, nil
+}
+
+func (l *Lexer) peekIs(c byte) bool {
+	next, err := l.peekChar()
+	return err == nil && next == c
+}
+
+func hexValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
 	}
-	return bytes, nil
+	return -1
 }
 
 func (l *Lexer) slurpComment() ([]byte, error) {
 	var bytes []byte
-	_, _ = l.takeChar() // Take leading #
 
 	cur, err := l.peekChar()
 	for cur != '\n' && err == nil {
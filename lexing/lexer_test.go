@@ -1,6 +1,7 @@
 package lexing
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -117,6 +118,65 @@ func Test_Lexer(t *testing.T) {
 			Token{Text: "{", Position: Position{Line: 0, Column: 16}, Type: D_LBRACE},
 			Token{Text: "}", Position: Position{Line: 0, Column: 17}, Type: D_RBRACE},
 		}},
+		{"break continue", []Token{
+			Token{Text: "break", Position: Position{Line: 0, Column: 0}, Type: K_BREAK},
+			Token{Text: "continue", Position: Position{Line: 0, Column: 6}, Type: K_CONTINUE},
+		}},
+		{"match x { 1 | 2 => a }", []Token{
+			Token{Text: "match", Position: Position{Line: 0, Column: 0}, Type: K_MATCH},
+			Token{Text: "x", Position: Position{Line: 0, Column: 6}, Type: IDENT},
+			Token{Text: "{", Position: Position{Line: 0, Column: 8}, Type: D_LBRACE},
+			Token{Text: "1", Position: Position{Line: 0, Column: 10}, Type: L_INTEGER},
+			Token{Text: "|", Position: Position{Line: 0, Column: 12}, Type: O_PIPE},
+			Token{Text: "2", Position: Position{Line: 0, Column: 14}, Type: L_INTEGER},
+			Token{Text: "=>", Position: Position{Line: 0, Column: 16}, Type: O_FATARROW},
+			Token{Text: "a", Position: Position{Line: 0, Column: 19}, Type: IDENT},
+			Token{Text: "}", Position: Position{Line: 0, Column: 21}, Type: D_RBRACE},
+		}},
+		{"a ** -b & ~c | d ^ e << f >> g", []Token{
+			Token{Text: "a", Position: Position{Line: 0, Column: 0}, Type: IDENT},
+			Token{Text: "**", Position: Position{Line: 0, Column: 2}, Type: O_POWER},
+			Token{Text: "-", Position: Position{Line: 0, Column: 5}, Type: O_MINUS},
+			Token{Text: "b", Position: Position{Line: 0, Column: 6}, Type: IDENT},
+			Token{Text: "&", Position: Position{Line: 0, Column: 8}, Type: O_AMP},
+			Token{Text: "~", Position: Position{Line: 0, Column: 10}, Type: O_TILDE},
+			Token{Text: "c", Position: Position{Line: 0, Column: 11}, Type: IDENT},
+			Token{Text: "|", Position: Position{Line: 0, Column: 13}, Type: O_PIPE},
+			Token{Text: "d", Position: Position{Line: 0, Column: 15}, Type: IDENT},
+			Token{Text: "^", Position: Position{Line: 0, Column: 17}, Type: O_CARET},
+			Token{Text: "e", Position: Position{Line: 0, Column: 19}, Type: IDENT},
+			Token{Text: "<<", Position: Position{Line: 0, Column: 21}, Type: O_SHL},
+			Token{Text: "f", Position: Position{Line: 0, Column: 24}, Type: IDENT},
+			Token{Text: ">>", Position: Position{Line: 0, Column: 26}, Type: O_SHR},
+			Token{Text: "g", Position: Position{Line: 0, Column: 29}, Type: IDENT},
+		}},
+		{"x ?? none is none", []Token{
+			Token{Text: "x", Position: Position{Line: 0, Column: 0}, Type: IDENT},
+			Token{Text: "??", Position: Position{Line: 0, Column: 2}, Type: O_DEFAULT},
+			Token{Text: "none", Position: Position{Line: 0, Column: 5}, Type: K_NONE},
+			Token{Text: "is", Position: Position{Line: 0, Column: 10}, Type: K_IS},
+			Token{Text: "none", Position: Position{Line: 0, Column: 13}, Type: K_NONE},
+		}},
+		{"1..48 0..<n 1.5 x.y", []Token{
+			Token{Text: "1", Position: Position{Line: 0, Column: 0}, Type: L_INTEGER},
+			Token{Text: "..", Position: Position{Line: 0, Column: 1}, Type: O_RANGE},
+			Token{Text: "48", Position: Position{Line: 0, Column: 3}, Type: L_INTEGER},
+			Token{Text: "0", Position: Position{Line: 0, Column: 6}, Type: L_INTEGER},
+			Token{Text: "..<", Position: Position{Line: 0, Column: 7}, Type: O_RANGEX},
+			Token{Text: "n", Position: Position{Line: 0, Column: 10}, Type: IDENT},
+			Token{Text: "1.5", Position: Position{Line: 0, Column: 12}, Type: L_FLOAT},
+			Token{Text: "x", Position: Position{Line: 0, Column: 16}, Type: IDENT},
+			Token{Text: ".", Position: Position{Line: 0, Column: 17}, Type: O_DOT},
+			Token{Text: "y", Position: Position{Line: 0, Column: 18}, Type: IDENT},
+		}},
+		{"#{\"a\": 1} # a comment", []Token{
+			Token{Text: "#{", Position: Position{Line: 0, Column: 0}, Type: D_MAPBRACE},
+			Token{Text: "a", Position: Position{Line: 0, Column: 2}, Type: L_STRING},
+			Token{Text: ":", Position: Position{Line: 0, Column: 5}, Type: O_COLON},
+			Token{Text: "1", Position: Position{Line: 0, Column: 7}, Type: L_INTEGER},
+			Token{Text: "}", Position: Position{Line: 0, Column: 8}, Type: D_RBRACE},
+			Token{Text: " a comment", Position: Position{Line: 0, Column: 10}, Type: COMMENT},
+		}},
 	}
 
 	for i := range tests {
@@ -143,3 +203,133 @@ func Test_Lexer(t *testing.T) {
 		}
 	}
 }
+
+func Test_IsKeyword(t *testing.T) {
+	for _, tt := range []TokenType{K_LET, K_FOREACH, K_IN, K_BREAK, K_CONTINUE, K_MATCH, K_TRY, K_CATCH} {
+		if !tt.IsKeyword() {
+			t.Errorf("expected %s to be a keyword", TokenStrings[tt])
+		}
+	}
+	for _, tt := range []TokenType{L_INTEGER, IDENT, O_ARROW, EOF} {
+		if tt.IsKeyword() {
+			t.Errorf("expected %s not to be a keyword", TokenStrings[tt])
+		}
+	}
+	for tt, s := range TokenStrings {
+		if !tt.IsKeyword() {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(s, "keyword '"), "'")
+		if kw, ok := Keyword(name); !ok || kw != tt {
+			t.Errorf("expected '%s' to be looked up as %s", name, s)
+		}
+	}
+	if _, ok := Keyword("foo"); ok {
+		t.Errorf("expected 'foo' not to be a keyword")
+	}
+}
+
+func Test_IsIdentifier(t *testing.T) {
+	for _, name := range []string{"foo", "_foo", "foo_", "_", "if_Index2"} {
+		if !IsIdentifier(name) {
+			t.Errorf("expected '%s' to be an identifier", name)
+		}
+	}
+	for _, name := range []string{"", "1a", "a-b", "a.b", "match", "é"} {
+		if IsIdentifier(name) {
+			t.Errorf("expected '%s' not to be an identifier", name)
+		}
+	}
+}
+
+func Test_Strings(t *testing.T) {
+	tests := []struct {
+		in   string
+		text string
+		line int
+		err  error
+	}{
+		{`"ifHCInOctets"`, "ifHCInOctets", 0, nil},
+		{`""`, "", 0, nil},
+		{`"a\tb\n\"c\" \\"`, "a\tb\n\"c\" \\", 0, nil},
+		{`"caf\u00e9 \U0001F600"`, "café 😀", 0, nil},
+		{"\"\"\"\nline one\n  \"two\"\n\"\"\"", "line one\n  \"two\"\n", 3, nil},
+		{`""""quoted""""`, `"quoted"`, 0, nil},
+		{"`\\d+\\.\\d+`", `\d+\.\d+`, 0, nil},
+		{"`a\nb`", "a\nb", 1, nil},
+		{`"bad \q"`, "", 0, ErrInvalidEscape},
+		{`"\u12"`, "", 0, ErrInvalidEscape},
+		{`"open`, "", 0, ErrUnterminatedString},
+		{"`open", "", 0, ErrUnterminatedString},
+	}
+	for i, test := range tests {
+		lex := NewLexer(strings.NewReader(test.in))
+		tok, err := lex.NextToken()
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("[%d] expected error %v, found %v", i, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+		} else if tok.Type != L_STRING || tok.Text != test.text {
+			t.Errorf("[%d] expected string %q, found %s %q", i, test.text, TokenStrings[tok.Type], tok.Text)
+		} else if lex.Position().Line != test.line {
+			t.Errorf("[%d] expected to end on line %d, found %d", i, test.line, lex.Position().Line)
+		}
+	}
+}
+
+func Test_StringParts(t *testing.T) {
+	tests := []struct {
+		in    string
+		typ   TokenType
+		parts []StringPart
+	}{
+		{`"plain"`, L_STRING, nil},
+		{`"ifInOctets.${idx}"`, L_INTERP, []StringPart{
+			{Kind: PartText, Text: "ifInOctets.", Position: Position{Line: 0, Column: 1}, End: Position{Line: 0, Column: 12}},
+			{Kind: PartInterpolation, Text: "idx", Position: Position{Line: 0, Column: 12}, End: Position{Line: 0, Column: 18}},
+		}},
+		{`"${m["}"]}{{ .Host }}"`, L_INTERP, []StringPart{
+			{Kind: PartInterpolation, Text: `m["}"]`, Position: Position{Line: 0, Column: 1}, End: Position{Line: 0, Column: 10}},
+			{Kind: PartTemplate, Text: "{{ .Host }}", Position: Position{Line: 0, Column: 10}, End: Position{Line: 0, Column: 21}},
+		}},
+		{"`{{ .Host }} ${x}`", L_STRING, []StringPart{
+			{Kind: PartTemplate, Text: "{{ .Host }}", Position: Position{Line: 0, Column: 1}, End: Position{Line: 0, Column: 12}},
+			{Kind: PartText, Text: " ${x}", Position: Position{Line: 0, Column: 12}, End: Position{Line: 0, Column: 17}},
+		}},
+		{`"\${x} {{ open"`, L_STRING, nil},
+		{`"{{ .Key - ${n} }}"`, L_INTERP, []StringPart{
+			{Kind: PartTemplate, Text: "{{ .Key - ", Position: Position{Line: 0, Column: 1}, End: Position{Line: 0, Column: 11}},
+			{Kind: PartInterpolation, Text: "n", Position: Position{Line: 0, Column: 11}, End: Position{Line: 0, Column: 15}},
+			{Kind: PartTemplate, Text: " }}", Position: Position{Line: 0, Column: 15}, End: Position{Line: 0, Column: 18}},
+		}},
+		{`"{{ ${n} open"`, L_INTERP, []StringPart{
+			{Kind: PartText, Text: "{{ ", Position: Position{Line: 0, Column: 1}, End: Position{Line: 0, Column: 4}},
+			{Kind: PartInterpolation, Text: "n", Position: Position{Line: 0, Column: 4}, End: Position{Line: 0, Column: 8}},
+			{Kind: PartText, Text: " open", Position: Position{Line: 0, Column: 8}, End: Position{Line: 0, Column: 13}},
+		}},
+	}
+	for i, test := range tests {
+		lex := NewLexer(strings.NewReader(test.in))
+		tok, err := lex.NextToken()
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+			continue
+		}
+		if tok.Type != test.typ {
+			t.Errorf("[%d] expected %s, found %s", i, TokenStrings[test.typ], TokenStrings[tok.Type])
+		}
+		if len(tok.Parts) != len(test.parts) {
+			t.Errorf("[%d] expected %d parts, found %+v", i, len(test.parts), tok.Parts)
+			continue
+		}
+		for j := range test.parts {
+			if tok.Parts[j] != test.parts[j] {
+				t.Errorf("[%d] part %d: expected %+v, found %+v", i, j, test.parts[j], tok.Parts[j])
+			}
+		}
+	}
+}
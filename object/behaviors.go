@@ -19,6 +19,18 @@ type Computable interface {
 	Modulus(Object) (Object, error)
 }
 
+// Integral objects take the operators only integers have: the bitwise ones,
+// and exponentiation.
+type Integral interface {
+	Object
+	And(Object) (Object, error)
+	Or(Object) (Object, error)
+	Xor(Object) (Object, error)
+	ShiftLeft(Object) (Object, error)
+	ShiftRight(Object) (Object, error)
+	Power(Object) (Object, error)
+}
+
 type Connectable interface {
 	Object
 	Connect(Connectable) (Object, error)
@@ -0,0 +1,519 @@
+package object
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Converting between Go values and objects:
+//
+//	Go                          stitch
+//	--------------------------  -------
+//	bool                        BOOL
+//	int*, uint*                 INTEGER
+//	string                      STRING
+//	slices, arrays              LIST
+//	maps with string keys       MAP
+//	structs                     MAP
+//	Object (eg. *Node)          the object itself
+//	nil pointers, slices, maps  NONE, as arguments
+//
+// Struct fields are named after their `stitch` tag, or the field name:
+//
+//	type Agent struct {
+//		Address   string `stitch:"address"`
+//		Community string `stitch:"community,omitempty"`
+//		Secret    string `stitch:"-"`
+//	}
+//
+// Fields tagged "-", and unexported fields, are skipped, and "omitempty"
+// leaves zero values out of the map. Embedded structs without a tag have their
+// fields promoted, as with encoding/json.
+//
+// Objects without a Go equivalent, like nodes, are passed as opaque handles:
+// they convert to fields and arguments of their own type, or of an interface
+// they implement.
+
+// TypeMismatchError is returned when an object can't be converted to the Go
+// type that was asked for.
+type TypeMismatchError struct {
+	Expected string
+	Received ObjectType
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("type mismatch: expected %s, received %s", e.Expected, e.Received)
+}
+
+// ConversionError locates an error within the value being converted, for
+// example `Agents[2].port`.
+type ConversionError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConversionError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *ConversionError) Unwrap() error { return e.Err }
+
+func conversionError(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return &ConversionError{Path: path, Err: err}
+}
+
+var objectInterface = reflect.TypeOf((*Object)(nil)).Elem()
+
+// TypeName returns the name of the stitch type a Go type converts to, for use
+// in errors and signatures.
+func TypeName(t reflect.Type) string {
+	if t.Implements(objectInterface) {
+		if t.Kind() == reflect.Ptr {
+			// Objects are all pointers, so the zero value knows its type.
+			return string(reflect.New(t.Elem()).Interface().(Object).Type())
+		}
+		return "ANY"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return TypeName(t.Elem())
+	case reflect.Bool:
+		return BoolObjectType
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return IntegerObjectType
+	case reflect.String:
+		return StringObjectType
+	case reflect.Slice, reflect.Array:
+		return ListObjectType
+	case reflect.Map, reflect.Struct:
+		return MapObjectType
+	case reflect.Interface:
+		return "ANY"
+	}
+	return t.String()
+}
+
+// FromGo converts a Go value to a stitch object.
+func FromGo(v interface{}) (Object, error) {
+	return FromValue(reflect.ValueOf(v))
+}
+
+// ToGo converts an object into the Go value pointed to by target.
+func ToGo(obj Object, target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, found %T", target)
+	}
+	v, err := ToValue(obj, ptr.Type().Elem())
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(v)
+	return nil
+}
+
+// FromValue converts a Go value to a stitch object.
+func FromValue(v reflect.Value) (Object, error) {
+	return fromValue(v, "")
+}
+
+func fromValue(v reflect.Value, path string) (Object, error) {
+	if !v.IsValid() {
+		return nil, conversionError(path, fmt.Errorf("cannot convert nil to an object"))
+	}
+	if v.Type().Implements(objectInterface) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil, conversionError(path, fmt.Errorf("cannot convert nil to an object"))
+		}
+		return v.Interface().(Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, conversionError(path, fmt.Errorf("cannot convert nil to an object"))
+		}
+		return fromValue(v.Elem(), path)
+	case reflect.Bool:
+		return NewBoolObject(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := v.Uint(); u > 1<<63-1 {
+			return nil, conversionError(path, fmt.Errorf("%d overflows INTEGER", u))
+		} else {
+			return &Integer{Value: int64(u)}, nil
+		}
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		list := &List{Contents: make([]Object, 0, v.Len()), InnerType: UnknownObjectType}
+		for i := 0; i < v.Len(); i++ {
+			obj, err := fromValue(v.Index(i), indexPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			if list.InnerType == UnknownObjectType {
+				list.InnerType = obj.Type()
+			} else if list.InnerType != obj.Type() {
+				return nil, conversionError(indexPath(path, i), fmt.Errorf("mixed types for list, %s and %s", list.InnerType, obj.Type()))
+			}
+			list.Contents = append(list.Contents, obj)
+		}
+		return list, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, conversionError(path, fmt.Errorf("cannot convert map with %s keys, only string keys are supported", v.Type().Key()))
+		}
+		// Go maps are unordered, so keys are added in sorted order.
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		m := NewMap()
+		for _, key := range keys {
+			obj, err := fromValue(v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())), keyPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, obj)
+		}
+		return m, nil
+	case reflect.Struct:
+		m := NewMap()
+		for _, f := range structFields(v.Type()) {
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || (f.omitEmpty && fv.IsZero()) {
+				continue
+			}
+			obj, err := fromValue(fv, fieldPath(path, f.name))
+			if err != nil {
+				return nil, err
+			}
+			m.Set(f.name, obj)
+		}
+		return m, nil
+	}
+	return nil, conversionError(path, fmt.Errorf("cannot convert %s to an object", v.Type()))
+}
+
+// ToValue converts an object to a value of the Go type. Objects are only
+// converted to the types they'd be converted from by FromValue, or OIDs, IPs
+// and CIDRs to strings, otherwise a *TypeMismatchError is returned.
+// Converting to an empty interface gives the natural Go value: int64, string,
+// bool, []interface{}, or map[string]interface{}; other objects are passed as
+// they are.
+func ToValue(obj Object, t reflect.Type) (reflect.Value, error) {
+	return toValue(obj, t, "")
+}
+
+func toValue(obj Object, t reflect.Type, path string) (reflect.Value, error) {
+	if obj == nil {
+		return reflect.Value{}, conversionError(path, fmt.Errorf("cannot convert nil to %s", t))
+	}
+	objType := reflect.TypeOf(obj)
+	if t.Kind() == reflect.Interface && t.NumMethod() > 0 {
+		if objType.Implements(t) {
+			return reflect.ValueOf(obj), nil
+		}
+		return reflect.Value{}, mismatch(t, obj, path)
+	} else if t.Kind() != reflect.Interface && t.Implements(objectInterface) {
+		if objType.AssignableTo(t) {
+			return reflect.ValueOf(obj), nil
+		}
+		return reflect.Value{}, mismatch(t, obj, path)
+	}
+
+	if r, ok := obj.(*Range); ok {
+		list, err := r.List() // Converted like the list it stands for.
+		if err != nil {
+			return reflect.Value{}, conversionError(path, err)
+		}
+		obj = list
+	}
+	if obj == None {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return toInterface(obj, t, path)
+	case reflect.Ptr:
+		v, err := toValue(obj, t.Elem(), path)
+		if err != nil {
+			return v, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(v)
+		return ptr, nil
+	case reflect.Bool:
+		if b, ok := obj.(*BoolObject); ok {
+			return reflect.ValueOf(bool(*b)).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*Integer); ok {
+			v := reflect.New(t).Elem()
+			if v.OverflowInt(i.Value) {
+				return v, conversionError(path, fmt.Errorf("%d overflows %s", i.Value, t))
+			}
+			v.SetInt(i.Value)
+			return v, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i, ok := obj.(*Integer); ok {
+			v := reflect.New(t).Elem()
+			if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+				return v, conversionError(path, fmt.Errorf("%d overflows %s", i.Value, t))
+			}
+			v.SetUint(uint64(i.Value))
+			return v, nil
+		}
+	case reflect.String:
+		switch s := obj.(type) {
+		case *String:
+			return reflect.ValueOf(s.Value).Convert(t), nil
+		case *OID, *IP, *CIDR: // Passed in their text form.
+			return reflect.ValueOf(s.Inspect()).Convert(t), nil
+		}
+	case reflect.Slice:
+		if l, ok := obj.(*List); ok {
+			v := reflect.MakeSlice(t, 0, len(l.Contents))
+			for i, item := range l.Contents {
+				elem, err := toValue(item, t.Elem(), indexPath(path, i))
+				if err != nil {
+					return v, err
+				}
+				v = reflect.Append(v, elem)
+			}
+			return v, nil
+		}
+	case reflect.Array:
+		if l, ok := obj.(*List); ok {
+			v := reflect.New(t).Elem()
+			if len(l.Contents) != t.Len() {
+				return v, conversionError(path, fmt.Errorf("expected %d items, found %d", t.Len(), len(l.Contents)))
+			}
+			for i, item := range l.Contents {
+				elem, err := toValue(item, t.Elem(), indexPath(path, i))
+				if err != nil {
+					return v, err
+				}
+				v.Index(i).Set(elem)
+			}
+			return v, nil
+		}
+	case reflect.Map:
+		if m, ok := obj.(*MapObject); ok && t.Key().Kind() == reflect.String {
+			if len(m.Fields) != m.Len() {
+				return reflect.Value{}, conversionError(path, fmt.Errorf("%s can only take STRING keys", TypeName(t)))
+			}
+			v := reflect.MakeMapWithSize(t, len(m.Fields))
+			for k, item := range m.Fields {
+				elem, err := toValue(item, t.Elem(), keyPath(path, k))
+				if err != nil {
+					return v, err
+				}
+				v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+			}
+			return v, nil
+		}
+	case reflect.Struct:
+		if m, ok := obj.(*MapObject); ok {
+			return toStruct(m, t, path)
+		}
+	}
+	return reflect.Value{}, mismatch(t, obj, path)
+}
+
+// Map fields without a struct field are an error, since they're most likely
+// a misspelling.
+func toStruct(m *MapObject, t reflect.Type, path string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	fields := structFields(t)
+	known := map[string]bool{}
+	for _, f := range fields {
+		known[f.name] = true
+		item, have := m.Fields[f.name]
+		if !have {
+			continue
+		}
+		elem, err := toValue(item, f.typ, fieldPath(path, f.name))
+		if err != nil {
+			return v, err
+		}
+		fv, err := fieldByIndexAlloc(v, f.index)
+		if err != nil {
+			return v, conversionError(fieldPath(path, f.name), err)
+		}
+		fv.Set(elem)
+	}
+	for _, k := range sortedFieldNames(m) {
+		if !known[k] {
+			return v, conversionError(path, fmt.Errorf("unknown field '%s' for %s", k, t))
+		}
+	}
+	if len(m.Fields) != m.Len() {
+		return v, conversionError(path, fmt.Errorf("%s can only take STRING keys", t))
+	}
+	return v, nil
+}
+
+func toInterface(obj Object, t reflect.Type, path string) (reflect.Value, error) {
+	var natural reflect.Type
+	switch obj.(type) {
+	case *Integer:
+		natural = reflect.TypeOf(int64(0))
+	case *String:
+		natural = reflect.TypeOf("")
+	case *BoolObject:
+		natural = reflect.TypeOf(false)
+	case *List:
+		natural = reflect.TypeOf([]interface{}{})
+	case *MapObject:
+		natural = reflect.TypeOf(map[string]interface{}{})
+	default:
+		// Opaque handle.
+		return reflect.ValueOf(obj).Convert(t), nil
+	}
+	v, err := toValue(obj, natural, path)
+	if err != nil {
+		return v, err
+	}
+	return v.Convert(t), nil
+}
+
+func mismatch(t reflect.Type, obj Object, path string) error {
+	return conversionError(path, &TypeMismatchError{Expected: TypeName(t), Received: obj.Type()})
+}
+
+// Struct fields ///////////////////////////////////////////////////////////////
+
+type structField struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+}
+
+// Lists the fields of a struct that are converted, including the fields of
+// untagged embedded structs. Outer fields hide promoted fields of the same
+// name.
+func structFields(t reflect.Type) []structField {
+	fields := []structField{}
+	seen := map[string]bool{}
+	var promoted []structField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("stitch")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct && !f.Type.Implements(objectInterface) {
+			for _, inner := range structFields(ft) {
+				inner.index = append([]int{i}, inner.index...)
+				promoted = append(promoted, inner)
+			}
+			continue
+		} else if f.PkgPath != "" { // Unexported
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		seen[name] = true
+		fields = append(fields, structField{
+			name:      name,
+			index:     []int{i},
+			typ:       f.Type,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+	for _, f := range promoted {
+		if !seen[f.name] {
+			seen[f.name] = true
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// FieldByIndex, without panicking on nil embedded struct pointers.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, true
+}
+
+// FieldByIndex, allocating nil embedded struct pointers along the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, fmt.Errorf("cannot set embedded pointer to unexported struct")
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, nil
+}
+
+func sortedFieldNames(m *MapObject) []string {
+	names := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Paths ///////////////////////////////////////////////////////////////////////
+
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+func keyPath(path, key string) string {
+	return fmt.Sprintf("%s[%q]", path, key)
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,105 @@
+package object
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testAddress struct {
+	Host string `stitch:"host"`
+	Port int    `stitch:"port,omitempty"`
+}
+
+type testAgent struct {
+	testAddress
+	Name      string            `stitch:"name"`
+	Community string            `stitch:"-"`
+	Tags      []string          `stitch:"tags"`
+	Labels    map[string]string `stitch:"labels,omitempty"`
+	Root      *Node             `stitch:"root,omitempty"`
+	hidden    bool
+}
+
+func Test_GoRoundTrip(t *testing.T) {
+	node := NewNode()
+	agent := testAgent{
+		testAddress: testAddress{Host: "10.0.0.1", Port: 161},
+		Name:        "core-1",
+		Community:   "secret",
+		Tags:        []string{"core", "dc1"},
+		Root:        node,
+	}
+
+	obj, err := FromGo(agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := obj.(*MapObject)
+	if !ok {
+		t.Fatalf("expected MAP, found %s", obj.Type())
+	}
+	expected := map[string]string{
+		"host": `"10.0.0.1"`,
+		"port": "161",
+		"name": `"core-1"`,
+		"tags": `["core", "dc1"]`,
+	}
+	for k, v := range expected {
+		if f, have := m.Fields[k]; !have || f.Inspect() != v {
+			t.Errorf("field '%s': expected %s, found %v", k, v, f)
+		}
+	}
+	if _, have := m.Fields["labels"]; have {
+		t.Errorf("empty field 'labels' should be omitted")
+	}
+	if _, have := m.Fields["Community"]; have {
+		t.Errorf("field 'Community' should be skipped")
+	}
+	if m.Fields["root"] != node {
+		t.Errorf("expected node to be passed as a handle")
+	}
+
+	var back testAgent
+	if err := ToGo(obj, &back); err != nil {
+		t.Fatal(err)
+	}
+	agent.Community = ""
+	if !reflect.DeepEqual(agent, back) {
+		t.Errorf("round trip mismatch:\n%+v\n%+v", agent, back)
+	}
+
+	var generic interface{}
+	if err := ToGo(obj, &generic); err != nil {
+		t.Fatal(err)
+	} else if g, ok := generic.(map[string]interface{}); !ok || g["port"] != int64(161) || g["root"] != node {
+		t.Errorf("unexpected generic value: %#v", generic)
+	}
+}
+
+func Test_ToGoErrors(t *testing.T) {
+	agent := &MapObject{Fields: map[string]Object{
+		"name": &String{Value: "core-1"},
+		"tags": &List{Contents: []Object{&String{Value: "a"}, &Integer{Value: 2}}},
+	}}
+	tests := []struct {
+		obj      Object
+		target   interface{}
+		expected string
+	}{
+		{agent, &testAgent{}, `tags[1]: type mismatch: expected STRING, received INTEGER`},
+		{&MapObject{Fields: map[string]Object{"nmae": &String{}}}, &testAgent{}, `unknown field 'nmae' for object.testAgent`},
+		{&Integer{Value: -1}, new(uint), `-1 overflows uint`},
+		{&Integer{Value: 300}, new(uint8), `300 overflows uint8`},
+		{&String{Value: "x"}, new(*Node), `type mismatch: expected NODE, received STRING`},
+		{&String{Value: "x"}, testAgent{}, `target must be a non-nil pointer, found object.testAgent`},
+	}
+	for i, test := range tests {
+		if err := ToGo(test.obj, test.target); err == nil || err.Error() != test.expected {
+			t.Errorf("[%d] expected error %q, found %v", i, test.expected, err)
+		}
+	}
+
+	if _, err := FromGo(map[string]interface{}{"a": []interface{}{1, "b"}}); err == nil || err.Error() != `["a"][1]: mixed types for list, INTEGER and STRING` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
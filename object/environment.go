@@ -1,14 +1,19 @@
 package object
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/rs/xid"
 )
 
+// ErrConstant is returned for assignments to a name bound with const.
+var ErrConstant = errors.New("cannot assign to constant")
+
 type Environment struct {
 	packages     map[string]*Package
 	store        map[string]Object
+	consts       map[string]bool
 	unboundNodes map[string]Object
 	parent       *Environment
 }
@@ -16,6 +21,7 @@ type Environment struct {
 func NewEnvironment() *Environment {
 	return &Environment{
 		store:        map[string]Object{},
+		consts:       map[string]bool{},
 		unboundNodes: map[string]Object{},
 		packages:     map[string]*Package{},
 		parent:       nil,
@@ -102,6 +108,48 @@ func (e *Environment) Put(name string, val Object) Object {
 	return retObj
 }
 
+// PutLocal binds the name in this scope, even if an enclosing scope has it,
+// as for loop variables.
+func (e *Environment) PutLocal(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// PutConst binds the name in this scope as a constant, which Assign then
+// refuses to change.
+func (e *Environment) PutConst(name string, val Object) error {
+	if err := e.Assign(name, val); err != nil {
+		return err
+	}
+	e.scopeOf(name).consts[name] = true
+	return nil
+}
+
+// Assign binds the name like Put does, unless the name is a constant.
+func (e *Environment) Assign(name string, val Object) error {
+	if e.IsConst(name) {
+		return fmt.Errorf("%w: %s", ErrConstant, name)
+	}
+	e.Put(name, val)
+	return nil
+}
+
+// IsConst reports whether the name, in the scope it's bound in, is a constant.
+func (e *Environment) IsConst(name string) bool {
+	scope := e.scopeOf(name)
+	return scope != nil && scope.consts[name]
+}
+
+// The scope the name is bound in, or nil.
+func (e *Environment) scopeOf(name string) *Environment {
+	if _, ok := e.store[name]; ok {
+		return e
+	} else if e.parent != nil {
+		return e.parent.scopeOf(name)
+	}
+	return nil
+}
+
 func (e *Environment) parent_get(name string) (Object, bool) {
 	if e.parent == nil {
 		return nil, false
@@ -112,8 +160,9 @@ func (e *Environment) parent_get(name string) (Object, bool) {
 
 // This function will track flow nodes, if our env is the global environment,
 // in order to allow us to wire up top-level nodes with no Input referenced.
+// Nodes the environment already holds, by name or not, aren't tracked again.
 func (e *Environment) PutUnboundNode(val Object) Object {
-	if val.Type() == NodeObjectType && e.IsGlobal() {
+	if val.Type() == NodeObjectType && e.IsGlobal() && !e.holds(val) {
 		name := "_unbound" + xid.New().String()
 		e.unboundNodes[name] = val
 		return val
@@ -121,6 +170,20 @@ func (e *Environment) PutUnboundNode(val Object) Object {
 	return nil
 }
 
+func (e *Environment) holds(val Object) bool {
+	for _, o := range e.store {
+		if o == val {
+			return true
+		}
+	}
+	for _, o := range e.unboundNodes {
+		if o == val {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *Environment) GetNames() []string {
 	names := []string{}
 	for k, _ := range e.store {
@@ -0,0 +1,460 @@
+package object
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nirosys/stitch/ast"
+)
+
+// Methods ////////////////////////////////////////////////////////////////////
+
+// Apply calls a function object for a method, so methods can take stitch
+// lambdas.
+type Apply func(fn Callable, args []Object) (Object, error)
+
+// Param is a parameter of a method. Parameters of FunctionObjectType take any
+// callable, and parameters without a type take anything.
+type Param struct {
+	Name string
+	Type ObjectType
+}
+
+// Method is a builtin method of lists, maps or strings, eg. `l.map(fn(x): x)`.
+type Method struct {
+	Name    string
+	Params  []Param
+	Returns ObjectType // Empty when it depends on the arguments.
+	Fn      func(apply Apply, receiver Object, args []Object) (Object, error)
+}
+
+var listMethods = map[string]*Method{}
+var mapMethods = map[string]*Method{}
+var stringMethods = map[string]*Method{}
+
+// Methods are added in init, since some of them refer to the tables.
+func init() {
+	for _, m := range []*Method{
+		{Name: "len", Returns: IntegerObjectType, Fn: listLen},
+		{Name: "map", Params: []Param{{"fn", FunctionObjectType}}, Returns: ListObjectType, Fn: listMap},
+		{Name: "filter", Params: []Param{{"fn", FunctionObjectType}}, Returns: ListObjectType, Fn: listFilter},
+		{Name: "reduce", Params: []Param{{"fn", FunctionObjectType}, {"initial", ""}}, Fn: listReduce},
+		{Name: "contains", Params: []Param{{"value", ""}}, Returns: BoolObjectType, Fn: listContains},
+		{Name: "join", Params: []Param{{"separator", StringObjectType}}, Returns: StringObjectType, Fn: listJoin},
+		{Name: "sort", Returns: ListObjectType, Fn: listSort},
+		{Name: "unique", Returns: ListObjectType, Fn: listUnique},
+	} {
+		listMethods[m.Name] = m
+	}
+	for _, m := range []*Method{
+		{Name: "len", Returns: IntegerObjectType, Fn: mapLen},
+		{Name: "keys", Returns: ListObjectType, Fn: mapKeys},
+		{Name: "values", Returns: ListObjectType, Fn: mapValues},
+		{Name: "contains", Params: []Param{{"key", ""}}, Returns: BoolObjectType, Fn: mapContains},
+		{Name: "delete", Params: []Param{{"key", ""}}, Returns: BoolObjectType, Fn: mapDelete},
+		{Name: "map", Params: []Param{{"fn", FunctionObjectType}}, Returns: ListObjectType, Fn: mapMap},
+		{Name: "filter", Params: []Param{{"fn", FunctionObjectType}}, Returns: MapObjectType, Fn: mapFilter},
+	} {
+		mapMethods[m.Name] = m
+	}
+	for _, m := range []*Method{
+		{Name: "len", Returns: IntegerObjectType, Fn: stringLen},
+		{Name: "contains", Params: []Param{{"substring", StringObjectType}}, Returns: BoolObjectType, Fn: stringContains},
+		{Name: "split", Params: []Param{{"separator", StringObjectType}}, Returns: ListObjectType, Fn: stringSplit},
+	} {
+		stringMethods[m.Name] = m
+	}
+}
+
+// MethodsOf returns the builtin methods of a type, by name.
+func MethodsOf(t ObjectType) map[string]*Method {
+	switch t {
+	case ListObjectType:
+		return listMethods
+	case MapObjectType:
+		return mapMethods
+	case StringObjectType:
+		return stringMethods
+	case OIDObjectType:
+		return oidMethods
+	case IPObjectType:
+		return ipMethods
+	case CIDRObjectType:
+		return cidrMethods
+	case RangeObjectType:
+		return rangeMethods
+	}
+	return nil
+}
+
+// BuiltinMethod //////////////////////////////////////////////////////////////
+
+// BuiltinMethod is a method bound to the object it was looked up on.
+type BuiltinMethod struct {
+	Method   *Method
+	Receiver Object
+}
+
+func (b *BuiltinMethod) Type() ObjectType { return MethodObjectType }
+func (b *BuiltinMethod) Inspect() string {
+	params := make([]string, 0, len(b.Method.Params))
+	for _, p := range b.Method.Params {
+		params = append(params, p.Name)
+	}
+	return fmt.Sprintf("%s.%s(%s)", b.Receiver.Type(), b.Method.Name, strings.Join(params, ", "))
+}
+
+func (b *BuiltinMethod) Identifier(name string) (Object, error) {
+	return nil, fmt.Errorf("'%s' not defined for method", name)
+}
+
+func (b *BuiltinMethod) FuncBody() ast.Expression {
+	return nil
+}
+
+func (b *BuiltinMethod) FuncParameters() []*ast.FunctionParameter {
+	params := make([]*ast.FunctionParameter, 0, len(b.Method.Params))
+	for _, p := range b.Method.Params {
+		params = append(params, &ast.FunctionParameter{Identifier: &ast.Identifier{Identifier: p.Name}})
+	}
+	return params
+}
+
+func (b *BuiltinMethod) Scope() *Environment {
+	return nil
+}
+
+// Call checks the arguments against the method's parameters, and calls it.
+func (b *BuiltinMethod) Call(apply Apply, args []Object) (Object, error) {
+	m := b.Method
+	if len(args) != len(m.Params) {
+		return nil, fmt.Errorf("%s expects %d arguments but found %d", m.Name, len(m.Params), len(args))
+	}
+	for i, p := range m.Params {
+		if args[i] == nil {
+			return nil, fmt.Errorf("%s expects a value for %s", m.Name, p.Name)
+		}
+		if p.Type == FunctionObjectType {
+			if _, ok := args[i].(Callable); !ok {
+				return nil, fmt.Errorf("%s expects a function for %s, found %s", m.Name, p.Name, args[i].Type())
+			}
+		} else if p.Type != "" && args[i].Type() != p.Type {
+			return nil, fmt.Errorf("%s expects %s for %s, found %s", m.Name, p.Type, p.Name, args[i].Type())
+		}
+	}
+	return m.Fn(apply, b.Receiver, args)
+}
+
+func methodOf(methods map[string]*Method, receiver Object, name string) (Object, bool) {
+	if m, ok := methods[name]; ok {
+		return &BuiltinMethod{Method: m, Receiver: receiver}, true
+	}
+	return nil, false
+}
+
+// Calls fn with args, making sure stitch functions take that many.
+func call(apply Apply, method string, fn Object, args ...Object) (Object, error) {
+	callable := fn.(Callable)
+	if f, ok := callable.(*Function); ok && len(f.Parameters) != len(args) {
+		return nil, fmt.Errorf("%s expects a function of %d arguments, found %d", method, len(args), len(f.Parameters))
+	}
+	return apply(callable, args)
+}
+
+// NewList makes a list of the objects, which must all be of the same type.
+func NewList(contents []Object) (*List, error) {
+	list := &List{InnerType: UnknownObjectType, Contents: contents}
+	for _, obj := range contents {
+		if list.InnerType == UnknownObjectType {
+			list.InnerType = obj.Type()
+		} else if list.InnerType != obj.Type() {
+			return nil, fmt.Errorf("mixed types for list, %s and %s", list.InnerType, obj.Type())
+		}
+	}
+	return list, nil
+}
+
+// NewMixedList makes a list of objects that can be of different types, such
+// as the keys of a map. Its InnerType is UnknownObjectType unless they're all
+// the same type.
+func NewMixedList(contents []Object) *List {
+	if list, err := NewList(contents); err == nil {
+		return list
+	}
+	return &List{InnerType: UnknownObjectType, Contents: contents}
+}
+
+// Equal reports whether two objects are equal. Objects of different types are
+// never equal.
+func Equal(left, right Object) (bool, error) {
+	if left == nil || right == nil || left == None || right == None {
+		return left == right, nil
+	}
+	// Ranges are equal to the lists they stand for.
+	if r, ok := left.(*Range); ok {
+		return rangeEqual(r, right)
+	} else if r, ok := right.(*Range); ok {
+		return rangeEqual(r, left)
+	}
+	if l, ok := left.(*List); ok {
+		r, ok := right.(*List)
+		if !ok || len(l.Contents) != len(r.Contents) {
+			return false, nil
+		}
+		for i := range l.Contents {
+			if equal, err := Equal(l.Contents[i], r.Contents[i]); err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	l, lok := left.(Comparable)
+	r, rok := right.(Comparable)
+	if !lok || !rok || !l.IsComparable(r) {
+		return false, nil
+	}
+	return l.Equals(r)
+}
+
+// Compares a range to another range, or a list, an integer at a time.
+func rangeEqual(r *Range, other Object) (bool, error) {
+	n := r.Len()
+	switch t := other.(type) {
+	case *Range:
+		if n != t.Len() {
+			return false, nil
+		}
+		return n == 0 || (r.Start == t.Start && (n == 1 || r.Step == t.Step)), nil
+	case *List:
+		if n != int64(len(t.Contents)) {
+			return false, nil
+		}
+		for i := int64(0); i < n; i++ {
+			if equal, err := Equal(r.At(i), t.Contents[i]); err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// List methods ///////////////////////////////////////////////////////////////
+
+func listLen(apply Apply, receiver Object, args []Object) (Object, error) {
+	return &Integer{Value: int64(len(receiver.(*List).Contents))}, nil
+}
+
+func listMap(apply Apply, receiver Object, args []Object) (Object, error) {
+	results := []Object{}
+	for _, obj := range receiver.(*List).Contents {
+		if result, err := call(apply, "map", args[0], obj); err != nil {
+			return nil, err
+		} else if result == nil {
+			return nil, fmt.Errorf("map function returned nothing for %s", obj.Inspect())
+		} else {
+			results = append(results, result)
+		}
+	}
+	return NewList(results)
+}
+
+func listFilter(apply Apply, receiver Object, args []Object) (Object, error) {
+	list := receiver.(*List)
+	results := &List{InnerType: list.InnerType, Contents: []Object{}}
+	for _, obj := range list.Contents {
+		if keep, err := predicate(apply, "filter", args[0], obj); err != nil {
+			return nil, err
+		} else if keep {
+			results.Contents = append(results.Contents, obj)
+		}
+	}
+	return results, nil
+}
+
+func predicate(apply Apply, method string, fn Object, args ...Object) (bool, error) {
+	result, err := call(apply, method, fn, args...)
+	if err != nil {
+		return false, err
+	}
+	if b, ok := result.(*BoolObject); ok {
+		return bool(*b), nil
+	}
+	return false, fmt.Errorf("%s function must return BOOL, found %s", method, typeName(result))
+}
+
+func listReduce(apply Apply, receiver Object, args []Object) (Object, error) {
+	acc := args[1]
+	for _, obj := range receiver.(*List).Contents {
+		if result, err := call(apply, "reduce", args[0], acc, obj); err != nil {
+			return nil, err
+		} else if result == nil {
+			return nil, fmt.Errorf("reduce function returned nothing for %s", obj.Inspect())
+		} else {
+			acc = result
+		}
+	}
+	return acc, nil
+}
+
+func listContains(apply Apply, receiver Object, args []Object) (Object, error) {
+	for _, obj := range receiver.(*List).Contents {
+		if equal, err := Equal(obj, args[0]); err != nil {
+			return nil, err
+		} else if equal {
+			return NewBoolObject(true), nil
+		}
+	}
+	return NewBoolObject(false), nil
+}
+
+// Strings are joined by value, anything else as it's inspected.
+func listJoin(apply Apply, receiver Object, args []Object) (Object, error) {
+	items := []string{}
+	for _, obj := range receiver.(*List).Contents {
+		if s, ok := obj.(*String); ok {
+			items = append(items, s.Value)
+		} else {
+			items = append(items, obj.Inspect())
+		}
+	}
+	return &String{Value: strings.Join(items, args[0].(*String).Value)}, nil
+}
+
+// Lists of integers or strings can be sorted.
+func listSort(apply Apply, receiver Object, args []Object) (Object, error) {
+	list := receiver.(*List)
+	contents := make([]Object, len(list.Contents))
+	copy(contents, list.Contents)
+
+	switch list.InnerType {
+	case IntegerObjectType:
+		sort.SliceStable(contents, func(i, j int) bool {
+			return contents[i].(*Integer).Value < contents[j].(*Integer).Value
+		})
+	case StringObjectType:
+		sort.SliceStable(contents, func(i, j int) bool {
+			return contents[i].(*String).Value < contents[j].(*String).Value
+		})
+	case UnknownObjectType:
+		if len(contents) > 1 {
+			return nil, fmt.Errorf("cannot sort a list of mixed types")
+		}
+	default:
+		if len(contents) > 1 {
+			return nil, fmt.Errorf("cannot sort a list of %s", list.InnerType)
+		}
+	}
+	return &List{InnerType: list.InnerType, Contents: contents}, nil
+}
+
+// Keeps the first of each equal element, in order.
+func listUnique(apply Apply, receiver Object, args []Object) (Object, error) {
+	list := receiver.(*List)
+	results := &List{InnerType: list.InnerType, Contents: []Object{}}
+	for _, obj := range list.Contents {
+		if seen, err := listContains(apply, results, []Object{obj}); err != nil {
+			return nil, err
+		} else if !bool(*seen.(*BoolObject)) {
+			results.Contents = append(results.Contents, obj)
+		}
+	}
+	return results, nil
+}
+
+// Map methods ////////////////////////////////////////////////////////////////
+
+func mapLen(apply Apply, receiver Object, args []Object) (Object, error) {
+	return &Integer{Value: int64(receiver.(*MapObject).Len())}, nil
+}
+
+// Keys can be strings and integers, and values anything, so both can be mixed
+// lists.
+func mapKeys(apply Apply, receiver Object, args []Object) (Object, error) {
+	return NewMixedList(receiver.(*MapObject).KeyObjects()), nil
+}
+
+func mapValues(apply Apply, receiver Object, args []Object) (Object, error) {
+	m := receiver.(*MapObject)
+	values := []Object{}
+	for _, k := range m.KeyObjects() {
+		v, _, _ := m.Get(k)
+		values = append(values, v)
+	}
+	return NewMixedList(values), nil
+}
+
+// Keys are looked up the same way as indexing does.
+func mapContains(apply Apply, receiver Object, args []Object) (Object, error) {
+	if _, have, err := receiver.(*MapObject).Get(args[0]); err != nil {
+		return nil, err
+	} else {
+		return NewBoolObject(have), nil
+	}
+}
+
+// Removes the key from the map, reporting whether it was there.
+func mapDelete(apply Apply, receiver Object, args []Object) (Object, error) {
+	if have, err := receiver.(*MapObject).Remove(args[0]); err != nil {
+		return nil, err
+	} else {
+		return NewBoolObject(have), nil
+	}
+}
+
+// The function is called with each key and value.
+func mapMap(apply Apply, receiver Object, args []Object) (Object, error) {
+	m := receiver.(*MapObject)
+	results := []Object{}
+	for _, k := range m.KeyObjects() {
+		v, _, _ := m.Get(k)
+		if result, err := call(apply, "map", args[0], k, v); err != nil {
+			return nil, err
+		} else if result == nil {
+			return nil, fmt.Errorf("map function returned nothing for %s", k.Inspect())
+		} else {
+			results = append(results, result)
+		}
+	}
+	return NewList(results)
+}
+
+func mapFilter(apply Apply, receiver Object, args []Object) (Object, error) {
+	m := receiver.(*MapObject)
+	results := NewMap()
+	for _, k := range m.KeyObjects() {
+		v, _, _ := m.Get(k)
+		if keep, err := predicate(apply, "filter", args[0], k, v); err != nil {
+			return nil, err
+		} else if keep {
+			_ = results.Put(k, v)
+		}
+	}
+	return results, nil
+}
+
+// String methods /////////////////////////////////////////////////////////////
+
+func stringLen(apply Apply, receiver Object, args []Object) (Object, error) {
+	return &Integer{Value: int64(len([]rune(receiver.(*String).Value)))}, nil
+}
+
+func stringContains(apply Apply, receiver Object, args []Object) (Object, error) {
+	return NewBoolObject(strings.Contains(receiver.(*String).Value, args[0].(*String).Value)), nil
+}
+
+func stringSplit(apply Apply, receiver Object, args []Object) (Object, error) {
+	parts := []Object{}
+	for _, s := range strings.Split(receiver.(*String).Value, args[0].(*String).Value) {
+		parts = append(parts, &String{Value: s})
+	}
+	return NewList(parts)
+}
+
+func typeName(obj Object) ObjectType {
+	if obj == nil {
+		return "nothing"
+	}
+	return obj.Type()
+}
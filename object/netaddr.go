@@ -0,0 +1,353 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var oidMethods = map[string]*Method{}
+var ipMethods = map[string]*Method{}
+var cidrMethods = map[string]*Method{}
+
+func init() {
+	for _, m := range []*Method{
+		{Name: "len", Returns: IntegerObjectType, Fn: oidLen},
+		{Name: "append", Params: []Param{{"suffix", ""}}, Returns: OIDObjectType, Fn: oidAppend},
+		{Name: "startswith", Params: []Param{{"prefix", ""}}, Returns: BoolObjectType, Fn: oidStartsWith},
+		{Name: "index", Params: []Param{{"prefix", ""}}, Returns: OIDObjectType, Fn: oidIndex},
+	} {
+		oidMethods[m.Name] = m
+	}
+	for _, m := range []*Method{
+		{Name: "version", Returns: IntegerObjectType, Fn: ipVersion},
+	} {
+		ipMethods[m.Name] = m
+	}
+	for _, m := range []*Method{
+		{Name: "len", Returns: IntegerObjectType, Fn: cidrLen},
+		{Name: "contains", Params: []Param{{"ip", ""}}, Returns: BoolObjectType, Fn: cidrContains},
+		{Name: "network", Returns: IPObjectType, Fn: cidrNetwork},
+		{Name: "prefix", Returns: IntegerObjectType, Fn: cidrPrefix},
+	} {
+		cidrMethods[m.Name] = m
+	}
+}
+
+// OID ////////////////////////////////////////////////////////////////////////
+
+// OID is an SNMP object identifier, eg. 1.3.6.1.2.1.2.2.1.10.
+type OID struct {
+	Arcs []uint32
+}
+
+// NewOID parses the dotted form of an OID. A leading dot is allowed, as
+// net-snmp writes them.
+func NewOID(s string) (*OID, error) {
+	text := strings.TrimPrefix(s, ".")
+	if text == "" {
+		return nil, fmt.Errorf("invalid OID '%s'", s)
+	}
+	parts := strings.Split(text, ".")
+	arcs := make([]uint32, 0, len(parts))
+	for _, p := range parts {
+		arc, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID '%s', '%s' is not an arc", s, p)
+		}
+		arcs = append(arcs, uint32(arc))
+	}
+	return &OID{Arcs: arcs}, nil
+}
+
+func (o *OID) Type() ObjectType { return OIDObjectType }
+
+func (o *OID) Inspect() string {
+	parts := make([]string, 0, len(o.Arcs))
+	for _, arc := range o.Arcs {
+		parts = append(parts, strconv.FormatUint(uint64(arc), 10))
+	}
+	return strings.Join(parts, ".")
+}
+
+func (o *OID) Identifier(name string) (Object, error) {
+	if m, ok := methodOf(oidMethods, o, name); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("'%s' not defined for OID", name)
+}
+
+// HasPrefix reports whether the OID is prefix, or below it in the tree.
+func (o *OID) HasPrefix(prefix *OID) bool {
+	if len(prefix.Arcs) > len(o.Arcs) {
+		return false
+	}
+	for i, arc := range prefix.Arcs {
+		if o.Arcs[i] != arc {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *OID) IsComparable(other Comparable) bool {
+	_, ok := other.(*OID)
+	return ok
+}
+
+// OIDs are ordered arc by arc, as a walk visits them.
+func (o *OID) Equals(other Comparable) (bool, error) {
+	c, err := o.compare(other)
+	return c == 0, err
+}
+func (o *OID) GreaterThan(other Comparable) (bool, error) {
+	c, err := o.compare(other)
+	return c > 0, err
+}
+func (o *OID) LessThan(other Comparable) (bool, error) {
+	c, err := o.compare(other)
+	return c < 0, err
+}
+
+func (o *OID) compare(other Comparable) (int, error) {
+	that, ok := other.(*OID)
+	if !ok {
+		return 0, fmt.Errorf("cannot compare an OID against %s", other.Type())
+	}
+	for i := 0; i < len(o.Arcs) && i < len(that.Arcs); i++ {
+		if o.Arcs[i] < that.Arcs[i] {
+			return -1, nil
+		} else if o.Arcs[i] > that.Arcs[i] {
+			return 1, nil
+		}
+	}
+	return len(o.Arcs) - len(that.Arcs), nil
+}
+
+// Takes an OID, or its dotted form, as an argument of an OID method.
+func oidOf(method string, obj Object) (*OID, error) {
+	switch t := obj.(type) {
+	case *OID:
+		return t, nil
+	case *String:
+		return NewOID(t.Value)
+	case *Integer:
+		if t.Value < 0 || t.Value > 0xffffffff {
+			return nil, fmt.Errorf("%d is not an OID arc", t.Value)
+		}
+		return &OID{Arcs: []uint32{uint32(t.Value)}}, nil
+	}
+	return nil, fmt.Errorf("%s expects an OID, found %s", method, obj.Type())
+}
+
+func oidLen(apply Apply, receiver Object, args []Object) (Object, error) {
+	return &Integer{Value: int64(len(receiver.(*OID).Arcs))}, nil
+}
+
+// Appending gives a new OID, the receiver is left as it was.
+func oidAppend(apply Apply, receiver Object, args []Object) (Object, error) {
+	suffix, err := oidOf("append", args[0])
+	if err != nil {
+		return nil, err
+	}
+	o := receiver.(*OID)
+	arcs := make([]uint32, 0, len(o.Arcs)+len(suffix.Arcs))
+	arcs = append(append(arcs, o.Arcs...), suffix.Arcs...)
+	return &OID{Arcs: arcs}, nil
+}
+
+func oidStartsWith(apply Apply, receiver Object, args []Object) (Object, error) {
+	if prefix, err := oidOf("startswith", args[0]); err != nil {
+		return nil, err
+	} else {
+		return NewBoolObject(receiver.(*OID).HasPrefix(prefix)), nil
+	}
+}
+
+// The index of a table entry is what follows the column's OID, eg. the
+// ifIndex 5 of 1.3.6.1.2.1.2.2.1.10.5.
+func oidIndex(apply Apply, receiver Object, args []Object) (Object, error) {
+	o := receiver.(*OID)
+	if prefix, err := oidOf("index", args[0]); err != nil {
+		return nil, err
+	} else if !o.HasPrefix(prefix) || len(prefix.Arcs) == len(o.Arcs) {
+		return nil, fmt.Errorf("%s is not below %s", o.Inspect(), prefix.Inspect())
+	} else {
+		return &OID{Arcs: append([]uint32{}, o.Arcs[len(prefix.Arcs):]...)}, nil
+	}
+}
+
+// IP /////////////////////////////////////////////////////////////////////////
+
+// IP is an IPv4 or IPv6 address.
+type IP struct {
+	Value net.IP
+}
+
+func NewIP(s string) (*IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address '%s'", s)
+	}
+	return &IP{Value: ip}, nil
+}
+
+func (i *IP) Type() ObjectType { return IPObjectType }
+func (i *IP) Inspect() string  { return i.Value.String() }
+
+func (i *IP) Identifier(name string) (Object, error) {
+	if m, ok := methodOf(ipMethods, i, name); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("'%s' not defined for IP", name)
+}
+
+func (i *IP) IsComparable(other Comparable) bool {
+	_, ok := other.(*IP)
+	return ok
+}
+
+// Addresses are ordered numerically, with IPv4 addresses before IPv6 ones.
+func (i *IP) Equals(other Comparable) (bool, error) {
+	c, err := i.compare(other)
+	return c == 0, err
+}
+func (i *IP) GreaterThan(other Comparable) (bool, error) {
+	c, err := i.compare(other)
+	return c > 0, err
+}
+func (i *IP) LessThan(other Comparable) (bool, error) {
+	c, err := i.compare(other)
+	return c < 0, err
+}
+
+func (i *IP) compare(other Comparable) (int, error) {
+	that, ok := other.(*IP)
+	if !ok {
+		return 0, fmt.Errorf("cannot compare an IP against %s", other.Type())
+	}
+	a, b := i.Value.To4(), that.Value.To4()
+	if a == nil || b == nil {
+		a, b = i.Value.To16(), that.Value.To16()
+		if i.Value.To4() != nil && that.Value.To4() == nil {
+			return -1, nil
+		} else if i.Value.To4() == nil && that.Value.To4() != nil {
+			return 1, nil
+		}
+	}
+	return bytes.Compare(a, b), nil
+}
+
+func ipVersion(apply Apply, receiver Object, args []Object) (Object, error) {
+	if receiver.(*IP).Value.To4() != nil {
+		return &Integer{Value: 4}, nil
+	}
+	return &Integer{Value: 6}, nil
+}
+
+// CIDR ///////////////////////////////////////////////////////////////////////
+
+// CIDR is a network prefix, eg. 10.0.0.0/24. Looping over a CIDR gives each of
+// its addresses, worked out as they're needed.
+type CIDR struct {
+	Value *net.IPNet
+}
+
+// NewCIDR parses a prefix. Host bits are dropped, so 10.0.0.5/24 is
+// 10.0.0.0/24.
+func NewCIDR(s string) (*CIDR, error) {
+	_, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR '%s'", s)
+	}
+	return &CIDR{Value: ipnet}, nil
+}
+
+func (c *CIDR) Type() ObjectType { return CIDRObjectType }
+func (c *CIDR) Inspect() string  { return c.Value.String() }
+
+func (c *CIDR) Identifier(name string) (Object, error) {
+	if m, ok := methodOf(cidrMethods, c, name); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("'%s' not defined for CIDR", name)
+}
+
+// Len is the number of addresses in the prefix. Prefixes too large to count,
+// like most IPv6 ones, are an error.
+func (c *CIDR) Len() (int64, error) {
+	ones, bits := c.Value.Mask.Size()
+	if bits-ones > 62 {
+		return 0, fmt.Errorf("%s has too many addresses to count", c.Inspect())
+	}
+	return int64(1) << uint(bits-ones), nil
+}
+
+// At is the i'th address of the prefix, for i below Len.
+func (c *CIDR) At(i int64) *IP {
+	ip := append(net.IP{}, c.Value.IP...)
+	for b := len(ip) - 1; b >= 0 && i > 0; b-- {
+		sum := int64(ip[b]) + i&0xff
+		ip[b] = byte(sum)
+		i = i>>8 + sum>>8
+	}
+	return &IP{Value: ip}
+}
+
+func (c *CIDR) IsComparable(other Comparable) bool {
+	_, ok := other.(*CIDR)
+	return ok
+}
+
+func (c *CIDR) Equals(other Comparable) (bool, error) {
+	that, ok := other.(*CIDR)
+	if !ok {
+		return false, fmt.Errorf("unable to compare CIDR against type: %s", other.Type())
+	}
+	return c.Inspect() == that.Inspect(), nil
+}
+
+func (c *CIDR) GreaterThan(other Comparable) (bool, error) {
+	return false, fmt.Errorf("cannot compare CIDR relatively")
+}
+
+func (c *CIDR) LessThan(other Comparable) (bool, error) {
+	return false, fmt.Errorf("cannot compare CIDR relatively")
+}
+
+func cidrLen(apply Apply, receiver Object, args []Object) (Object, error) {
+	if n, err := receiver.(*CIDR).Len(); err != nil {
+		return nil, err
+	} else {
+		return &Integer{Value: n}, nil
+	}
+}
+
+// Contains takes an address, or its text.
+func cidrContains(apply Apply, receiver Object, args []Object) (Object, error) {
+	var ip *IP
+	switch t := args[0].(type) {
+	case *IP:
+		ip = t
+	case *String:
+		parsed, err := NewIP(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		ip = parsed
+	default:
+		return nil, fmt.Errorf("contains expects an IP, found %s", args[0].Type())
+	}
+	return NewBoolObject(receiver.(*CIDR).Value.Contains(ip.Value)), nil
+}
+
+func cidrNetwork(apply Apply, receiver Object, args []Object) (Object, error) {
+	return &IP{Value: receiver.(*CIDR).Value.IP}, nil
+}
+
+func cidrPrefix(apply Apply, receiver Object, args []Object) (Object, error) {
+	ones, _ := receiver.(*CIDR).Value.Mask.Size()
+	return &Integer{Value: int64(ones)}, nil
+}
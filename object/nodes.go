@@ -3,9 +3,11 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/lexing"
 )
 
 // NodeType ///////////////////////////////////////////////////////////////////
@@ -14,6 +16,7 @@ type NodeType struct {
 	NodeArgs    []*ast.FunctionParameter // Argument names
 	InputSlots  []string                 // Input Slot Names
 	OutputSlots []string                 // Output Slot Names
+	Defaults    map[string]Object        // Values for arguments that are left off
 
 	// For user supplied node types
 	Body *ast.BlockExpression
@@ -48,6 +51,18 @@ func (n *NodeType) Arguments() []*ast.FunctionParameter {
 }
 
 func (n *NodeType) Construct(args []Object) (Object, error) {
+	if len(args) > len(n.NodeArgs) {
+		return nil, fmt.Errorf("expected %d arguments but found %d", len(n.NodeArgs), len(args))
+	}
+	// Trailing arguments can be left off when they have a default.
+	for _, param := range n.NodeArgs[len(args):] {
+		if def, ok := n.Defaults[param.Identifier.String()]; ok {
+			args = append(args, def)
+		} else {
+			return nil, fmt.Errorf("expected %d arguments but found %d", len(n.NodeArgs), len(args))
+		}
+	}
+
 	node := NewNode()
 	node.NodeType = n
 	node.Arguments = args
@@ -68,6 +83,7 @@ type Node struct {
 	OutputSlots map[string]struct{}
 	TagName     *string
 	FieldName   *string
+	Position    lexing.Position // Where the node was constructed in source.
 
 	connections map[string][]*NodeSlot
 }
@@ -81,11 +97,26 @@ func NewNode() *Node {
 	}
 }
 
+// GetConnections returns the node's connections ordered by the slot they
+// start from, so graphs compile the same way every time.
 func (f *Node) GetConnections() []*Connection {
+	names := make([]string, 0, len(f.connections))
+	for n := range f.connections {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
 	conns := []*Connection{}
-	for n, slots := range f.connections {
+	for _, n := range names {
+		slots := f.connections[n]
+		start := f.GetSlot(n)
+		if start == nil {
+			// Connected from a slot the node doesn't declare, which is left
+			// for validation to report.
+			start = &NodeSlot{Name: n, Node: f}
+		}
 		for _, slot := range slots {
-			conns = append(conns, NewConnection(f.GetSlot(n), slot))
+			conns = append(conns, NewConnection(start, slot))
 		}
 	}
 	return conns
@@ -162,6 +193,33 @@ func (f *Node) ConnectSlots(mine string, otherSlot *NodeSlot) (Object, error) {
 	return otherSlot.Node, nil
 }
 
+// IsConnected reports whether one of this node's slots is connected to the
+// given slot.
+func (f *Node) IsConnected(mine string, otherSlot *NodeSlot) bool {
+	for _, s := range f.connections[mine] {
+		if s.Node == otherSlot.Node && s.Name == otherSlot.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Disconnect removes the connection between one of this node's slots and the
+// given slot, returning false if they weren't connected.
+func (f *Node) Disconnect(mine string, otherSlot *NodeSlot) bool {
+	slots := f.connections[mine]
+	for i, s := range slots {
+		if s.Node == otherSlot.Node && s.Name == otherSlot.Name {
+			f.connections[mine] = append(slots[:i:i], slots[i+1:]...)
+			if len(f.connections[mine]) == 0 {
+				delete(f.connections, mine)
+			}
+			return true
+		}
+	}
+	return false
+}
+
 func (f *Node) Connect(other Connectable) (Object, error) {
 	var otherSlot *NodeSlot
 	switch t := other.(type) {
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/lexing"
 )
 
 type ObjectType string
@@ -25,6 +26,13 @@ const (
 	BoolObjectType     = "BOOL"
 	ModifierObjectType = "MODIFIER"
 	MapObjectType      = "MAP"
+	ErrorObjectType    = "ERROR"
+	MethodObjectType   = "METHOD"
+	RangeObjectType    = "RANGE"
+	NoneObjectType     = "NONE"
+	OIDObjectType      = "OID"
+	IPObjectType       = "IP"
+	CIDRObjectType     = "CIDR"
 )
 
 func (t ObjectType) IsPrimitive() bool {
@@ -64,6 +72,33 @@ func (p *Package) Identifier(name string) (Object, error) {
 	}
 }
 
+// Error //////////////////////////////////////////////////////////////////////
+
+// Error is an error caught by a try expression. Its fields are message, line
+// and column.
+type Error struct {
+	Message  string
+	Position lexing.Position
+}
+
+func (e *Error) Type() ObjectType { return ErrorObjectType }
+func (e *Error) Inspect() string {
+	return fmt.Sprintf("error(%q)", e.Message)
+}
+
+func (e *Error) Identifier(name string) (Object, error) {
+	switch name {
+	case "message":
+		return &String{Value: e.Message}, nil
+	case "line":
+		return &Integer{Value: int64(e.Position.Line)}, nil
+	case "column":
+		return &Integer{Value: int64(e.Position.Column)}, nil
+	default:
+		return nil, fmt.Errorf("'%s' not defined for error", name)
+	}
+}
+
 // Connection /////////////////////////////////////////////////////////////////
 type Connection struct {
 	Start *NodeSlot
@@ -120,9 +155,9 @@ func (f *Function) Scope() *Environment {
 // InternalObject /////////////////////////////////////////////////////////////
 
 type NativeFunction struct {
-	Fn     func(env *Environment, args []Object) (Object, error)
-	Params []*ast.FunctionParameter
-	//Params []object.ObjectType
+	Fn       func(env *Environment, args []Object) (Object, error)
+	Params   []*ast.FunctionParameter
+	Variadic bool // The last parameter takes any number of arguments.
 }
 
 type InternalFunction struct {
@@ -132,7 +167,6 @@ type InternalFunction struct {
 
 func (i *InternalFunction) Type() ObjectType { return InternalObjectType }
 func (i *InternalFunction) Inspect() string {
-	fmt.Printf("Internal: %#v\n", i)
 	var buffer bytes.Buffer
 	buffer.WriteString("fn (")
 	params := make([]string, 0, len(i.Fn.Params))
@@ -182,6 +216,9 @@ func (l *List) Inspect() string {
 }
 
 func (l *List) Identifier(name string) (Object, error) {
+	if m, ok := methodOf(listMethods, l, name); ok {
+		return m, nil
+	}
 	return nil, fmt.Errorf("'%s' not defined for list", name)
 }
 
@@ -203,6 +240,13 @@ func (l *List) Connect(other Connectable) (Object, error) {
 }
 
 func (l *List) Add(other Object) (Object, error) {
+	if r, ok := other.(*Range); ok {
+		rl, err := r.List()
+		if err != nil {
+			return nil, err
+		}
+		other = rl
+	}
 	if t, ok := other.(*List); ok {
 		ret := &List{InnerType: l.InnerType}
 
@@ -230,17 +274,17 @@ func (l *List) Add(other Object) (Object, error) {
 }
 
 func (l *List) Subtract(other Object) (Object, error) {
-	return nil, nil
+	return nil, fmt.Errorf("operator '-' not defined for LIST")
 }
 
 func (l *List) Multiply(other Object) (Object, error) {
-	return nil, nil
+	return nil, fmt.Errorf("operator '*' not defined for LIST")
 }
 
 func (l *List) Divide(other Object) (Object, error) {
-	return nil, nil
+	return nil, fmt.Errorf("operator '/' not defined for LIST")
 }
 
 func (l *List) Modulus(other Object) (Object, error) {
-	return nil, nil
+	return nil, fmt.Errorf("operator '%%' not defined for LIST")
 }
@@ -2,7 +2,10 @@ package object
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Integer ////////////////////////////////////////////////////////////////////
@@ -52,6 +55,9 @@ func (i *Integer) Divide(other Object) (Object, error) {
 		return nil, fmt.Errorf("type mis-match")
 	}
 	o := other.(*Integer)
+	if o.Value == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
 	return &Integer{Value: i.Value / o.Value}, nil
 }
 
@@ -60,9 +66,82 @@ func (i *Integer) Modulus(other Object) (Object, error) {
 		return nil, fmt.Errorf("type mis-match")
 	}
 	o := other.(*Integer)
+	if o.Value == 0 {
+		return nil, fmt.Errorf("modulus by zero")
+	}
 	return &Integer{Value: i.Value % o.Value}, nil
 }
 
+func (i *Integer) And(other Object) (Object, error) {
+	if o, ok := other.(*Integer); !ok {
+		return nil, fmt.Errorf("type mis-match")
+	} else {
+		return &Integer{Value: i.Value & o.Value}, nil
+	}
+}
+
+func (i *Integer) Or(other Object) (Object, error) {
+	if o, ok := other.(*Integer); !ok {
+		return nil, fmt.Errorf("type mis-match")
+	} else {
+		return &Integer{Value: i.Value | o.Value}, nil
+	}
+}
+
+func (i *Integer) Xor(other Object) (Object, error) {
+	if o, ok := other.(*Integer); !ok {
+		return nil, fmt.Errorf("type mis-match")
+	} else {
+		return &Integer{Value: i.Value ^ o.Value}, nil
+	}
+}
+
+// Shifting by 64 or more leaves nothing of the value, and shifting right keeps
+// the sign.
+func (i *Integer) ShiftLeft(other Object) (Object, error) {
+	if n, err := shiftCount(other); err != nil {
+		return nil, err
+	} else {
+		return &Integer{Value: i.Value << n}, nil
+	}
+}
+
+func (i *Integer) ShiftRight(other Object) (Object, error) {
+	if n, err := shiftCount(other); err != nil {
+		return nil, err
+	} else {
+		return &Integer{Value: i.Value >> n}, nil
+	}
+}
+
+func shiftCount(other Object) (uint64, error) {
+	o, ok := other.(*Integer)
+	if !ok {
+		return 0, fmt.Errorf("type mis-match")
+	} else if o.Value < 0 {
+		return 0, fmt.Errorf("negative shift count %d", o.Value)
+	}
+	return uint64(o.Value), nil
+}
+
+// Powers wrap around like the other operators, so 2 ** 64 is 0.
+func (i *Integer) Power(other Object) (Object, error) {
+	o, ok := other.(*Integer)
+	if !ok {
+		return nil, fmt.Errorf("type mis-match")
+	} else if o.Value < 0 {
+		return nil, fmt.Errorf("negative exponent %d", o.Value)
+	}
+	result, base := int64(1), i.Value
+	for exp := o.Value; exp > 0; exp >>= 1 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+	}
+	return &Integer{Value: result}, nil
+}
+
 func (i *Integer) IsComparable(other Comparable) bool {
 	_, ok := other.(*Integer)
 	return ok
@@ -108,13 +187,79 @@ func (s *String) Type() ObjectType {
 }
 
 func (s *String) Inspect() string {
-	return fmt.Sprintf("\"%s\"", s.Value)
+	return strconv.Quote(s.Value)
 }
 
 func (i *String) Identifier(name string) (Object, error) {
+	if m, ok := methodOf(stringMethods, i, name); ok {
+		return m, nil
+	}
 	return nil, fmt.Errorf("'%s' not defined for String", name)
 }
 
+// Strings add to strings, and to integers by their text.
+func (s *String) Add(other Object) (Object, error) {
+	switch o := other.(type) {
+	case *String:
+		return &String{Value: s.Value + o.Value}, nil
+	case *Integer:
+		return &String{Value: s.Value + o.Inspect()}, nil
+	}
+	return nil, fmt.Errorf("cannot add %s to a string", other.Type())
+}
+
+func (s *String) Subtract(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '-' not defined for STRING")
+}
+
+func (s *String) Multiply(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '*' not defined for STRING")
+}
+
+func (s *String) Divide(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '/' not defined for STRING")
+}
+
+func (s *String) Modulus(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '%%' not defined for STRING")
+}
+
+func (s *String) IsComparable(other Comparable) bool {
+	_, ok := other.(*String)
+	return ok
+}
+
+// Strings are ordered byte by byte, so "Z" comes before "a".
+func (s *String) Equals(other Comparable) (bool, error) {
+	if v, err := getStringValue(other); err != nil {
+		return false, err
+	} else {
+		return s.Value == v, nil
+	}
+}
+func (s *String) GreaterThan(other Comparable) (bool, error) {
+	if v, err := getStringValue(other); err != nil {
+		return false, err
+	} else {
+		return s.Value > v, nil
+	}
+}
+func (s *String) LessThan(other Comparable) (bool, error) {
+	if v, err := getStringValue(other); err != nil {
+		return false, err
+	} else {
+		return s.Value < v, nil
+	}
+}
+
+func getStringValue(c Comparable) (string, error) {
+	if that, ok := c.(*String); ok {
+		return that.Value, nil
+	} else {
+		return "", fmt.Errorf("cannot compare a string against %s", c.Type())
+	}
+}
+
 // NodeSlot ///////////////////////////////////////////////////////////////////
 type NodeSlot struct {
 	Name    string
@@ -161,8 +306,19 @@ func (n *NodeSlot) Connect(obj Connectable) (Object, error) {
 }
 
 // MapObject //////////////////////////////////////////////////////////////////
+
+// MapObject keeps its keys in the order they were added, so maps inspect and
+// iterate the same way every time. Keys are strings or integers, and 1 and "1"
+// are different keys. Fields holds the string keys, and can be written
+// directly, but keys added that way come after the ordered ones, sorted.
 type MapObject struct {
 	Fields map[string]Object
+	ints   map[int64]Object
+	order  []Object
+}
+
+func NewMap() *MapObject {
+	return &MapObject{Fields: map[string]Object{}}
 }
 
 func (m *MapObject) Type() ObjectType {
@@ -172,9 +328,14 @@ func (m *MapObject) Type() ObjectType {
 func (m *MapObject) Inspect() string {
 	var buff strings.Builder
 	buff.WriteByte('{')
-	for k, v := range m.Fields {
-		buff.WriteString(k)
+	for _, k := range m.KeyObjects() {
+		if s, ok := k.(*String); ok && isIdentifier(s.Value) {
+			buff.WriteString(s.Value)
+		} else {
+			buff.WriteString(k.Inspect())
+		}
 		buff.WriteByte('=')
+		v, _, _ := m.Get(k)
 		buff.WriteString(v.Inspect())
 		buff.WriteByte(';')
 	}
@@ -182,14 +343,252 @@ func (m *MapObject) Inspect() string {
 	return buff.String()
 }
 
+// Len is the number of keys, of either type.
+func (m *MapObject) Len() int {
+	return len(m.Fields) + len(m.ints)
+}
+
+func checkKey(key Object) error {
+	switch key.(type) {
+	case *String, *Integer:
+		return nil
+	case nil:
+		return fmt.Errorf("map keys are STRING or INTEGER, found nothing")
+	}
+	return fmt.Errorf("map keys are STRING or INTEGER, found %s", key.Type())
+}
+
+// Get returns the value of a string or integer key.
+func (m *MapObject) Get(key Object) (Object, bool, error) {
+	var obj Object
+	var have bool
+	switch k := key.(type) {
+	case *String:
+		obj, have = m.Fields[k.Value]
+	case *Integer:
+		obj, have = m.ints[k.Value]
+	default:
+		return nil, false, checkKey(key)
+	}
+	return obj, have, nil
+}
+
+// Put adds or replaces the value of a string or integer key. Replaced keys
+// keep their place.
+func (m *MapObject) Put(key Object, value Object) error {
+	if _, have, err := m.Get(key); err != nil {
+		return err
+	} else if !have {
+		m.order = append(m.order, key)
+	}
+	switch k := key.(type) {
+	case *String:
+		if m.Fields == nil {
+			m.Fields = map[string]Object{}
+		}
+		m.Fields[k.Value] = value
+	case *Integer:
+		if m.ints == nil {
+			m.ints = map[int64]Object{}
+		}
+		m.ints[k.Value] = value
+	}
+	return nil
+}
+
+// Remove removes a string or integer key, reporting whether it was there.
+func (m *MapObject) Remove(key Object) (bool, error) {
+	if _, have, err := m.Get(key); err != nil || !have {
+		return false, err
+	}
+	switch k := key.(type) {
+	case *String:
+		delete(m.Fields, k.Value)
+	case *Integer:
+		delete(m.ints, k.Value)
+	}
+	for i, k := range m.order {
+		if sameKey(k, key) {
+			m.order = append(m.order[:i:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return true, nil
+}
+
+func sameKey(a, b Object) bool {
+	switch k := a.(type) {
+	case *String:
+		other, ok := b.(*String)
+		return ok && k.Value == other.Value
+	case *Integer:
+		other, ok := b.(*Integer)
+		return ok && k.Value == other.Value
+	}
+	return false
+}
+
+// Set adds or replaces the value of a string key.
+func (m *MapObject) Set(key string, value Object) {
+	_ = m.Put(&String{Value: key}, value)
+}
+
+// Delete removes a string key, reporting whether it was there.
+func (m *MapObject) Delete(key string) bool {
+	have, _ := m.Remove(&String{Value: key})
+	return have
+}
+
+// KeyObjects returns the keys, as strings and integers, in the order they were
+// added.
+func (m *MapObject) KeyObjects() []Object {
+	keys := make([]Object, 0, m.Len())
+	seen := make(map[string]bool, len(m.Fields))
+	seenInts := make(map[int64]bool, len(m.ints))
+	for _, k := range m.order {
+		switch t := k.(type) {
+		case *String:
+			if _, have := m.Fields[t.Value]; have && !seen[t.Value] {
+				seen[t.Value] = true
+				keys = append(keys, k)
+			}
+		case *Integer:
+			if _, have := m.ints[t.Value]; have && !seenInts[t.Value] {
+				seenInts[t.Value] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	if len(keys) == m.Len() {
+		return keys
+	}
+	rest := []string{}
+	for k := range m.Fields {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		keys = append(keys, &String{Value: k})
+	}
+	return keys
+}
+
+// Keys returns the string keys in the order they were added.
+func (m *MapObject) Keys() []string {
+	keys := make([]string, 0, len(m.Fields))
+	for _, k := range m.KeyObjects() {
+		if s, ok := k.(*String); ok {
+			keys = append(keys, s.Value)
+		}
+	}
+	return keys
+}
+
+// Merge returns a new map with the keys of both, where other's values win.
+func (m *MapObject) Merge(other *MapObject) *MapObject {
+	merged := NewMap()
+	for _, from := range []*MapObject{m, other} {
+		for _, k := range from.KeyObjects() {
+			v, _, _ := from.Get(k)
+			_ = merged.Put(k, v)
+		}
+	}
+	return merged
+}
+
+func (m *MapObject) Add(other Object) (Object, error) {
+	if o, ok := other.(*MapObject); ok {
+		return m.Merge(o), nil
+	}
+	return nil, fmt.Errorf("can only merge map with map (not %s)", other.Type())
+}
+
+func (m *MapObject) Subtract(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '-' not defined for MAP")
+}
+
+func (m *MapObject) Multiply(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '*' not defined for MAP")
+}
+
+func (m *MapObject) Divide(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '/' not defined for MAP")
+}
+
+func (m *MapObject) Modulus(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '%%' not defined for MAP")
+}
+
+func (m *MapObject) IsComparable(other Comparable) bool {
+	_, ok := other.(*MapObject)
+	return ok
+}
+
+// Maps are equal with the same keys and equal values, in any order.
+func (m *MapObject) Equals(other Comparable) (bool, error) {
+	o, ok := other.(*MapObject)
+	if !ok {
+		return false, fmt.Errorf("unable to compare map against type: %s", other.Type())
+	}
+	if m.Len() != o.Len() {
+		return false, nil
+	}
+	for _, k := range m.KeyObjects() {
+		v, _, _ := m.Get(k)
+		if ov, have, _ := o.Get(k); !have {
+			return false, nil
+		} else if equal, err := Equal(v, ov); err != nil || !equal {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (m *MapObject) GreaterThan(other Comparable) (bool, error) {
+	return false, fmt.Errorf("cannot compare map relatively")
+}
+
+func (m *MapObject) LessThan(other Comparable) (bool, error) {
+	return false, fmt.Errorf("cannot compare map relatively")
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Fields hide the methods of the same name.
 func (m *MapObject) Identifier(name string) (Object, error) {
-	if obj, has := m.Fields[name]; !has {
-		return nil, fmt.Errorf("field not found: %s", name)
-	} else {
+	if obj, has := m.Fields[name]; has {
 		return obj, nil
+	} else if method, ok := methodOf(mapMethods, m, name); ok {
+		return method, nil
+	} else {
+		return nil, fmt.Errorf("field not found: %s", name)
 	}
 }
 
+// NoneObject /////////////////////////////////////////////////////////////////
+
+// NoneObject is the value of expressions that have none, like an if without an
+// else whose condition is false, or a function that returns nothing. There is
+// only the one, None.
+type NoneObject struct{}
+
+var None = &NoneObject{}
+
+func (n *NoneObject) Type() ObjectType { return NoneObjectType }
+func (n *NoneObject) Inspect() string  { return "none" }
+func (n *NoneObject) Identifier(name string) (Object, error) {
+	return nil, fmt.Errorf("'%s' not defined for none", name)
+}
+
 // BoolObject /////////////////////////////////////////////////////////////////
 type BoolObject bool
 
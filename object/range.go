@@ -0,0 +1,275 @@
+package object
+
+import (
+	"fmt"
+	"math"
+)
+
+// Range //////////////////////////////////////////////////////////////////////
+
+// Range is a sequence of integers from Start towards End, Step apart. Its
+// integers are worked out as they're needed, so loops and the range's methods
+// never hold them all; anything else that needs a list gets one from List.
+type Range struct {
+	Start     int64
+	End       int64
+	Step      int64
+	Inclusive bool // End is part of the range, as with 1..48.
+}
+
+// NewRange returns the integers from start up to, but not including, end.
+// A negative step counts down.
+func NewRange(start, end, step int64) (*Range, error) {
+	if step == 0 {
+		return nil, fmt.Errorf("range step cannot be 0")
+	}
+	return checkLength(&Range{Start: start, End: end, Step: step})
+}
+
+// NewIntegerRange returns the integers from start to end, including end if
+// inclusive is set.
+func NewIntegerRange(start, end int64, inclusive bool) (*Range, error) {
+	return checkLength(&Range{Start: start, End: end, Step: 1, Inclusive: inclusive})
+}
+
+// A range's length has to fit in an int64, so 0..9223372036854775807 is one
+// integer too long.
+func checkLength(r *Range) (*Range, error) {
+	if r.length() > math.MaxInt64 {
+		return nil, fmt.Errorf("%s has more than %d integers", r.Inspect(), int64(math.MaxInt64))
+	}
+	return r, nil
+}
+
+func (r *Range) Type() ObjectType {
+	return RangeObjectType
+}
+
+func (r *Range) Inspect() string {
+	switch {
+	case r.Step != 1:
+		return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+	case r.Inclusive:
+		return fmt.Sprintf("%d..%d", r.Start, r.End)
+	default:
+		return fmt.Sprintf("%d..<%d", r.Start, r.End)
+	}
+}
+
+// Ranges have their own methods, which work out each integer as it's needed.
+func (r *Range) Identifier(name string) (Object, error) {
+	if m, ok := methodOf(rangeMethods, r, name); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("'%s' not defined for range", name)
+}
+
+// Len is the number of integers in the range, which NewRange and
+// NewIntegerRange make sure fits.
+func (r *Range) Len() int64 {
+	return int64(r.length())
+}
+
+// The distance between two integers, and the size of a step, fit in a uint64
+// where they wouldn't in an int64. Lengths too large for a uint64 are given
+// as MaxUint64.
+func (r *Range) length() uint64 {
+	if r.End == r.Start && !r.Inclusive {
+		return 0
+	}
+	span, ok := r.offset(r.End)
+	if !ok {
+		return 0
+	}
+	if !r.Inclusive {
+		span--
+	}
+	if n := span / r.stepSize(); n < math.MaxUint64 {
+		return n + 1
+	}
+	return math.MaxUint64
+}
+
+// How far n is past the start, in the direction of the step.
+func (r *Range) offset(n int64) (uint64, bool) {
+	switch {
+	case r.Step > 0 && n >= r.Start:
+		return uint64(n) - uint64(r.Start), true
+	case r.Step < 0 && n <= r.Start:
+		return uint64(r.Start) - uint64(n), true
+	}
+	return 0, false
+}
+
+func (r *Range) stepSize() uint64 {
+	if r.Step < 0 {
+		return -uint64(r.Step)
+	}
+	return uint64(r.Step)
+}
+
+// At is the i'th integer of the range, for i below Len.
+func (r *Range) At(i int64) *Integer {
+	return &Integer{Value: r.Start + i*r.Step}
+}
+
+// Contains reports whether n is one of the range's integers, without going
+// through them.
+func (r *Range) Contains(n int64) bool {
+	offset, ok := r.offset(n)
+	return ok && offset%r.stepSize() == 0 && offset/r.stepSize() < r.length()
+}
+
+// Slice is the integers from the low'th up to, but not including, the high'th,
+// as a range. Only ranges counting by 1 reach the largest, or smallest, int64,
+// and a slice of one that ends there includes its end instead.
+func (r *Range) Slice(low, high int64) *Range {
+	start := r.Start + low*r.Step
+	if low >= high {
+		return &Range{Start: start, End: start, Step: r.Step}
+	}
+	last, next := r.Start+(high-1)*r.Step, int64(1)
+	if r.Step < 0 {
+		next = -1
+	}
+	if last == math.MaxInt64 || last == math.MinInt64 {
+		return &Range{Start: start, End: last, Step: r.Step, Inclusive: true}
+	}
+	return &Range{Start: start, End: last + next, Step: r.Step}
+}
+
+// MaxRangeList is the most integers a range can be made into a list with, so
+// a range too large to hold is an error rather than running out of memory.
+const MaxRangeList = 1 << 24
+
+// List returns the range's integers as a list, if there are at most
+// MaxRangeList of them.
+func (r *Range) List() (*List, error) {
+	n := r.Len()
+	if n > MaxRangeList {
+		return nil, fmt.Errorf("%s has %d integers, too many for a list", r.Inspect(), n)
+	}
+	contents := make([]Object, 0, n)
+	for i := int64(0); i < n; i++ {
+		contents = append(contents, r.At(i))
+	}
+	return &List{Contents: contents, InnerType: IntegerObjectType}, nil
+}
+
+// Ranges concatenate with lists, and other ranges, as the list of their
+// integers.
+func (r *Range) Add(other Object) (Object, error) {
+	l, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	return l.Add(other)
+}
+
+func (r *Range) Subtract(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '-' not defined for RANGE")
+}
+
+func (r *Range) Multiply(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '*' not defined for RANGE")
+}
+
+func (r *Range) Divide(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '/' not defined for RANGE")
+}
+
+func (r *Range) Modulus(other Object) (Object, error) {
+	return nil, fmt.Errorf("operator '%%' not defined for RANGE")
+}
+
+// Range methods //////////////////////////////////////////////////////////////
+
+var rangeMethods = map[string]*Method{}
+
+func init() {
+	for _, m := range []*Method{
+		{Name: "len", Returns: IntegerObjectType, Fn: rangeLen},
+		{Name: "contains", Params: []Param{{"value", ""}}, Returns: BoolObjectType, Fn: rangeContains},
+		{Name: "map", Params: []Param{{"fn", FunctionObjectType}}, Returns: ListObjectType, Fn: rangeMap},
+		{Name: "filter", Params: []Param{{"fn", FunctionObjectType}}, Returns: ListObjectType, Fn: rangeFilter},
+		{Name: "reduce", Params: []Param{{"fn", FunctionObjectType}, {"initial", ""}}, Fn: rangeReduce},
+		// The rest need the list the range stands for.
+		{Name: "join", Params: []Param{{"separator", StringObjectType}}, Returns: StringObjectType, Fn: onList(listJoin)},
+		{Name: "sort", Returns: ListObjectType, Fn: onList(listSort)},
+		{Name: "unique", Returns: ListObjectType, Fn: onList(listUnique)},
+	} {
+		rangeMethods[m.Name] = m
+	}
+}
+
+func onList(fn func(apply Apply, receiver Object, args []Object) (Object, error)) func(apply Apply, receiver Object, args []Object) (Object, error) {
+	return func(apply Apply, receiver Object, args []Object) (Object, error) {
+		if list, err := receiver.(*Range).List(); err != nil {
+			return nil, err
+		} else {
+			return fn(apply, list, args)
+		}
+	}
+}
+
+func rangeLen(apply Apply, receiver Object, args []Object) (Object, error) {
+	return &Integer{Value: receiver.(*Range).Len()}, nil
+}
+
+// Only integers are in a range, anything else just isn't.
+func rangeContains(apply Apply, receiver Object, args []Object) (Object, error) {
+	if i, ok := args[0].(*Integer); ok {
+		return NewBoolObject(receiver.(*Range).Contains(i.Value)), nil
+	}
+	return NewBoolObject(false), nil
+}
+
+func rangeMap(apply Apply, receiver Object, args []Object) (Object, error) {
+	r := receiver.(*Range)
+	n := r.Len()
+	if n > MaxRangeList {
+		return nil, fmt.Errorf("%s has %d integers, too many to map to a list", r.Inspect(), n)
+	}
+	results := []Object{}
+	for i := int64(0); i < n; i++ {
+		if result, err := call(apply, "map", args[0], r.At(i)); err != nil {
+			return nil, err
+		} else if result == nil {
+			return nil, fmt.Errorf("map function returned nothing for %d", r.At(i).Value)
+		} else {
+			results = append(results, result)
+		}
+	}
+	return NewList(results)
+}
+
+func rangeFilter(apply Apply, receiver Object, args []Object) (Object, error) {
+	r := receiver.(*Range)
+	results := &List{InnerType: IntegerObjectType, Contents: []Object{}}
+	for i, n := int64(0), r.Len(); i < n; i++ {
+		if keep, err := predicate(apply, "filter", args[0], r.At(i)); err != nil {
+			return nil, err
+		} else if !keep {
+			continue
+		} else if len(results.Contents) == MaxRangeList {
+			return nil, fmt.Errorf("filtering %s keeps too many integers for a list", r.Inspect())
+		}
+		results.Contents = append(results.Contents, r.At(i))
+	}
+	return results, nil
+}
+
+func rangeReduce(apply Apply, receiver Object, args []Object) (Object, error) {
+	r := receiver.(*Range)
+	acc := args[1]
+	for i, n := int64(0), r.Len(); i < n; i++ {
+		if result, err := call(apply, "reduce", args[0], acc, r.At(i)); err != nil {
+			return nil, err
+		} else if result == nil {
+			return nil, fmt.Errorf("reduce function returned nothing for %d", r.At(i).Value)
+		} else {
+			acc = result
+		}
+	}
+	return acc, nil
+}
@@ -0,0 +1,215 @@
+// Package optimize rewrites an evaluated node graph before it is emitted.
+//
+// Passes run between evaluation and compilation, and each one reports the
+// changes it made so the rewritten graph can be related back to the source.
+package optimize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nirosys/stitch/object"
+)
+
+// Pass is a single graph rewrite.
+type Pass interface {
+	Name() string
+	// Run rewrites the graph in place, returning a description of each change.
+	Run(g *Graph) ([]string, error)
+}
+
+// Change is a single rewrite made by a pass.
+type Change struct {
+	Pass    string
+	Message string
+}
+
+func (c Change) String() string {
+	return c.Pass + ": " + c.Message
+}
+
+// Config tells the passes about the node types in the graph, which usually
+// come from a catalog.
+type Config struct {
+	Pure []string // Node types without side effects, which prune can remove.
+}
+
+// Passes //////////////////////////////////////////////////////////////////////
+
+var passes = map[string]func(c Config) Pass{
+	"passthru": func(c Config) Pass { return &CollapsePassthru{Types: []string{"std:passthru"}} },
+	"cse":      func(c Config) Pass { return &MergeDuplicates{} },
+	"prune":    func(c Config) Pass { return &Prune{Pure: c.Pure} },
+}
+
+// The order passes run in when all of them are enabled. Collapsing passthrus
+// first exposes more duplicates, and merging duplicates leaves nodes to prune.
+var defaultOrder = []string{"passthru", "cse", "prune"}
+
+// Names returns the names of the available passes, in the order they run by
+// default.
+func Names() []string {
+	return append([]string{}, defaultOrder...)
+}
+
+// Lookup returns a new instance of the named pass.
+func Lookup(name string, c Config) (Pass, error) {
+	if p, have := passes[name]; have {
+		return p(c), nil
+	}
+	return nil, fmt.Errorf("unknown optimization pass '%s', expected one of %v", name, defaultOrder)
+}
+
+// Pipeline ////////////////////////////////////////////////////////////////////
+
+type Pipeline struct {
+	Passes []Pass
+}
+
+// NewPipeline builds a pipeline from pass names. Enabled passes always run in
+// the default order, whatever order they are named in.
+func NewPipeline(c Config, names ...string) (*Pipeline, error) {
+	enabled := map[string]bool{}
+	for _, name := range names {
+		if _, have := passes[name]; !have {
+			return nil, fmt.Errorf("unknown optimization pass '%s', expected one of %v", name, defaultOrder)
+		}
+		enabled[name] = true
+	}
+	p := &Pipeline{}
+	for _, name := range defaultOrder {
+		if enabled[name] {
+			p.Passes = append(p.Passes, passes[name](c))
+		}
+	}
+	return p, nil
+}
+
+// Run applies each pass to the graph reachable from the roots, and returns the
+// roots of the rewritten graph. The nodes are modified in place.
+func (p *Pipeline) Run(roots []*object.Node) ([]*object.Node, []Change, error) {
+	g := &Graph{Roots: append([]*object.Node{}, roots...)}
+	changes := []Change{}
+	for _, pass := range p.Passes {
+		msgs, err := pass.Run(g)
+		if err != nil {
+			return nil, changes, fmt.Errorf("%s: %w", pass.Name(), err)
+		}
+		for _, msg := range msgs {
+			changes = append(changes, Change{Pass: pass.Name(), Message: msg})
+		}
+	}
+	return g.Roots, changes, nil
+}
+
+// Graph ///////////////////////////////////////////////////////////////////////
+
+// Graph is the set of nodes reachable from its roots. It holds no state of its
+// own beyond the roots, so passes are free to rewire the nodes.
+type Graph struct {
+	Roots []*object.Node
+}
+
+// Nodes returns every node reachable from the roots, in visit order.
+func (g *Graph) Nodes() []*object.Node {
+	nodes := []*object.Node{}
+	visited := map[*object.Node]bool{}
+	var visit func(n *object.Node)
+	visit = func(n *object.Node) {
+		if visited[n] {
+			return
+		}
+		visited[n] = true
+		nodes = append(nodes, n)
+		for _, c := range connections(n) {
+			visit(c.End.Node)
+		}
+	}
+	for _, root := range g.Roots {
+		visit(root)
+	}
+	return nodes
+}
+
+// Upstream returns the connections feeding each reachable node.
+func (g *Graph) Upstream() map[*object.Node][]*object.Connection {
+	upstream := map[*object.Node][]*object.Connection{}
+	for _, n := range g.Nodes() {
+		for _, c := range connections(n) {
+			upstream[c.End.Node] = append(upstream[c.End.Node], c)
+		}
+	}
+	return upstream
+}
+
+// Replaces one root with another, without listing the same root twice.
+func (g *Graph) replaceRoot(old, new *object.Node) {
+	roots := []*object.Node{}
+	for _, r := range g.Roots {
+		if r == old {
+			r = new
+		}
+		if new != nil && r == new && containsNode(roots, new) {
+			continue
+		}
+		if r != nil {
+			roots = append(roots, r)
+		}
+	}
+	g.Roots = roots
+}
+
+func (g *Graph) isRoot(n *object.Node) bool {
+	return containsNode(g.Roots, n)
+}
+
+func containsNode(nodes []*object.Node, n *object.Node) bool {
+	for _, v := range nodes {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Connections in slot order, so passes visit nodes deterministically.
+func connections(n *object.Node) []*object.Connection {
+	conns := n.GetConnections()
+	sort.SliceStable(conns, func(i, j int) bool {
+		return conns[i].Start.Name < conns[j].Start.Name
+	})
+	return conns
+}
+
+func typeName(n *object.Node) string {
+	if n.NodeType == nil {
+		return "node"
+	}
+	return n.NodeType.Name
+}
+
+// Describes a node for change reports: `snmp:get("ifInOctets") at line 3 column 8`.
+func describe(n *object.Node) string {
+	args := make([]string, 0, len(n.Arguments))
+	for _, arg := range n.Arguments {
+		args = append(args, arg.Inspect())
+	}
+	return fmt.Sprintf("%s(%s) at line %d column %d",
+		typeName(n), strings.Join(args, ", "), n.Position.Line, n.Position.Column)
+}
+
+// Nodes with a field or tag name produce named data, and can't be removed or
+// merged with nodes that don't.
+func isNamed(n *object.Node) bool {
+	return n.FieldName != nil || n.TagName != nil
+}
+
+func hasType(n *object.Node, types []string) bool {
+	for _, t := range types {
+		if typeName(n) == t {
+			return true
+		}
+	}
+	return false
+}
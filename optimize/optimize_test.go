@@ -0,0 +1,110 @@
+package optimize
+
+import (
+	"testing"
+
+	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/object"
+)
+
+var oidArg = []*ast.FunctionParameter{{Identifier: &ast.Identifier{Identifier: "oid"}}}
+
+var (
+	walkType = &object.NodeType{Name: "snmp:walk", NodeArgs: oidArg, InputSlots: []string{"Input"}, OutputSlots: []string{"Output", "Error"}}
+	getType  = &object.NodeType{Name: "snmp:get", NodeArgs: oidArg, InputSlots: []string{"Input"}, OutputSlots: []string{"Output"}}
+	passType = &object.NodeType{Name: "std:passthru", InputSlots: []string{"Input"}, OutputSlots: []string{"Output"}}
+)
+
+var passthruPure = Config{Pure: []string{"std:passthru"}}
+
+func construct(t *testing.T, nt *object.NodeType, args ...object.Object) *object.Node {
+	obj, err := nt.Construct(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*object.Node)
+}
+
+func Test_Pipeline(t *testing.T) {
+	walk := construct(t, walkType, &object.String{Value: "ifType"})
+	p1, p2 := construct(t, passType), construct(t, passType)
+	a1 := construct(t, getType, &object.String{Value: "a"})
+	a2 := construct(t, getType, &object.String{Value: "a"})
+	b := construct(t, getType, &object.String{Value: "b"})
+
+	walk.Connect(p1)
+	p1.Connect(p2)
+	p2.Connect(&object.List{Contents: []object.Object{a1, a2, b}})
+	walk.ConnectSlots("Error", construct(t, passType).GetSlot("Input"))
+
+	pipeline, err := NewPipeline(passthruPure, Names()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, changes, err := pipeline.Run([]*object.Node{walk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 4 {
+		t.Errorf("expected 4 changes, found %d: %v", len(changes), changes)
+	}
+
+	nodes := (&Graph{Roots: roots}).Nodes()
+	if len(nodes) != 3 || nodes[0] != walk || nodes[1] != a1 || nodes[2] != b {
+		t.Errorf("unexpected nodes after optimization: %v", nodes)
+	}
+}
+
+func Test_PipelineDisabled(t *testing.T) {
+	walk := construct(t, walkType, &object.String{Value: "ifType"})
+	walk.Connect(&object.List{Contents: []object.Object{
+		construct(t, getType, &object.String{Value: "a"}),
+		construct(t, getType, &object.String{Value: "a"}),
+	}})
+
+	pipeline, err := NewPipeline(passthruPure, "passthru", "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, changes, err := pipeline.Run([]*object.Node{walk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 || len((&Graph{Roots: roots}).Nodes()) != 3 {
+		t.Errorf("duplicates merged with cse disabled: %v", changes)
+	}
+
+	if _, err := NewPipeline(passthruPure, "inline"); err == nil {
+		t.Errorf("expected error for unknown pass")
+	}
+}
+
+// Passthrus without a name, and nothing downstream, are removed along with the
+// passthrus that only fed them. Gets aren't pure, so leaf gets are kept.
+func Test_PrunePure(t *testing.T) {
+	walk := construct(t, walkType, &object.String{Value: "ifType"})
+	unused := construct(t, passType)
+	feeding := construct(t, passType)
+	named := construct(t, passType)
+	field := "octets"
+	named.FieldName = &field
+	get := construct(t, getType, &object.String{Value: "a"})
+	walk.Connect(&object.List{Contents: []object.Object{unused, feeding, named, get}})
+	feeding.Connect(construct(t, passType))
+
+	pipeline, err := NewPipeline(passthruPure, "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, changes, err := pipeline.Run([]*object.Node{walk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("expected 3 passthrus removed, found %v", changes)
+	}
+	nodes := (&Graph{Roots: roots}).Nodes()
+	if len(nodes) != 3 || nodes[0] != walk || nodes[1] != named || nodes[2] != get {
+		t.Errorf("unexpected nodes after pruning: %v", nodes)
+	}
+}
@@ -0,0 +1,202 @@
+package optimize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nirosys/stitch/object"
+)
+
+// CollapsePassthru ////////////////////////////////////////////////////////////
+
+// CollapsePassthru connects the nodes feeding a passthru directly to the nodes
+// it feeds, removing the passthru. Chains of passthrus collapse one link at a
+// time. Passthrus without upstream nodes are left alone, since they are the
+// entry point of the graph, as are passthrus with a field or tag name.
+type CollapsePassthru struct {
+	Types []string // Node types that forward their input unchanged.
+}
+
+func (p *CollapsePassthru) Name() string { return "passthru" }
+
+func (p *CollapsePassthru) Run(g *Graph) ([]string, error) {
+	changes := []string{}
+	upstream := g.Upstream()
+	for _, n := range g.Nodes() {
+		if !hasType(n, p.Types) || isNamed(n) || g.isRoot(n) || !collapsible(n, upstream[n]) {
+			continue
+		}
+		downstream := connections(n)
+		for _, in := range upstream[n] {
+			from := in.Start.Node
+			from.Disconnect(in.Start.Name, in.End)
+			for _, out := range downstream {
+				if !from.IsConnected(in.Start.Name, out.End) {
+					from.ConnectSlots(in.Start.Name, out.End)
+				}
+			}
+		}
+		// The connections moved upstream, so the graph needs to be walked again
+		// to see the new shape.
+		upstream = g.Upstream()
+		changes = append(changes, fmt.Sprintf("removed %s", describe(n)))
+	}
+	return changes, nil
+}
+
+// A passthru can only be collapsed when everything flows into one slot, and
+// out of one slot, otherwise it isn't a plain passthru.
+func collapsible(n *object.Node, upstream []*object.Connection) bool {
+	if len(upstream) == 0 {
+		return false
+	}
+	for _, c := range upstream {
+		if c.Start.Node == n || c.End.Name != upstream[0].End.Name {
+			return false
+		}
+	}
+	downstream := connections(n)
+	for _, c := range downstream {
+		if c.End.Node == n || c.Start.Name != downstream[0].Start.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeDuplicates /////////////////////////////////////////////////////////////
+
+// MergeDuplicates merges nodes of the same type, with the same arguments,
+// names, and upstream connections (common subexpression elimination). Every
+// connection out of a duplicate is moved to the node it is merged into.
+// Merging can make the nodes downstream of it identical as well, so the pass
+// runs until nothing changes.
+type MergeDuplicates struct{}
+
+func (p *MergeDuplicates) Name() string { return "cse" }
+
+func (p *MergeDuplicates) Run(g *Graph) ([]string, error) {
+	changes := []string{}
+	for {
+		keep, dup := p.findDuplicate(g)
+		if dup == nil {
+			return changes, nil
+		}
+		merge(g, keep, dup)
+		changes = append(changes, fmt.Sprintf("merged %s into %s", describe(dup), describe(keep)))
+	}
+}
+
+func (p *MergeDuplicates) findDuplicate(g *Graph) (*object.Node, *object.Node) {
+	nodes := g.Nodes()
+	index := map[*object.Node]int{}
+	for i, n := range nodes {
+		index[n] = i
+	}
+	upstream := g.Upstream()
+
+	seen := map[string]*object.Node{}
+	for _, n := range nodes {
+		key := nodeKey(n, upstream[n], index)
+		if first, have := seen[key]; have {
+			return first, n
+		}
+		seen[key] = n
+	}
+	return nil, nil
+}
+
+// Two nodes with the same key produce the same data.
+func nodeKey(n *object.Node, upstream []*object.Connection, index map[*object.Node]int) string {
+	var buffer strings.Builder
+	buffer.WriteString(typeName(n))
+	if n.NodeType != nil {
+		fmt.Fprintf(&buffer, "[%s|%s]", strings.Join(n.NodeType.InputSlots, ","), strings.Join(n.NodeType.OutputSlots, ","))
+	} else {
+		// Node literals are only comparable through their slots.
+		fmt.Fprintf(&buffer, "[%s|%s]", strings.Join(slotNames(n.InputSlots), ","), strings.Join(slotNames(n.OutputSlots), ","))
+	}
+	for _, arg := range n.Arguments {
+		fmt.Fprintf(&buffer, "(%s:%s)", arg.Type(), arg.Inspect())
+	}
+	if n.FieldName != nil {
+		fmt.Fprintf(&buffer, "field:%q", *n.FieldName)
+	}
+	if n.TagName != nil {
+		fmt.Fprintf(&buffer, "tag:%q", *n.TagName)
+	}
+
+	sources := make([]string, 0, len(upstream))
+	for _, c := range upstream {
+		sources = append(sources, fmt.Sprintf("%d.%s>%s", index[c.Start.Node], c.Start.Name, c.End.Name))
+	}
+	sort.Strings(sources)
+	buffer.WriteString("<-")
+	buffer.WriteString(strings.Join(sources, ","))
+	return buffer.String()
+}
+
+func slotNames(slots map[string]struct{}) []string {
+	names := make([]string, 0, len(slots))
+	for n := range slots {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Moves every connection to and from dup onto keep.
+func merge(g *Graph, keep, dup *object.Node) {
+	upstream := g.Upstream()
+	for _, c := range upstream[dup] {
+		from := c.Start.Node
+		from.Disconnect(c.Start.Name, c.End)
+		if slot := keep.GetSlot(c.End.Name); slot != nil && !from.IsConnected(c.Start.Name, slot) {
+			from.ConnectSlots(c.Start.Name, slot)
+		}
+	}
+	for _, c := range connections(dup) {
+		dup.Disconnect(c.Start.Name, c.End)
+		if !keep.IsConnected(c.Start.Name, c.End) {
+			keep.ConnectSlots(c.Start.Name, c.End)
+		}
+	}
+	if g.isRoot(dup) {
+		g.replaceRoot(dup, keep)
+	}
+}
+
+// Prune ///////////////////////////////////////////////////////////////////////
+
+// Prune removes nodes that have no effect: nodes of a pure type (one whose only
+// effect is the data it sends downstream) that have nothing downstream, and no
+// field or tag name. Removing a node can leave the node feeding it with
+// nothing downstream, so the pass runs until nothing changes. Roots are never
+// removed.
+type Prune struct {
+	Pure []string // Node types without side effects.
+}
+
+func (p *Prune) Name() string { return "prune" }
+
+func (p *Prune) Run(g *Graph) ([]string, error) {
+	changes := []string{}
+	for {
+		removed := false
+		upstream := g.Upstream()
+		for _, n := range g.Nodes() {
+			if g.isRoot(n) || isNamed(n) || !hasType(n, p.Pure) || len(connections(n)) > 0 {
+				continue
+			}
+			for _, c := range upstream[n] {
+				c.Start.Node.Disconnect(c.Start.Name, c.End)
+			}
+			changes = append(changes, fmt.Sprintf("removed %s", describe(n)))
+			removed = true
+		}
+		if !removed {
+			return changes, nil
+		}
+	}
+}
@@ -1,13 +1,13 @@
 package parsing
 
 import (
-	"fmt"
-
 	"github.com/nirosys/stitch/ast"
 	"github.com/nirosys/stitch/lexing"
 )
 
-func (p *Parser) parseForeach() ast.Statement {
+// foreach v in <list|map|integer> { ... }
+// foreach k, v in <map> { ... }
+func (p *Parser) parseForeach() ast.Expression {
 	stmt := &ast.ForeachStatement{Token: p.curToken}
 
 	if !p.expectPeek(lexing.IDENT) {
@@ -20,6 +20,15 @@ func (p *Parser) parseForeach() ast.Statement {
 		stmt.LoopVar = ident.(*ast.Identifier)
 	}
 
+	if p.peekTokenIs(lexing.O_COMMA) {
+		p.nextToken()
+		if !p.expectPeek(lexing.IDENT) {
+			return nil
+		}
+		stmt.KeyVar = stmt.LoopVar
+		stmt.LoopVar = p.parseIdentifier().(*ast.Identifier)
+	}
+
 	if !p.expectPeek(lexing.K_IN) {
 		return nil
 	}
@@ -35,15 +44,139 @@ func (p *Parser) parseForeach() ast.Statement {
 		return nil
 	}
 
-	if block := p.parseBlockExpression(); block == nil {
+	if block := asBlock(p.parseBlockExpression()); block == nil {
 		return nil
 	} else {
-		stmt.Block = block.(*ast.BlockExpression)
+		stmt.Block = block
 	}
 
 	return stmt
 }
 
+// Blocks that are empty, or only assignments, parse as map literals. Where
+// only a block makes sense, they are turned back into one.
+func asBlock(exp ast.Expression) *ast.BlockExpression {
+	switch t := exp.(type) {
+	case *ast.BlockExpression:
+		return t
+	case *ast.MapLiteral:
+		block := &ast.BlockExpression{Token: t.Token, Statements: []ast.Statement{}}
+		for _, assign := range t.Assignments {
+			block.Statements = append(block.Statements, assign)
+		}
+		return block
+	default:
+		return nil
+	}
+}
+
+func (p *Parser) parseBreak() ast.Statement {
+	return &ast.BreakStatement{Token: p.curToken}
+}
+
+func (p *Parser) parseContinue() ast.Statement {
+	return &ast.ContinueStatement{Token: p.curToken}
+}
+
+// match <expr> { <pattern> [| <pattern>...] => <expr>, ..., _ => <expr> }
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+
+	p.nextToken()
+	if exp.Subject = p.parseExpression(LOWEST); exp.Subject == nil {
+		return nil
+	}
+
+	if !p.expectPeek(lexing.D_LBRACE) {
+		return nil
+	}
+	p.nextToken()
+
+	for !p.curTokenIs(lexing.D_RBRACE) {
+		arm := &ast.MatchArm{Token: p.curToken}
+		for {
+			// Patterns stop at '|', so bitwise or needs parentheses in them.
+			if pattern := p.parseExpression(BITOR); pattern == nil {
+				return nil
+			} else {
+				arm.Patterns = append(arm.Patterns, pattern)
+			}
+			if !p.peekTokenIs(lexing.O_PIPE) {
+				break
+			}
+			p.nextToken()
+			p.nextToken()
+		}
+
+		if !p.expectPeek(lexing.O_FATARROW) {
+			return nil
+		}
+		p.nextToken()
+		if arm.Body = p.parseExpression(LOWEST); arm.Body == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if p.peekTokenIs(lexing.O_COMMA) || p.peekTokenIs(lexing.D_SEMICOLON) {
+			p.nextToken()
+		}
+		if p.peekTokenIs(lexing.EOF) {
+			p.peekError(lexing.D_RBRACE)
+			return nil
+		}
+		p.nextToken()
+	}
+
+	if !exhaustive(exp) {
+		p.warnf(exp.Token.Position, "match has no default arm (_ => ...)")
+	}
+	return exp
+}
+
+// A match is exhaustive with a default arm, or arms for both booleans.
+func exhaustive(m *ast.MatchExpression) bool {
+	if m.HasDefault() {
+		return true
+	}
+	seen := map[bool]bool{}
+	for _, arm := range m.Arms {
+		for _, pattern := range arm.Patterns {
+			if b, ok := pattern.(*ast.BoolLiteral); ok {
+				seen[b.Value] = true
+			}
+		}
+	}
+	return seen[true] && seen[false]
+}
+
+// try { ... } catch [err] { ... }
+func (p *Parser) parseTryExpression() ast.Expression {
+	exp := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(lexing.D_LBRACE) {
+		return nil
+	}
+	if exp.Block = asBlock(p.parseBlockExpression()); exp.Block == nil {
+		return nil
+	}
+
+	if !p.expectPeek(lexing.K_CATCH) {
+		return nil
+	}
+	if p.peekTokenIs(lexing.IDENT) {
+		p.nextToken()
+		exp.ErrVar = p.parseIdentifier().(*ast.Identifier)
+	}
+
+	if !p.expectPeek(lexing.D_LBRACE) {
+		return nil
+	}
+	if exp.Catch = asBlock(p.parseBlockExpression()); exp.Catch == nil {
+		return nil
+	}
+	return exp
+}
+
 func (p *Parser) parseConditionalExpression() ast.Expression {
 	exp := &ast.ConditionalExpression{Token: p.curToken}
 
@@ -59,10 +192,7 @@ func (p *Parser) parseConditionalExpression() ast.Expression {
 		return nil
 	}
 
-	if e := p.parseBlockExpression(); e != nil {
-		exp.Block = e.(*ast.BlockExpression)
-	} else {
-		fmt.Printf("Returning block not expression: %#v", e)
+	if exp.Block = asBlock(p.parseBlockExpression()); exp.Block == nil {
 		return nil
 	}
 
@@ -94,6 +224,7 @@ func (p *Parser) parseBlockExpression() ast.Expression {
 	}
 
 	if p.curToken.Type == lexing.EOF {
+		p.errorf(block.Token.Position, "expected %s to close the block; have %s", lexing.TokenStrings[lexing.D_RBRACE], lexing.TokenStrings[lexing.EOF])
 		return nil
 	}
 
@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/diagnostic"
 	"github.com/nirosys/stitch/lexing"
 )
 
@@ -12,11 +13,18 @@ const (
 	_ int = iota
 	LOWEST
 	OR
+	DEFAULT
 	AND
 	EQUAL
+	RANGE
+	BITOR
+	BITXOR
+	BITAND
+	SHIFT
 	SUM
 	PRODUCT
 	PREFIX
+	POWER
 	CALL
 	DEREFERENCE
 )
@@ -29,13 +37,23 @@ var precedences = map[lexing.TokenType]int{
 	lexing.O_COMMA:    OR,
 	lexing.O_ASSIGN:   OR,
 	lexing.K_OR:       OR,
+	lexing.O_DEFAULT:  DEFAULT,
 	lexing.K_AND:      AND,
 	lexing.O_PLUS:     SUM,
 	lexing.O_MINUS:    SUM,
 	lexing.O_STAR:     PRODUCT,
 	lexing.O_SLASH:    PRODUCT,
 	lexing.O_MODULUS:  PRODUCT,
+	lexing.O_RANGE:    RANGE,
+	lexing.O_RANGEX:   RANGE,
+	lexing.O_PIPE:     BITOR,
+	lexing.O_CARET:    BITXOR,
+	lexing.O_AMP:      BITAND,
+	lexing.O_SHL:      SHIFT,
+	lexing.O_SHR:      SHIFT,
+	lexing.O_POWER:    POWER,
 	lexing.D_LPARENTH: CALL,
+	lexing.D_LBRACKET: CALL,
 	lexing.O_DOT:      DEREFERENCE,
 	lexing.O_COLON:    DEREFERENCE,
 	lexing.O_EQ:       EQUAL,
@@ -44,6 +62,7 @@ var precedences = map[lexing.TokenType]int{
 	lexing.O_LTEQ:     EQUAL,
 	lexing.O_GT:       EQUAL,
 	lexing.O_GTEQ:     EQUAL,
+	lexing.K_IS:       EQUAL,
 	lexing.O_ARROW:    OR,
 }
 
@@ -57,17 +76,25 @@ type Parser struct {
 	infixParseFns   map[lexing.TokenType]infixParseFunc
 	postFixParseFns map[lexing.TokenType]postfixParseFunc
 
-	errors []string
+	errors      []string
+	diagnostics []diagnostic.Diagnostic
+
+	colonEnds bool // ':' separates slice bounds, or map keys from values.
 }
 
 func NewParser(r io.Reader) *Parser {
+	return newParser(lexing.NewLexer(r))
+}
+
+func newParser(lex *lexing.Lexer) *Parser {
 	p := &Parser{
-		lex:    lexing.NewLexer(r),
+		lex:    lex,
 		errors: []string{},
 	}
 
 	p.prefixParseFns = map[lexing.TokenType]prefixParseFunc{
 		lexing.L_STRING:  p.parseStringLiteral,
+		lexing.L_INTERP:  p.parseInterpolatedString,
 		lexing.L_INTEGER: p.parseIntegerLiteral,
 		lexing.IDENT:     p.parseIdentifier,
 		lexing.D_LBRACE:  p.parseBlockExpression,
@@ -78,13 +105,22 @@ func NewParser(r io.Reader) *Parser {
 		lexing.K_INTERNAL:  p.parseInternalExpression,
 		lexing.K_TRUE:      p.parseBooleanLiteral,
 		lexing.K_FALSE:     p.parseBooleanLiteral,
+		lexing.K_NONE:      p.parseNoneLiteral,
 		lexing.O_BANG:      p.parseNegatedExpression,
+		lexing.O_MINUS:     p.parsePrefixExpression,
+		lexing.O_PLUS:      p.parsePrefixExpression,
+		lexing.O_TILDE:     p.parsePrefixExpression,
 		lexing.K_IF:        p.parseConditionalExpression,
+		lexing.K_FOREACH:   p.parseForeach,
+		lexing.K_MATCH:     p.parseMatchExpression,
+		lexing.K_TRY:       p.parseTryExpression,
 		lexing.D_LBRACKET:  p.parseListExpression,
+		lexing.D_MAPBRACE:  p.parseHashLiteral,
 		lexing.O_TAGMARKER: p.parseTagName,
 	}
 	p.infixParseFns = map[lexing.TokenType]infixParseFunc{
 		lexing.D_LPARENTH: p.parseCallExpression,
+		lexing.D_LBRACKET: p.parseIndexExpression,
 		lexing.O_ARROW:    p.parseArrowExpression,
 		lexing.O_ASSIGN:   p.parseInfixExpression,
 		lexing.O_PLUS:     p.parseInfixExpression,
@@ -92,6 +128,16 @@ func NewParser(r io.Reader) *Parser {
 		lexing.O_STAR:     p.parseInfixExpression,
 		lexing.O_SLASH:    p.parseInfixExpression,
 		lexing.O_MODULUS:  p.parseInfixExpression,
+		lexing.O_RANGE:    p.parseInfixExpression,
+		lexing.O_RANGEX:   p.parseInfixExpression,
+		lexing.O_PIPE:     p.parseInfixExpression,
+		lexing.O_CARET:    p.parseInfixExpression,
+		lexing.O_AMP:      p.parseInfixExpression,
+		lexing.O_SHL:      p.parseInfixExpression,
+		lexing.O_SHR:      p.parseInfixExpression,
+		lexing.O_POWER:    p.parseInfixExpression,
+		lexing.O_DEFAULT:  p.parseInfixExpression,
+		lexing.K_IS:       p.parseIsNone,
 		lexing.O_DOT:      p.parseInfixExpression,
 		lexing.O_EQ:       p.parseInfixExpression,
 		lexing.O_GT:       p.parseInfixExpression,
@@ -119,6 +165,11 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// Diagnostics returns the errors found while parsing, with their positions.
+func (p *Parser) Diagnostics() []diagnostic.Diagnostic {
+	return p.diagnostics
+}
+
 func (p *Parser) Parse() *ast.ASTree {
 	tree := &ast.ASTree{Statements: []ast.Statement{}}
 
@@ -154,7 +205,7 @@ func (p *Parser) ParsePartial() *ast.Program {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case lexing.K_LET:
+	case lexing.K_LET, lexing.K_CONST:
 		return p.parseLetStatement()
 	case lexing.COMMENT:
 		return p.parseComment()
@@ -166,8 +217,12 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseNodeStatement()
 	case lexing.K_MODIFIER:
 		return p.parseModifier()
-	case lexing.K_FOREACH:
-		return p.parseForeach()
+	case lexing.K_BREAK:
+		return p.parseBreak()
+	case lexing.K_CONTINUE:
+		return p.parseContinue()
+	case lexing.D_SEMICOLON:
+		return nil // Separates statements on one line.
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -201,7 +256,7 @@ func (p *Parser) parseInternalExpression() ast.Expression {
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curToken}
+	stmt := &ast.LetStatement{Token: p.curToken, Const: p.curTokenIs(lexing.K_CONST)}
 
 	if !p.expectPeek(lexing.IDENT) {
 		return nil // TODO: Do errors..
@@ -290,10 +345,8 @@ func (p *Parser) parseModifier() ast.Statement {
 		return nil
 	}
 
-	if block := p.parseBlockExpression(); block == nil {
+	if mod.Block = asBlock(p.parseBlockExpression()); mod.Block == nil {
 		return nil
-	} else {
-		mod.Block = block.(*ast.BlockExpression)
 	}
 
 	return mod
@@ -353,9 +406,7 @@ func (p *Parser) parseNodeStatement() ast.Statement {
 	node.OutputSlots = outputs
 	p.nextToken()
 
-	if b := p.parseBlockExpression(); b != nil {
-		node.Block = b.(*ast.BlockExpression)
-	} else {
+	if node.Block = asBlock(p.parseBlockExpression()); node.Block == nil {
 		return nil
 	}
 	stmt.Literal = node
@@ -384,15 +435,9 @@ func (p *Parser) parseFunctionStatement() ast.Statement {
 	if !p.expectPeek(lexing.D_LBRACE) {
 		return nil
 	}
-	body := p.parseBlockExpression()
-	if body == nil {
+	if stmt.Body = asBlock(p.parseBlockExpression()); stmt.Body == nil {
 		return nil
 	}
-	if b, ok := body.(*ast.BlockExpression); !ok {
-		return nil
-	} else {
-		stmt.Body = b
-	}
 
 	return stmt
 }
@@ -400,6 +445,7 @@ func (p *Parser) parseFunctionStatement() ast.Statement {
 func (p *Parser) parseExpression(prec int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
+		p.errorf(p.curToken.Position, "unexpected %s", lexing.TokenStrings[p.curToken.Type])
 		return nil
 	}
 
@@ -429,6 +475,15 @@ func (p *Parser) parseNegatedExpression() ast.Expression {
 	return not
 }
 
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	exp := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Text}
+	p.nextToken()
+	if exp.Right = p.parseExpression(PREFIX); exp.Right == nil {
+		return nil
+	}
+	return exp
+}
+
 func (p *Parser) parseTagName() ast.Expression {
 	tag := &ast.TagName{Token: p.curToken}
 
@@ -442,7 +497,6 @@ func (p *Parser) parseTagName() ast.Expression {
 	}
 
 	if ident := p.parseIdentifier(); ident == nil {
-		fmt.Printf("Invalid ident\n")
 		return nil
 	} else {
 		tag.Identifier = ident.(*ast.Identifier)
@@ -452,7 +506,7 @@ func (p *Parser) parseTagName() ast.Expression {
 
 func (p *Parser) parseIdentifier() ast.Expression {
 	if !p.curTokenIs(lexing.IDENT) {
-		fmt.Printf("ERROR: %+v is not an IDENT\n", p.curToken)
+		p.errorf(p.curToken.Position, "expected %s; have %s", lexing.TokenStrings[lexing.IDENT], lexing.TokenStrings[p.curToken.Type])
 		return nil
 	}
 
@@ -483,6 +537,77 @@ func (p *Parser) parseCallExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// left[index], or left[low:high] where either bound can be left off.
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	tok := p.curToken
+	defer p.endAtColon(true)()
+
+	var low ast.Expression
+	if !p.peekTokenIs(lexing.O_COLON) {
+		p.nextToken()
+		if low = p.parseExpression(LOWEST); low == nil {
+			return nil
+		}
+	}
+	if !p.peekTokenIs(lexing.O_COLON) {
+		if !p.expectPeek(lexing.D_RBRACKET) {
+			return nil
+		}
+		return &ast.IndexExpression{Token: tok, Left: left, Index: low}
+	}
+	p.nextToken()
+
+	exp := &ast.SliceExpression{Token: tok, Left: left, Low: low}
+	if !p.peekTokenIs(lexing.D_RBRACKET) {
+		p.nextToken()
+		if exp.High = p.parseExpression(LOWEST); exp.High == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(lexing.D_RBRACKET) {
+		return nil
+	}
+	return exp
+}
+
+// Sets whether ':' ends expressions, returning a func restoring the setting.
+func (p *Parser) endAtColon(ends bool) func() {
+	was := p.colonEnds
+	p.colonEnds = ends
+	return func() { p.colonEnds = was }
+}
+
+// #{ key: value, ... }
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+
+	for !p.peekTokenIs(lexing.D_RBRACE) {
+		p.nextToken()
+		restore := p.endAtColon(true)
+		key := p.parseExpression(LOWEST)
+		restore()
+		if key == nil || !p.expectPeek(lexing.O_COLON) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+		hash.Keys = append(hash.Keys, key)
+		hash.Values = append(hash.Values, value)
+
+		if !p.peekTokenIs(lexing.O_COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(lexing.D_RBRACE) {
+		return nil
+	}
+	return hash
+}
+
 func (p *Parser) parseNamedNode(left ast.Expression) ast.Expression {
 	curToken := p.curToken
 
@@ -516,6 +641,11 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 			p.nextToken()
 			stmt.Value = p.parseExpression(prec)
 			return stmt
+		} else if index, ok := left.(*ast.IndexExpression); ok {
+			stmt := &ast.IndexAssignment{Token: p.curToken, Target: index}
+			p.nextToken()
+			stmt.Value = p.parseExpression(prec)
+			return stmt
 		} else {
 			// TODO: Error
 		}
@@ -526,12 +656,25 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 			Operator: p.curToken.Text,
 		}
 		p.nextToken()
+		if curToken.Type == lexing.O_POWER {
+			prec-- // 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		}
 		stmt.Right = p.parseExpression(prec)
 		return stmt
 	}
 	return nil
 }
 
+// 'is' only checks for none, as in `x is none`.
+func (p *Parser) parseIsNone(left ast.Expression) ast.Expression {
+	exp := &ast.InfixExpression{Token: p.curToken, Left: left, Operator: "is"}
+	if !p.expectPeek(lexing.K_NONE) {
+		return nil
+	}
+	exp.Right = &ast.NoneLiteral{Token: p.curToken}
+	return exp
+}
+
 func (p *Parser) parseArrowExpression(left ast.Expression) ast.Expression {
 	exp := &ast.ArrowExpression{
 		Token: p.curToken,
@@ -576,6 +719,9 @@ func (p *Parser) parseParameterList(end lexing.TokenType) []*ast.FunctionParamet
 func (p *Parser) parseExpressionList(end lexing.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
+	// Named arguments are allowed again, even inside an index.
+	defer p.endAtColon(false)()
+
 	if p.peekTokenIs(end) {
 		p.nextToken()
 		return list
@@ -607,11 +753,15 @@ func (p *Parser) parseComment() *ast.CommentStatement {
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	//fmt.Printf("TOKEN: %+v\n", p.curToken)
+	if p.curToken.Type == lexing.EOF {
+		return
+	}
 	if peek, err := p.lex.NextToken(); err == nil {
 		p.peekToken = peek
 	} else {
-		// TODO: This should bubble up
-		fmt.Printf("ERROR: %s\n", err.Error())
+		// Nothing after a bad token can be trusted, so parsing stops there.
+		p.errorf(peek.Position, "%s", err)
+		p.peekToken = lexing.Token{Position: peek.Position, Type: lexing.EOF}
 	}
 }
 
@@ -638,9 +788,28 @@ func (p *Parser) peekError(t lexing.TokenType) {
 	have := lexing.TokenStrings[p.peekToken.Type]
 	msg := fmt.Sprintf("line %d column %d: expected %s; have %s", pos.Line, pos.Column, exp, have)
 	p.errors = append(p.errors, msg)
+	p.diagnostics = append(p.diagnostics, diagnostic.Errorf(pos, "expected %s; have %s", exp, have))
+}
+
+func (p *Parser) errorf(pos lexing.Position, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	p.errors = append(p.errors, fmt.Sprintf("line %d column %d: %s", pos.Line, pos.Column, msg))
+	p.diagnostics = append(p.diagnostics, diagnostic.Errorf(pos, "%s", msg))
+}
+
+// Warnings don't stop the tree from being returned.
+func (p *Parser) warnf(pos lexing.Position, format string, args ...interface{}) {
+	p.diagnostics = append(p.diagnostics, diagnostic.Warningf(pos, format, args...))
 }
 
 func (p *Parser) peekPrecedence() int {
+	if p.colonEnds && p.peekTokenIs(lexing.O_COLON) {
+		return LOWEST
+	}
+	// A list starting a new line isn't an index into the line before.
+	if p.peekTokenIs(lexing.D_LBRACKET) && p.peekToken.Position.Line != p.curToken.Position.Line {
+		return LOWEST
+	}
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
 	}
@@ -31,6 +31,35 @@ func Test_ParseSingleStatement(t *testing.T) {
 		{prog: "[1,2,3,4]", statements: 1},
 		// map literal
 		{prog: "{foo = \"bar\"; bar = 2}", statements: 1},
+		// foreach loops, including as an expression
+		{prog: "foreach i in [1, 2] { if i == 1 { continue }; i }", statements: 1},
+		{prog: "foreach k, v in {a = 1} { break }", statements: 1},
+		{prog: "let fan = foreach i in 3 {}", statements: 1},
+		// match expressions
+		{prog: "match x { 1 | 2 => \"low\", _ => \"high\" }", statements: 1},
+		{prog: "let s = match x {\n  true => 1\n  false => 0\n}", statements: 1},
+		// try expressions
+		{prog: "let w = try { internal \"host:walk\" } catch err { println(err.message); 1 }", statements: 1},
+		{prog: "try { a = 1 } catch {}", statements: 1},
+		// indexing, slicing and index assignment
+		{prog: "l[0] + l[-1]", statements: 1},
+		{prog: "l[1:3]; s[:3]; s[1:]", statements: 3},
+		{prog: "m[\"ifHCInOctets\"] = m[k][f(a: 1)]", statements: 1},
+		// unary, bitwise and power operators
+		{prog: "x * -2 + +1 - ~mask", statements: 1},
+		{prog: "(counter + 2 ** 32 - last) & 4294967295", statements: 1},
+		{prog: "match x { 1 | 2 => \"low\", (4 | 8) => \"flags\", _ => \"high\" }", statements: 1},
+		// ranges
+		{prog: "foreach i in 1..48 { i }; (0..<n + 1).len()", statements: 2},
+		// none
+		{prog: "let x = f() ?? g(none) ?? 1; x is none == false", statements: 2},
+		// constants
+		{prog: "const ifHCInOctets = \"1.3.6.1.2.1.31.1.1.1.6\"", statements: 1},
+		// interpolated strings
+		{prog: "let oid = \"ifInOctets.${idx + 1} of ${m[\"a\"]}\"", statements: 1},
+		// hash literals
+		{prog: "let m = #{\"a b\": 1, 2: [1, 2], k: f(a: 1),}", statements: 1},
+		{prog: "#{}\n#{\n  \"x\": 1\n}", statements: 2},
 	}
 
 	for i, test := range tests {
@@ -59,6 +88,7 @@ func Test_ParseMultipleStatements(t *testing.T) {
 		// let statement
 		{prog: "let foo = \"bar\";\nsnmp.get(\"sysDescr\");\n", statements: 2},
 		{prog: "{\n  let foo = \"bar\";\n}\n;\n", statements: 1},
+		{prog: "let x = get()\n[x, y] -> join()", statements: 2},
 		{prog: "let foo = {\n  snmp.get(\"sysDescr\");\n}\n;\n", statements: 1},
 	}
 	for i, test := range tests {
@@ -77,27 +107,69 @@ func Test_ParseMultipleStatements(t *testing.T) {
 	}
 }
 
+func Test_ParseMatchWarning(t *testing.T) {
+	tests := []struct {
+		prog     string
+		warnings int
+	}{
+		{prog: "match x { 1 => 2 }", warnings: 1},
+		{prog: "match x { 1 => 2, _ => 3 }", warnings: 0},
+		{prog: "match x { true => 1, false => 0 }", warnings: 0},
+	}
+	for i, test := range tests {
+		p := NewParser(strings.NewReader(test.prog))
+		if prog := p.Parse(); prog == nil {
+			t.Fatalf("[%d] unable to parse: %v", i, p.Errors())
+		}
+		if len(p.Diagnostics()) != test.warnings {
+			t.Errorf("[%d] unexpected number of warnings: %d != %d", i, len(p.Diagnostics()), test.warnings)
+		}
+	}
+}
+
+func Test_ParseLexerErrors(t *testing.T) {
+	tests := []struct {
+		prog string
+		err  string
+	}{
+		{prog: "let s = \"bad \\q\"", err: "line 0 column 8: invalid escape sequence: \\q"},
+		{prog: "let s = 1\nlet t = `open", err: "line 1 column 8: unterminated string"},
+		{prog: "let s = $", err: "line 0 column 8: unexpected character: $"},
+		{prog: "let s = a ? b", err: "line 0 column 10: unexpected character: ?"},
+		{prog: "let s = \"a ${}\"", err: "line 0 column 11: empty interpolation"},
+		{prog: "let s = \"a ${x y}\"", err: "line 0 column 15: expected one expression in interpolation; have IDENTIFIER"},
+	}
+	for i, test := range tests {
+		p := NewParser(strings.NewReader(test.prog))
+		if prog := p.Parse(); prog != nil {
+			t.Errorf("[%d] expected parsing to fail", i)
+		}
+		if errs := p.Errors(); len(errs) == 0 || errs[0] != test.err {
+			t.Errorf("[%d] expected error %q, found %v", i, test.err, errs)
+		}
+	}
+}
+
 func Test_ParseWithErrors(t *testing.T) {
 	tests := []struct {
-		prog       string
-		statements int
-		err        error
+		prog string
+		err  string
 	}{
-		// let statement
-		{prog: "foreach i in [1, 2, 3, 4] {", statements: 0},
+		{prog: "foreach i in [1, 2, 3, 4] {", err: "line 0 column 26: expected '}' to close the block; have EOF"},
+		{prog: "let x = 99999999999999999999", err: "line 0 column 8: integer 99999999999999999999 doesn't fit in 64 bits"},
+		{prog: "let x = )", err: "line 0 column 8: unexpected ')'"},
+		{prog: "if true { a = 1 }\n@5", err: "line 1 column 1: expected IDENTIFIER; have INTEGER literal"},
 	}
 	for i, test := range tests {
 		p := NewParser(strings.NewReader(test.prog))
-		prog := p.Parse()
-		if len(prog.Statements) != test.statements {
-			t.Errorf("[%d] unexpected number of statements: %d != %d", i, len(prog.Statements), test.statements)
+		if prog := p.Parse(); prog != nil {
+			t.Errorf("[%d] expected parsing to fail", i)
+		}
+		if errs := p.Errors(); len(errs) == 0 || errs[0] != test.err {
+			t.Errorf("[%d] expected error %q, found %v", i, test.err, errs)
+		}
+		if diags := p.Diagnostics(); len(diags) != len(p.Errors()) {
+			t.Errorf("[%d] expected a diagnostic for each error, found %v", i, diags)
 		}
-		//progStr := prog.String()
-		//if progStr != test.prog {
-		//	t.Errorf("[%d] Program mismatch:\nParsed ===\n%s\n==== Test\n%s\n===", i, progStr, test.prog)
-		//	for j, s := range prog.Statements {
-		//		fmt.Printf("[%d/%d] %T\n", i, j, s)
-		//	}
-		//}
 	}
 }
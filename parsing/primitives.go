@@ -1,8 +1,9 @@
 package parsing
 
 import (
-	"fmt"
+	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/nirosys/stitch/ast"
 	"github.com/nirosys/stitch/lexing"
@@ -27,6 +28,10 @@ func (p *Parser) parseBooleanLiteral() ast.Expression {
 	}
 }
 
+func (p *Parser) parseNoneLiteral() ast.Expression {
+	return &ast.NoneLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	if !p.curTokenIs(lexing.L_STRING) {
 		return nil
@@ -37,14 +42,48 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	}
 }
 
+// Each interpolation is parsed by a parser of its own, which has to take a
+// single expression.
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	for _, part := range p.curToken.Parts {
+		if part.Kind != lexing.PartInterpolation {
+			tok := lexing.Token{Text: part.Text, Position: part.Position, Type: lexing.L_STRING}
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: tok, Value: part.Text})
+			continue
+		}
+
+		start := lexing.Position{Line: part.Position.Line, Column: part.Position.Column + 2} // after "${"
+		sub := newParser(lexing.NewLexerAt(strings.NewReader(part.Text), start))
+		if sub.curTokenIs(lexing.EOF) {
+			p.errorf(part.Position, "empty interpolation")
+			return nil
+		}
+		exp := sub.parseExpression(LOWEST)
+		if len(sub.errors) == 0 && !sub.peekTokenIs(lexing.EOF) {
+			sub.errorf(sub.peekToken.Position, "expected one expression in interpolation; have %s", lexing.TokenStrings[sub.peekToken.Type])
+		}
+		p.errors = append(p.errors, sub.errors...)
+		p.diagnostics = append(p.diagnostics, sub.diagnostics...)
+		if exp == nil || len(sub.errors) > 0 {
+			return nil
+		}
+		str.Parts = append(str.Parts, exp)
+	}
+	return str
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	if !p.curTokenIs(lexing.L_INTEGER) {
 		return nil
 	}
 
 	i, err := strconv.ParseInt(p.curToken.Text, 10, 64)
-	if err != nil {
-		fmt.Printf("ERROR: %s\n", err.Error())
+	if errors.Is(err, strconv.ErrRange) {
+		p.errorf(p.curToken.Position, "integer %s doesn't fit in 64 bits", p.curToken.Text)
+		return nil
+	} else if err != nil {
+		p.errorf(p.curToken.Position, "invalid integer %s", p.curToken.Text)
 		return nil
 	}
 	return &ast.IntegerLiteral{
@@ -2,13 +2,14 @@ package stitch
 
 import (
 	"bytes"
-	"fmt"
+	"errors"
 	//	"encoding/json"
 	"io"
 	//	"os"
 
 	"github.com/nirosys/stitch/analysis"
 	"github.com/nirosys/stitch/ast"
+	"github.com/nirosys/stitch/diagnostic"
 	"github.com/nirosys/stitch/parsing"
 )
 
@@ -22,15 +23,26 @@ func Version() string {
 type Program struct {
 	Tree    *ast.ASTree
 	Symbols *analysis.SymbolTable
+
+	errors   []string
+	warnings []string
 }
 
+var ErrInvalidSyntax = errors.New("invalid syntax")
+
+// NewProgram parses and analyzes a program. If either fails, Symbols is nil
+// and Errors describes why.
 func NewProgram(r io.Reader) *Program {
 	parser := parsing.NewParser(r)
 	tree := parser.Parse()
 
-	prog := &Program{Tree: tree}
+	prog := &Program{Tree: tree, warnings: warnings(parser)}
+	if tree == nil {
+		prog.errors = parser.Errors()
+		return prog
+	}
 	if symbols, err := analysis.Analyze(tree); err != nil {
-		fmt.Printf("ERROR: %s\n", err.Error())
+		prog.errors = []string{err.Error()}
 	} else {
 		prog.Symbols = symbols
 	}
@@ -45,18 +57,22 @@ func NewProgram(r io.Reader) *Program {
 func ExtendProgram(prog *Program, r io.Reader) (*Program, error) {
 	parser := parsing.NewParser(r)
 	tree := parser.Parse()
+	if tree == nil {
+		return &Program{Tree: prog.Tree, Symbols: prog.Symbols, errors: parser.Errors()}, ErrInvalidSyntax
+	}
 
 	if symbols, err := analysis.AnalyzeWithSymbols(tree, prog.Symbols); err == nil {
 		if prog.Tree != nil {
 			tree.Statements = append(prog.Tree.Statements, tree.Statements...)
 		}
 		newProg := &Program{
-			Tree:    tree,
-			Symbols: symbols,
+			Tree:     tree,
+			Symbols:  symbols,
+			warnings: warnings(parser),
 		}
 		return newProg, nil
 	} else {
-		return nil, err
+		return &Program{Tree: prog.Tree, Symbols: prog.Symbols}, err
 	}
 }
 
@@ -71,5 +87,21 @@ func (p *Program) String() string {
 }
 
 func (p *Program) Errors() []string {
-	return []string{}
+	return p.errors
+}
+
+// Warnings are problems found while parsing that don't stop the program from
+// being evaluated.
+func (p *Program) Warnings() []string {
+	return p.warnings
+}
+
+func warnings(parser *parsing.Parser) []string {
+	msgs := []string{}
+	for _, d := range parser.Diagnostics() {
+		if d.Severity == diagnostic.Warning {
+			msgs = append(msgs, d.String())
+		}
+	}
+	return msgs
 }